@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"time"
@@ -8,9 +9,18 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Logger   LoggerConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	Trash      TrashConfig
+	Password   PasswordConfig
+	JWT        JWTConfig
+	Session    SessionConfig
+	TOTP       TOTPConfig
+	Mail       MailConfig
+	Account    AccountConfig
+	Login      LoginConfig
+	Pagination PaginationConfig
+	Logger     LoggerConfig
 }
 
 // ServerConfig holds all server-related configuration
@@ -30,6 +40,122 @@ type DatabaseConfig struct {
 	Password string
 	Name     string
 	SSLMode  string
+
+	// AutoMigrate applies pending schema migrations on startup
+	AutoMigrate bool
+
+	// Connection pool settings, used by SQL-backed drivers
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// TrashConfig holds all soft-delete related configuration
+type TrashConfig struct {
+	// Retention is how long deleted records stay restorable before being purged
+	Retention     time.Duration
+	PurgeInterval time.Duration
+}
+
+// PasswordConfig holds all password hashing related configuration
+type PasswordConfig struct {
+	// Algorithm is either "argon2id" or "bcrypt"
+	Algorithm  string
+	BcryptCost int
+
+	// Argon2id parameters; memory is in KiB
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+
+	// MaxConcurrentHashes bounds how many passwords are hashed or verified at
+	// once; with argon2id each of them holds Argon2Memory KiB
+	MaxConcurrentHashes int
+}
+
+// JWTConfig holds all token signing related configuration
+type JWTConfig struct {
+	// Algorithm is either "HS256", signing with Secret, or "RS256", signing
+	// with the PEM-encoded RSA keys read from the key files
+	Algorithm      string
+	Secret         string
+	PrivateKeyFile string
+	PublicKeyFile  string
+
+	Issuer          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// SessionConfig holds all cookie session related configuration
+type SessionConfig struct {
+	// IdleTimeout ends sessions not used for this long; AbsoluteTimeout ends
+	// them this long after login regardless of activity
+	IdleTimeout     time.Duration
+	AbsoluteTimeout time.Duration
+
+	CookieName   string
+	CookieSecure bool
+	// CookieSameSite is either "strict" or "lax"
+	CookieSameSite string
+}
+
+// TOTPConfig holds all two-factor authentication related configuration
+type TOTPConfig struct {
+	// Issuer names the application in authenticator apps
+	Issuer string
+	// Skew is how many 30 second steps a code may be early or late
+	Skew int
+}
+
+// MailConfig holds all outgoing email related configuration
+type MailConfig struct {
+	// Driver is "log" to write messages to the application log, "file" to
+	// append them to File, or "smtp" to send them through an SMTP server
+	Driver string
+	From   string
+	File   string
+
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// AccountConfig holds all email verification and password reset related configuration
+type AccountConfig struct {
+	// BaseURL is where the links in account emails point to
+	BaseURL              string
+	EmailVerificationTTL time.Duration
+	PasswordResetTTL     time.Duration
+}
+
+// LoginConfig holds all brute-force protection related configuration
+type LoginConfig struct {
+	// MaxAttempts is how many failed logins lock an account; MaxIPAttempts is
+	// how many lock out a client address, whichever accounts it tried
+	MaxAttempts   int
+	MaxIPAttempts int
+
+	// Backoff is the delay after the first failure, doubling with each further
+	// one up to MaxBackoff. LockoutDuration is how long a lockout lasts and how
+	// long failures are remembered.
+	Backoff         time.Duration
+	MaxBackoff      time.Duration
+	LockoutDuration time.Duration
+}
+
+// PaginationConfig holds all list pagination related configuration
+type PaginationConfig struct {
+	// DefaultLimit is the page size when a request gives none; larger limits
+	// than MaxLimit are lowered to it
+	DefaultLimit int
+	MaxLimit     int
+
+	// CursorSecret signs pagination cursors. Instances behind the same load
+	// balancer must share it.
+	CursorSecret string
 }
 
 // LoggerConfig holds all logger related configuration
@@ -37,13 +163,55 @@ type LoggerConfig struct {
 	Level string
 }
 
-// NewConfig creates a new Config
-func NewConfig() *Config {
-	return &Config{
-		Server:   loadServerConfig(),
-		Database: loadDatabaseConfig(),
-		Logger:   loadLoggerConfig(),
+// NewConfig creates a new Config, failing if a setting has an invalid value
+func NewConfig() (*Config, error) {
+	trash, err := loadTrashConfig()
+	if err != nil {
+		return nil, err
+	}
+	passwords, err := loadPasswordConfig()
+	if err != nil {
+		return nil, err
+	}
+	jwt, err := loadJWTConfig()
+	if err != nil {
+		return nil, err
 	}
+	sessions, err := loadSessionConfig()
+	if err != nil {
+		return nil, err
+	}
+	twoFactor, err := loadTOTPConfig()
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := loadAccountConfig()
+	if err != nil {
+		return nil, err
+	}
+	logins, err := loadLoginConfig()
+	if err != nil {
+		return nil, err
+	}
+	pagination, err := loadPaginationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		Server:     loadServerConfig(),
+		Database:   loadDatabaseConfig(),
+		Trash:      trash,
+		Password:   passwords,
+		JWT:        jwt,
+		Session:    sessions,
+		TOTP:       twoFactor,
+		Mail:       loadMailConfig(),
+		Account:    accounts,
+		Login:      logins,
+		Pagination: pagination,
+		Logger:     loadLoggerConfig(),
+	}, nil
 }
 
 // loadServerConfig loads server configuration from environment variables
@@ -63,6 +231,12 @@ func loadServerConfig() ServerConfig {
 
 // loadDatabaseConfig loads database configuration from environment variables
 func loadDatabaseConfig() DatabaseConfig {
+	maxOpenConns, _ := strconv.Atoi(getEnv("DB_MAX_OPEN_CONNS", "25"))
+	maxIdleConns, _ := strconv.Atoi(getEnv("DB_MAX_IDLE_CONNS", "5"))
+	connMaxLifetime, _ := strconv.Atoi(getEnv("DB_CONN_MAX_LIFETIME", "300"))
+	connMaxIdleTime, _ := strconv.Atoi(getEnv("DB_CONN_MAX_IDLE_TIME", "60"))
+	autoMigrate, _ := strconv.ParseBool(getEnv("DB_AUTO_MIGRATE", "false"))
+
 	return DatabaseConfig{
 		Driver:   getEnv("DB_DRIVER", "postgres"),
 		Host:     getEnv("DB_HOST", "localhost"),
@@ -71,9 +245,197 @@ func loadDatabaseConfig() DatabaseConfig {
 		Password: getEnv("DB_PASSWORD", "postgres"),
 		Name:     getEnv("DB_NAME", "go_clean_api"),
 		SSLMode:  getEnv("DB_SSL_MODE", "disable"),
+
+		AutoMigrate: autoMigrate,
+
+		MaxOpenConns:    maxOpenConns,
+		MaxIdleConns:    maxIdleConns,
+		ConnMaxLifetime: time.Duration(connMaxLifetime) * time.Second,
+		ConnMaxIdleTime: time.Duration(connMaxIdleTime) * time.Second,
 	}
 }
 
+// loadTrashConfig loads soft-delete configuration from environment variables
+func loadTrashConfig() (TrashConfig, error) {
+	retentionDays, err := getEnvInt("TRASH_RETENTION_DAYS", 30, 0)
+	if err != nil {
+		return TrashConfig{}, err
+	}
+	purgeInterval, err := getEnvInt("TRASH_PURGE_INTERVAL", 60, 1)
+	if err != nil {
+		return TrashConfig{}, err
+	}
+
+	return TrashConfig{
+		Retention:     time.Duration(retentionDays) * 24 * time.Hour,
+		PurgeInterval: time.Duration(purgeInterval) * time.Minute,
+	}, nil
+}
+
+// loadPasswordConfig loads password hashing configuration from environment variables
+func loadPasswordConfig() (PasswordConfig, error) {
+	bcryptCost, _ := strconv.Atoi(getEnv("PASSWORD_BCRYPT_COST", "12"))
+	argon2Memory, _ := strconv.ParseUint(getEnv("PASSWORD_ARGON2_MEMORY", "65536"), 10, 32)
+	argon2Iterations, _ := strconv.ParseUint(getEnv("PASSWORD_ARGON2_ITERATIONS", "3"), 10, 32)
+	argon2Parallelism, _ := strconv.ParseUint(getEnv("PASSWORD_ARGON2_PARALLELISM", "2"), 10, 8)
+	maxConcurrentHashes, err := getEnvInt("PASSWORD_MAX_CONCURRENT_HASHES", 4, 1)
+	if err != nil {
+		return PasswordConfig{}, err
+	}
+
+	return PasswordConfig{
+		Algorithm:           getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		BcryptCost:          bcryptCost,
+		Argon2Memory:        uint32(argon2Memory),
+		Argon2Iterations:    uint32(argon2Iterations),
+		Argon2Parallelism:   uint8(argon2Parallelism),
+		MaxConcurrentHashes: maxConcurrentHashes,
+	}, nil
+}
+
+// loadJWTConfig loads token signing configuration from environment variables
+func loadJWTConfig() (JWTConfig, error) {
+	accessTokenTTL, err := getEnvInt("JWT_ACCESS_TOKEN_TTL", 15, 1)
+	if err != nil {
+		return JWTConfig{}, err
+	}
+	refreshTokenTTL, err := getEnvInt("JWT_REFRESH_TOKEN_TTL", 10080, 1)
+	if err != nil {
+		return JWTConfig{}, err
+	}
+
+	return JWTConfig{
+		Algorithm:       getEnv("JWT_ALGORITHM", "HS256"),
+		Secret:          getEnv("JWT_SECRET", ""),
+		PrivateKeyFile:  getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		PublicKeyFile:   getEnv("JWT_PUBLIC_KEY_FILE", ""),
+		Issuer:          getEnv("JWT_ISSUER", "go-clean-boilerplate"),
+		AccessTokenTTL:  time.Duration(accessTokenTTL) * time.Minute,
+		RefreshTokenTTL: time.Duration(refreshTokenTTL) * time.Minute,
+	}, nil
+}
+
+// loadSessionConfig loads cookie session configuration from environment variables
+func loadSessionConfig() (SessionConfig, error) {
+	idleTimeout, err := getEnvInt("SESSION_IDLE_TIMEOUT", 30, 1)
+	if err != nil {
+		return SessionConfig{}, err
+	}
+	absoluteTimeout, err := getEnvInt("SESSION_ABSOLUTE_TIMEOUT", 720, 1)
+	if err != nil {
+		return SessionConfig{}, err
+	}
+	cookieSecure, _ := strconv.ParseBool(getEnv("SESSION_COOKIE_SECURE", "true"))
+
+	return SessionConfig{
+		IdleTimeout:     time.Duration(idleTimeout) * time.Minute,
+		AbsoluteTimeout: time.Duration(absoluteTimeout) * time.Minute,
+		CookieName:      getEnv("SESSION_COOKIE_NAME", "session"),
+		CookieSecure:    cookieSecure,
+		CookieSameSite:  getEnv("SESSION_COOKIE_SAMESITE", "strict"),
+	}, nil
+}
+
+// loadTOTPConfig loads two-factor authentication configuration from environment variables
+func loadTOTPConfig() (TOTPConfig, error) {
+	skew, err := getEnvInt("TOTP_SKEW", 1, 0)
+	if err != nil {
+		return TOTPConfig{}, err
+	}
+
+	return TOTPConfig{
+		Issuer: getEnv("TOTP_ISSUER", "go-clean-boilerplate"),
+		Skew:   skew,
+	}, nil
+}
+
+// loadMailConfig loads outgoing email configuration from environment variables
+func loadMailConfig() MailConfig {
+	return MailConfig{
+		Driver:       getEnv("MAIL_DRIVER", "log"),
+		From:         getEnv("MAIL_FROM", "no-reply@localhost"),
+		File:         getEnv("MAIL_FILE", "mail.log"),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+	}
+}
+
+// loadAccountConfig loads email verification and password reset configuration from environment variables
+func loadAccountConfig() (AccountConfig, error) {
+	emailVerificationTTL, err := getEnvInt("EMAIL_VERIFICATION_TTL", 1440, 1)
+	if err != nil {
+		return AccountConfig{}, err
+	}
+	passwordResetTTL, err := getEnvInt("PASSWORD_RESET_TTL", 60, 1)
+	if err != nil {
+		return AccountConfig{}, err
+	}
+
+	return AccountConfig{
+		BaseURL:              getEnv("APP_BASE_URL", "http://localhost:8080"),
+		EmailVerificationTTL: time.Duration(emailVerificationTTL) * time.Minute,
+		PasswordResetTTL:     time.Duration(passwordResetTTL) * time.Minute,
+	}, nil
+}
+
+// loadLoginConfig loads brute-force protection configuration from environment variables
+func loadLoginConfig() (LoginConfig, error) {
+	maxAttempts, err := getEnvInt("LOGIN_MAX_ATTEMPTS", 5, 1)
+	if err != nil {
+		return LoginConfig{}, err
+	}
+	maxIPAttempts, err := getEnvInt("LOGIN_IP_MAX_ATTEMPTS", 50, 1)
+	if err != nil {
+		return LoginConfig{}, err
+	}
+	backoff, err := getEnvInt("LOGIN_BACKOFF", 1, 0)
+	if err != nil {
+		return LoginConfig{}, err
+	}
+	maxBackoff, err := getEnvInt("LOGIN_MAX_BACKOFF", 60, 0)
+	if err != nil {
+		return LoginConfig{}, err
+	}
+	if backoff > maxBackoff {
+		return LoginConfig{}, fmt.Errorf("LOGIN_BACKOFF %d exceeds LOGIN_MAX_BACKOFF %d", backoff, maxBackoff)
+	}
+	lockoutDuration, err := getEnvInt("LOGIN_LOCKOUT_DURATION", 15, 1)
+	if err != nil {
+		return LoginConfig{}, err
+	}
+
+	return LoginConfig{
+		MaxAttempts:     maxAttempts,
+		MaxIPAttempts:   maxIPAttempts,
+		Backoff:         time.Duration(backoff) * time.Second,
+		MaxBackoff:      time.Duration(maxBackoff) * time.Second,
+		LockoutDuration: time.Duration(lockoutDuration) * time.Minute,
+	}, nil
+}
+
+// loadPaginationConfig loads list pagination configuration from environment variables
+func loadPaginationConfig() (PaginationConfig, error) {
+	defaultLimit, err := getEnvInt("PAGINATION_DEFAULT_LIMIT", 10, 1)
+	if err != nil {
+		return PaginationConfig{}, err
+	}
+	maxLimit, err := getEnvInt("PAGINATION_MAX_LIMIT", 100, 1)
+	if err != nil {
+		return PaginationConfig{}, err
+	}
+	if defaultLimit > maxLimit {
+		return PaginationConfig{}, fmt.Errorf("PAGINATION_DEFAULT_LIMIT %d exceeds PAGINATION_MAX_LIMIT %d", defaultLimit, maxLimit)
+	}
+
+	return PaginationConfig{
+		DefaultLimit: defaultLimit,
+		MaxLimit:     maxLimit,
+		CursorSecret: getEnv("PAGINATION_CURSOR_SECRET", ""),
+	}, nil
+}
+
 // loadLoggerConfig loads logger configuration from environment variables
 func loadLoggerConfig() LoggerConfig {
 	return LoggerConfig{
@@ -89,3 +451,18 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvInt gets an environment variable holding an integer of at least
+// minValue or returns a default value, failing if it holds anything else
+func getEnvInt(key string, defaultValue, minValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < minValue {
+		return 0, fmt.Errorf("%s must be an integer of at least %d, got %q", key, minValue, value)
+	}
+	return n, nil
+}
@@ -0,0 +1,329 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewConfigTrash(t *testing.T) {
+	tests := []struct {
+		name          string
+		retentionDays string
+		purgeInterval string
+		want          TrashConfig
+		// wantErr is the variable the error names, if loading fails
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			want: TrashConfig{Retention: 30 * 24 * time.Hour, PurgeInterval: time.Hour},
+		},
+		{
+			name:          "purging right away",
+			retentionDays: "0",
+			purgeInterval: "5",
+			want:          TrashConfig{Retention: 0, PurgeInterval: 5 * time.Minute},
+		},
+		{name: "zero interval", purgeInterval: "0", wantErr: "TRASH_PURGE_INTERVAL"},
+		{name: "negative interval", purgeInterval: "-1", wantErr: "TRASH_PURGE_INTERVAL"},
+		{name: "interval with a unit", purgeInterval: "1h", wantErr: "TRASH_PURGE_INTERVAL"},
+		{name: "negative retention", retentionDays: "-30", wantErr: "TRASH_RETENTION_DAYS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRASH_RETENTION_DAYS", tt.retentionDays)
+			t.Setenv("TRASH_PURGE_INTERVAL", tt.purgeInterval)
+
+			cfg, err := NewConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewConfig() error = %v, want an error about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewConfig() error = %v", err)
+			}
+			if cfg.Trash != tt.want {
+				t.Errorf("Trash = %+v, want %+v", cfg.Trash, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewConfigPagination(t *testing.T) {
+	tests := []struct {
+		name         string
+		defaultLimit string
+		maxLimit     string
+		want         PaginationConfig
+		// wantErr is the variable the error names, if loading fails
+		wantErr string
+	}{
+		{name: "defaults", want: PaginationConfig{DefaultLimit: 10, MaxLimit: 100}},
+		{name: "custom", defaultLimit: "25", maxLimit: "50", want: PaginationConfig{DefaultLimit: 25, MaxLimit: 50}},
+		{name: "zero default", defaultLimit: "0", wantErr: "PAGINATION_DEFAULT_LIMIT"},
+		{name: "negative default", defaultLimit: "-10", wantErr: "PAGINATION_DEFAULT_LIMIT"},
+		{name: "invalid default", defaultLimit: "ten", wantErr: "PAGINATION_DEFAULT_LIMIT"},
+		{name: "zero maximum", maxLimit: "0", wantErr: "PAGINATION_MAX_LIMIT"},
+		{name: "default above maximum", defaultLimit: "50", maxLimit: "20", wantErr: "PAGINATION_MAX_LIMIT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PAGINATION_DEFAULT_LIMIT", tt.defaultLimit)
+			t.Setenv("PAGINATION_MAX_LIMIT", tt.maxLimit)
+			t.Setenv("PAGINATION_CURSOR_SECRET", "")
+
+			cfg, err := NewConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewConfig() error = %v, want an error about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewConfig() error = %v", err)
+			}
+			if cfg.Pagination != tt.want {
+				t.Errorf("Pagination = %+v, want %+v", cfg.Pagination, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewConfigMaxConcurrentHashes(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  int
+		// wantErr tells that loading fails
+		wantErr bool
+	}{
+		{name: "default", want: 4},
+		{name: "custom", value: "16", want: 16},
+		{name: "zero", value: "0", wantErr: true},
+		{name: "invalid", value: "many", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PASSWORD_MAX_CONCURRENT_HASHES", tt.value)
+
+			cfg, err := NewConfig()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "PASSWORD_MAX_CONCURRENT_HASHES") {
+					t.Fatalf("NewConfig() error = %v, want an error about PASSWORD_MAX_CONCURRENT_HASHES", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewConfig() error = %v", err)
+			}
+			if cfg.Password.MaxConcurrentHashes != tt.want {
+				t.Errorf("MaxConcurrentHashes = %d, want %d", cfg.Password.MaxConcurrentHashes, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewConfigJWT(t *testing.T) {
+	tests := []struct {
+		name            string
+		accessTokenTTL  string
+		refreshTokenTTL string
+		wantAccess      time.Duration
+		wantRefresh     time.Duration
+		// wantErr is the variable the error names, if loading fails
+		wantErr string
+	}{
+		{name: "defaults", wantAccess: 15 * time.Minute, wantRefresh: 7 * 24 * time.Hour},
+		{name: "custom", accessTokenTTL: "5", refreshTokenTTL: "60", wantAccess: 5 * time.Minute, wantRefresh: time.Hour},
+		{name: "zero access token lifetime", accessTokenTTL: "0", wantErr: "JWT_ACCESS_TOKEN_TTL"},
+		{name: "access token lifetime with a unit", accessTokenTTL: "15m", wantErr: "JWT_ACCESS_TOKEN_TTL"},
+		{name: "negative refresh token lifetime", refreshTokenTTL: "-1", wantErr: "JWT_REFRESH_TOKEN_TTL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_ACCESS_TOKEN_TTL", tt.accessTokenTTL)
+			t.Setenv("JWT_REFRESH_TOKEN_TTL", tt.refreshTokenTTL)
+
+			cfg, err := NewConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewConfig() error = %v, want an error about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewConfig() error = %v", err)
+			}
+			if cfg.JWT.AccessTokenTTL != tt.wantAccess || cfg.JWT.RefreshTokenTTL != tt.wantRefresh {
+				t.Errorf("token lifetimes = %s and %s, want %s and %s", cfg.JWT.AccessTokenTTL, cfg.JWT.RefreshTokenTTL, tt.wantAccess, tt.wantRefresh)
+			}
+		})
+	}
+}
+
+func TestNewConfigSession(t *testing.T) {
+	tests := []struct {
+		name            string
+		idleTimeout     string
+		absoluteTimeout string
+		wantIdle        time.Duration
+		wantAbsolute    time.Duration
+		// wantErr is the variable the error names, if loading fails
+		wantErr string
+	}{
+		{name: "defaults", wantIdle: 30 * time.Minute, wantAbsolute: 12 * time.Hour},
+		{name: "custom", idleTimeout: "10", absoluteTimeout: "60", wantIdle: 10 * time.Minute, wantAbsolute: time.Hour},
+		{name: "zero idle timeout", idleTimeout: "0", wantErr: "SESSION_IDLE_TIMEOUT"},
+		{name: "invalid idle timeout", idleTimeout: "half an hour", wantErr: "SESSION_IDLE_TIMEOUT"},
+		{name: "zero absolute timeout", absoluteTimeout: "0", wantErr: "SESSION_ABSOLUTE_TIMEOUT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SESSION_IDLE_TIMEOUT", tt.idleTimeout)
+			t.Setenv("SESSION_ABSOLUTE_TIMEOUT", tt.absoluteTimeout)
+
+			cfg, err := NewConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewConfig() error = %v, want an error about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewConfig() error = %v", err)
+			}
+			if cfg.Session.IdleTimeout != tt.wantIdle || cfg.Session.AbsoluteTimeout != tt.wantAbsolute {
+				t.Errorf("session timeouts = %s and %s, want %s and %s", cfg.Session.IdleTimeout, cfg.Session.AbsoluteTimeout, tt.wantIdle, tt.wantAbsolute)
+			}
+		})
+	}
+}
+
+func TestNewConfigTOTPSkew(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  int
+		// wantErr tells that loading fails
+		wantErr bool
+	}{
+		{name: "default", want: 1},
+		{name: "no skew", value: "0", want: 0},
+		{name: "negative", value: "-1", wantErr: true},
+		{name: "invalid", value: "one", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TOTP_SKEW", tt.value)
+
+			cfg, err := NewConfig()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "TOTP_SKEW") {
+					t.Fatalf("NewConfig() error = %v, want an error about TOTP_SKEW", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewConfig() error = %v", err)
+			}
+			if cfg.TOTP.Skew != tt.want {
+				t.Errorf("Skew = %d, want %d", cfg.TOTP.Skew, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewConfigAccount(t *testing.T) {
+	tests := []struct {
+		name                 string
+		emailVerificationTTL string
+		passwordResetTTL     string
+		wantVerification     time.Duration
+		wantReset            time.Duration
+		// wantErr is the variable the error names, if loading fails
+		wantErr string
+	}{
+		{name: "defaults", wantVerification: 24 * time.Hour, wantReset: time.Hour},
+		{name: "custom", emailVerificationTTL: "60", passwordResetTTL: "15", wantVerification: time.Hour, wantReset: 15 * time.Minute},
+		{name: "zero verification lifetime", emailVerificationTTL: "0", wantErr: "EMAIL_VERIFICATION_TTL"},
+		{name: "invalid reset lifetime", passwordResetTTL: "1h", wantErr: "PASSWORD_RESET_TTL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("EMAIL_VERIFICATION_TTL", tt.emailVerificationTTL)
+			t.Setenv("PASSWORD_RESET_TTL", tt.passwordResetTTL)
+
+			cfg, err := NewConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewConfig() error = %v, want an error about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewConfig() error = %v", err)
+			}
+			if cfg.Account.EmailVerificationTTL != tt.wantVerification || cfg.Account.PasswordResetTTL != tt.wantReset {
+				t.Errorf("link lifetimes = %s and %s, want %s and %s", cfg.Account.EmailVerificationTTL, cfg.Account.PasswordResetTTL, tt.wantVerification, tt.wantReset)
+			}
+		})
+	}
+}
+
+func TestNewConfigLogin(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want LoginConfig
+		// wantErr is the variable the error names, if loading fails
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			want: LoginConfig{MaxAttempts: 5, MaxIPAttempts: 50, Backoff: time.Second, MaxBackoff: time.Minute, LockoutDuration: 15 * time.Minute},
+		},
+		{
+			name: "without backoff",
+			env:  map[string]string{"LOGIN_BACKOFF": "0", "LOGIN_MAX_BACKOFF": "0"},
+			want: LoginConfig{MaxAttempts: 5, MaxIPAttempts: 50, LockoutDuration: 15 * time.Minute},
+		},
+		{name: "zero attempts", env: map[string]string{"LOGIN_MAX_ATTEMPTS": "0"}, wantErr: "LOGIN_MAX_ATTEMPTS"},
+		{name: "invalid attempts", env: map[string]string{"LOGIN_MAX_ATTEMPTS": "five"}, wantErr: "LOGIN_MAX_ATTEMPTS"},
+		{name: "zero address attempts", env: map[string]string{"LOGIN_IP_MAX_ATTEMPTS": "0"}, wantErr: "LOGIN_IP_MAX_ATTEMPTS"},
+		{name: "negative backoff", env: map[string]string{"LOGIN_BACKOFF": "-1"}, wantErr: "LOGIN_BACKOFF"},
+		{name: "backoff above maximum", env: map[string]string{"LOGIN_BACKOFF": "120"}, wantErr: "LOGIN_MAX_BACKOFF"},
+		{name: "zero lockout", env: map[string]string{"LOGIN_LOCKOUT_DURATION": "0"}, wantErr: "LOGIN_LOCKOUT_DURATION"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"LOGIN_MAX_ATTEMPTS", "LOGIN_IP_MAX_ATTEMPTS", "LOGIN_BACKOFF", "LOGIN_MAX_BACKOFF", "LOGIN_LOCKOUT_DURATION"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			cfg, err := NewConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewConfig() error = %v, want an error about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewConfig() error = %v", err)
+			}
+			if cfg.Login != tt.want {
+				t.Errorf("Login = %+v, want %+v", cfg.Login, tt.want)
+			}
+		})
+	}
+}
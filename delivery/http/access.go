@@ -0,0 +1,24 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/usecase"
+)
+
+// writeAccessError responds with 401 Unauthorized or 403 Forbidden if err reports
+// a missing principal or a denied operation, and reports whether it did
+func writeAccessError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, usecase.ErrUnauthenticated):
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+		writeProblem(w, r, "Authentication required", http.StatusUnauthorized)
+		return true
+	case errors.Is(err, usecase.ErrForbidden):
+		writeProblem(w, r, "You are not allowed to perform this operation", http.StatusForbidden)
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/usecase"
+)
+
+// AccountHandler represents the HTTP handler for email verification and password reset operations
+type AccountHandler struct {
+	accountUseCase *usecase.AccountUseCase
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(accountUseCase *usecase.AccountUseCase) *AccountHandler {
+	return &AccountHandler{
+		accountUseCase: accountUseCase,
+	}
+}
+
+// handleSendVerification handles POST /auth/verify-email/send
+func (h *AccountHandler) handleSendVerification(w http.ResponseWriter, r *http.Request) {
+	// Send verification email
+	err := h.accountUseCase.RequestVerification(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return success
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleVerifyEmail handles POST /auth/verify-email
+func (h *AccountHandler) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req struct {
+		Token string `json:"token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Verify email
+	err := h.accountUseCase.VerifyEmail(r.Context(), req.Token)
+	if errors.Is(err, usecase.ErrInvalidToken) {
+		writeProblem(w, r, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return success
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleForgotPassword handles POST /auth/forgot-password
+func (h *AccountHandler) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req struct {
+		Email string `json:"email"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Send password reset email
+	if err := h.accountUseCase.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return success, whether or not the address has an account
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleResetPassword handles POST /auth/reset-password
+func (h *AccountHandler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Reset password
+	err := h.accountUseCase.ResetPassword(r.Context(), req.Token, req.NewPassword)
+	if writeValidationErrors(w, r, err) {
+		return
+	}
+	if errors.Is(err, usecase.ErrInvalidToken) {
+		writeProblem(w, r, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return success
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/usecase"
+)
+
+// APIKeyHandler represents the HTTP handler for API key operations
+type APIKeyHandler struct {
+	apiKeyUseCase *usecase.APIKeyUseCase
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyUseCase *usecase.APIKeyUseCase) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyUseCase: apiKeyUseCase,
+	}
+}
+
+// createdAPIKeyResponse is the body returned when an API key is created. It is
+// the only response that ever contains the plaintext key.
+type createdAPIKeyResponse struct {
+	*entity.APIKey
+	Key string `json:"key"`
+}
+
+// getAPIKeys handles GET /api-keys
+func (h *APIKeyHandler) getAPIKeys(w http.ResponseWriter, r *http.Request) {
+	// List the caller's own keys unless another user is requested
+	principal, ok := usecase.PrincipalFromContext(r.Context())
+	if !ok {
+		writeAccessError(w, r, usecase.ErrUnauthenticated)
+		return
+	}
+
+	userID := principal.UserID
+	if userIDStr := r.URL.Query().Get("user_id"); userIDStr != "" {
+		parsedUserID, err := strconv.ParseUint(userIDStr, 10, 64)
+		if err != nil {
+			writeProblem(w, r, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		userID = parsedUserID
+	}
+
+	// Get API keys
+	keys, err := h.apiKeyUseCase.List(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return API keys
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// createAPIKey handles POST /api-keys
+func (h *APIKeyHandler) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req struct {
+		Name      string               `json:"name"`
+		Scopes    []entity.APIKeyScope `json:"scopes"`
+		ExpiresAt *time.Time           `json:"expires_at,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Create API key
+	key, secret, err := h.apiKeyUseCase.Create(r.Context(), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return API key
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdAPIKeyResponse{APIKey: key, Key: secret})
+}
+
+// revokeAPIKey handles DELETE /api-keys/{id}
+func (h *APIKeyHandler) revokeAPIKey(w http.ResponseWriter, r *http.Request, id uint64) {
+	// Revoke API key
+	key, err := h.apiKeyUseCase.Revoke(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return API key
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(key)
+}
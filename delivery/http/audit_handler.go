@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/usecase"
+)
+
+// AuditHandler represents the HTTP handler for audit log operations
+type AuditHandler struct {
+	auditUseCase *usecase.AuditUseCase
+	pagination   Pagination
+}
+
+// NewAuditHandler creates a new audit log handler
+func NewAuditHandler(auditUseCase *usecase.AuditUseCase, pagination Pagination) *AuditHandler {
+	return &AuditHandler{
+		auditUseCase: auditUseCase,
+		pagination:   pagination,
+	}
+}
+
+// handleAudit handles GET /audit
+func (h *AuditHandler) handleAudit(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	page, ok := h.pagination.page(w, r)
+	if !ok {
+		return
+	}
+
+	// Get audit entries
+	entries, total, err := h.auditUseCase.List(r.Context(), page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return audit entries
+	writePage(w, r, h.pagination, page, entries, total, auditCursor)
+}
+
+// auditCursor returns the cursor of an audit entry, which are ordered by ID
+func auditCursor(entry *entity.AuditEntry) repository.Cursor {
+	return repository.IDCursor(entry.ID)
+}
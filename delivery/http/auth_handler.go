@@ -0,0 +1,157 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/delivery/http/middleware"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/usecase"
+)
+
+// AuthHandler represents the HTTP handler for authentication operations
+type AuthHandler struct {
+	authUseCase *usecase.AuthUseCase
+}
+
+// NewAuthHandler creates a new authentication handler
+func NewAuthHandler(authUseCase *usecase.AuthUseCase) *AuthHandler {
+	return &AuthHandler{
+		authUseCase: authUseCase,
+	}
+}
+
+// tokenResponse is the body returned when tokens are issued
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int64  `json:"refresh_expires_in"`
+}
+
+// handleLogin handles POST /auth/login
+func (h *AuthHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+		TOTPCode string `json:"totp_code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Log in
+	tokens, err := h.authUseCase.Login(r.Context(), req.Email, req.Password, req.TOTPCode, clientIP(r))
+	if writeLoginError(w, r, err) {
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeTokens(w, tokens)
+}
+
+// handleRefresh handles POST /auth/refresh
+func (h *AuthHandler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Refresh tokens
+	tokens, err := h.authUseCase.Refresh(r.Context(), req.RefreshToken)
+	if errors.Is(err, usecase.ErrInvalidToken) {
+		writeProblem(w, r, "Invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeTokens(w, tokens)
+}
+
+// handleMe handles GET /auth/me
+func (h *AuthHandler) handleMe(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user
+	user, err := h.authUseCase.CurrentUser(r.Context())
+	if err != nil {
+		writeProblem(w, r, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	// Return user
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, user.Version)
+	json.NewEncoder(w).Encode(user)
+}
+
+// writeLoginError responds with 401 Unauthorized if err reports rejected login
+// credentials, or 429 Too Many Requests if it reports throttled ones, and reports
+// whether it did
+func writeLoginError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case writeThrottledError(w, r, err):
+	case errors.Is(err, usecase.ErrInvalidCredentials):
+		writeProblem(w, r, "Invalid email or password", http.StatusUnauthorized)
+	case errors.Is(err, usecase.ErrTOTPRequired):
+		writeProblem(w, r, "Two-factor code required", http.StatusUnauthorized)
+	case errors.Is(err, usecase.ErrInvalidTOTPCode):
+		writeProblem(w, r, "Invalid two-factor code", http.StatusUnauthorized)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// writeThrottledError responds with 429 Too Many Requests and a Retry-After
+// header if err reports a credential check refused after too many failed
+// attempts, and reports whether it did
+func writeThrottledError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var throttled *usecase.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+
+	retryAfter := int64(math.Ceil(throttled.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	middleware.WriteProblem(w, r, middleware.Problem{
+		Type:   problemTypeTooManyAttempts,
+		Title:  "Too many attempts",
+		Status: http.StatusTooManyRequests,
+		Detail: "Too many failed attempts, try again later",
+	})
+
+	return true
+}
+
+// writeTokens writes issued tokens in the OAuth 2.0 token response format
+func writeTokens(w http.ResponseWriter, tokens *usecase.AuthTokens) {
+	now := time.Now()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(tokenResponse{
+		AccessToken:      tokens.AccessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(tokens.AccessTokenExpiresAt.Sub(now).Round(time.Second).Seconds()),
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresIn: int64(tokens.RefreshTokenExpiresAt.Sub(now).Round(time.Second).Seconds()),
+	})
+}
@@ -0,0 +1,45 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// setETag exposes an entity version as a strong entity tag
+func setETag(w http.ResponseWriter, version uint64) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatUint(version, 10)))
+}
+
+// ifMatchVersion returns the version required by the If-Match header,
+// or 0 when the header is absent or "*"
+func ifMatchVersion(r *http.Request) (uint64, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	// If-Match uses strong comparison, so weak tags can never match
+	tag, err := strconv.Unquote(value)
+	if err != nil || strings.HasPrefix(value, "W/") {
+		return 0, errors.New("If-Match must be a single strong entity tag")
+	}
+
+	version, err := strconv.ParseUint(tag, 10, 64)
+	if err != nil || version == 0 {
+		return 0, errors.New("If-Match does not match the current entity tag")
+	}
+
+	return version, nil
+}
+
+// conflictStatus returns the status for a write rejected because of a stale version:
+// 412 when the client made the request conditional with If-Match, 409 otherwise
+func conflictStatus(r *http.Request) int {
+	if r.Header.Get("If-Match") != "" {
+		return http.StatusPreconditionFailed
+	}
+
+	return http.StatusConflict
+}
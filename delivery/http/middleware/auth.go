@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/usecase"
+)
+
+// Authenticator resolves the principal a bearer token or API key was issued for
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*entity.Principal, error)
+}
+
+// Authenticate is a middleware that requires a valid bearer token or X-API-Key header on
+// every request not reported as public, and stores the authenticated principal in the
+// request context
+func Authenticate(authenticator Authenticator, public func(r *http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Let requests already authenticated by their session cookie through
+			if _, ok := usecase.PrincipalFromContext(r.Context()); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, hasToken := bearerToken(r)
+			if !hasToken {
+				token, hasToken = apiKey(r)
+			}
+
+			if !hasToken {
+				if public(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				WriteProblem(w, r, Problem{Status: http.StatusUnauthorized, Detail: "Authentication required"})
+				return
+			}
+
+			principal, err := authenticator.Authenticate(r.Context(), token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+				WriteProblem(w, r, Problem{Status: http.StatusUnauthorized, Detail: "Invalid or expired token"})
+				return
+			}
+
+			// Call the next handler with the principal available to use cases
+			next.ServeHTTP(w, r.WithContext(usecase.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// apiKey extracts the key from an "X-API-Key: <key>" header
+func apiKey(r *http.Request) (string, bool) {
+	key := strings.TrimSpace(r.Header.Get("X-API-Key"))
+	return key, key != ""
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
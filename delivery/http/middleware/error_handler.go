@@ -1,30 +1,108 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
 )
 
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Status  int    `json:"status"`
+// ProblemContentType is the media type of RFC 7807 problem documents
+const ProblemContentType = "application/problem+json"
+
+// RequestIDHeader carries the ID that identifies a request in logs and problem documents
+const RequestIDHeader = "X-Request-ID"
+
+// Problem represents an RFC 7807 problem document describing a failed request
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// Errors lists the invalid fields of a request that failed validation
+	Errors []entity.FieldError `json:"errors,omitempty"`
+}
+
+// requestIDKey marks the request ID in a context
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the ID ErrorHandler assigned to the request carrying ctx
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// causeKey marks the holder of the error that made a request fail in a context
+type causeKey struct{}
+
+// RecordError keeps err as the cause of the failure of the request carrying
+// ctx, so ErrorHandler logs it with the error response. Errors a successful
+// response does not report are logged once the request is done. Causes are
+// only logged, never sent to the client.
+func RecordError(ctx context.Context, err error) {
+	if cause, ok := ctx.Value(causeKey{}).(*error); ok {
+		*cause = err
+	}
+}
+
+// WriteProblem responds to r with problem. A missing type defaults to
+// about:blank and a missing title to the text of the status; the instance and
+// request ID are taken from r.
+func WriteProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	problem.Instance = r.URL.Path
+	problem.RequestID = RequestIDFromContext(r.Context())
+
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Type", ProblemContentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
 }
 
-// ErrorHandler is a middleware that handles errors
+// ErrorHandler is a middleware that assigns every request an ID and makes sure
+// every error response is a problem document. Error responses written as plain
+// text, such as those of http.Error or http.ServeMux, are turned into problem
+// documents with the text as their detail.
 func ErrorHandler(logger *log.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Keep the caller's request ID so requests can be traced across services
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
 			// Create a custom response writer to capture errors
 			ew := &errorWriter{
 				ResponseWriter: w,
 				logger:         logger,
+				requestID:      requestID,
 			}
 
+			ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+			r = r.WithContext(context.WithValue(ctx, causeKey{}, &ew.cause))
+
 			// Call the next handler
 			next.ServeHTTP(ew, r)
+
+			ew.finish(r)
 		})
 	}
 }
@@ -32,30 +110,88 @@ func ErrorHandler(logger *log.Logger) func(http.Handler) http.Handler {
 // errorWriter is a custom response writer that handles errors
 type errorWriter struct {
 	http.ResponseWriter
-	logger *log.Logger
+	logger    *log.Logger
+	requestID string
+	// cause is the error recorded with RecordError, if any, and logged
+	// tells that it was logged with the status of the response
+	cause  error
+	logged bool
+
+	// status and detail hold a plain text error response being rewritten
+	status int
+	detail bytes.Buffer
 }
 
-// WriteHeader overrides the WriteHeader method to log error status codes
+// WriteHeader overrides the WriteHeader method to log error status codes and
+// hold back error responses that are not problem documents yet
 func (ew *errorWriter) WriteHeader(code int) {
 	if code >= 400 {
-		ew.logger.Printf("Error: %d", code)
+		if ew.cause != nil {
+			ew.logger.Printf("Error: %d (request %s): %v", code, ew.requestID, ew.cause)
+			ew.logged = true
+		} else {
+			ew.logger.Printf("Error: %d (request %s)", code, ew.requestID)
+		}
+
+		if !strings.HasPrefix(ew.Header().Get("Content-Type"), ProblemContentType) {
+			ew.status = code
+			return
+		}
 	}
+
 	ew.ResponseWriter.WriteHeader(code)
 }
 
-// Error writes an error response
-func (ew *errorWriter) Error(err error, message string, status int) {
-	ew.logger.Printf("Error: %s", err.Error())
+// Write collects the body of an error response being rewritten
+func (ew *errorWriter) Write(b []byte) (int, error) {
+	if ew.status != 0 {
+		return ew.detail.Write(b)
+	}
+
+	return ew.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped response writer for http.ResponseController
+func (ew *errorWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// finish logs a recorded error the response did not report and writes the
+// problem document replacing a plain text error response, if there was one
+func (ew *errorWriter) finish(r *http.Request) {
+	if ew.cause != nil && !ew.logged {
+		ew.logger.Printf("Error (request %s): %v", ew.requestID, ew.cause)
+	}
 
-	// Create error response
-	resp := ErrorResponse{
-		Error:   err.Error(),
-		Message: message,
-		Status:  status,
+	if ew.status == 0 {
+		return
 	}
 
-	// Write response
-	ew.ResponseWriter.Header().Set("Content-Type", "application/json")
-	ew.ResponseWriter.WriteHeader(status)
-	json.NewEncoder(ew.ResponseWriter).Encode(resp)
-}
\ No newline at end of file
+	WriteProblem(ew.ResponseWriter, r, Problem{
+		Status: ew.status,
+		Detail: strings.TrimSpace(ew.detail.String()),
+	})
+}
+
+// validRequestID reports whether a caller-supplied request ID is safe to reuse
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for _, c := range id {
+		isAlphanumeric := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+		if !isAlphanumeric && c != '-' && c != '_' && c != '.' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
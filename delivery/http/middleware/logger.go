@@ -23,12 +23,13 @@ func Logger(logger *log.Logger) func(http.Handler) http.Handler {
 
 			// Log the request
 			logger.Printf(
-				"%s %s %s %d %s",
+				"%s %s %s %d %s %s",
 				r.Method,
 				r.RequestURI,
 				r.RemoteAddr,
 				rw.statusCode,
 				time.Since(start),
+				RequestIDFromContext(r.Context()),
 			)
 		})
 	}
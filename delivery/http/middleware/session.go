@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/usecase"
+)
+
+// CSRFHeader is the request header carrying the CSRF token of a cookie session
+const CSRFHeader = "X-CSRF-Token"
+
+// SessionAuthenticator resolves the session a cookie token belongs to
+type SessionAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*entity.Principal, *entity.Session, error)
+}
+
+// Session is a middleware that authenticates requests by their session cookie and
+// stores the principal in the request context. Requests carrying a bearer token or
+// API key are left to Authenticate. State-changing requests for which protected
+// reports true must repeat the session's CSRF token in the X-CSRF-Token header.
+func Session(sessions SessionAuthenticator, cookieName string, protected func(r *http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, hasToken := bearerToken(r)
+			_, hasAPIKey := apiKey(r)
+			cookie, err := r.Cookie(cookieName)
+			if hasToken || hasAPIKey || err != nil || cookie.Value == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, session, err := sessions.Authenticate(r.Context(), cookie.Value)
+			if err != nil {
+				// Drop the stale cookie and carry on unauthenticated
+				http.SetCookie(w, &http.Cookie{Name: cookieName, Path: "/", MaxAge: -1})
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !safeMethod(r.Method) && protected(r) {
+				csrfToken := r.Header.Get(CSRFHeader)
+				if subtle.ConstantTimeCompare([]byte(csrfToken), []byte(session.CSRFToken)) != 1 {
+					WriteProblem(w, r, Problem{Status: http.StatusForbidden, Detail: "Invalid or missing CSRF token"})
+					return
+				}
+			}
+
+			// Call the next handler with the principal available to use cases
+			next.ServeHTTP(w, r.WithContext(usecase.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// safeMethod reports whether an HTTP method is defined not to change server state
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,196 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Pagination holds the page sizes of list endpoints and the key signing their cursors
+type Pagination struct {
+	DefaultLimit int
+	MaxLimit     int
+	CursorSecret []byte
+}
+
+// page parses the limit, offset and cursor query parameters of r. Missing or
+// invalid limits and offsets fall back to the defaults, and limits above the
+// maximum are lowered to it. Pages never hold fewer than one item. The returned
+// page asks for one item more than the client did, so writePage can tell whether
+// another page follows. It writes an error response and returns false if the
+// cursor is invalid.
+func (p Pagination) page(w http.ResponseWriter, r *http.Request) (repository.Page, bool) {
+	limit := p.DefaultLimit
+	if parsedLimit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && parsedLimit > 0 {
+		limit = parsedLimit
+	}
+	if p.MaxLimit > 0 && limit > p.MaxLimit {
+		limit = p.MaxLimit
+	}
+	// An empty page would link to itself as the next one
+	limit = max(limit, 1)
+
+	offset := 0
+	if parsedOffset, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && parsedOffset >= 0 {
+		offset = parsedOffset
+	}
+
+	page := repository.Page{Limit: limit + 1, Offset: offset}
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		cursor, err := p.decodeCursor(r, token)
+		if err != nil {
+			writeProblem(w, r, "Invalid cursor", http.StatusBadRequest)
+			return page, false
+		}
+		page.After = &cursor
+	}
+
+	return page, true
+}
+
+// cursorPayload is the signed content of a cursor
+type cursorPayload struct {
+	Key string `json:"k,omitempty"`
+	ID  uint64 `json:"id"`
+}
+
+// encodeCursor returns cursor as an opaque token for the list at the path of r
+func (p Pagination) encodeCursor(r *http.Request, cursor repository.Cursor) string {
+	payload, _ := json.Marshal(cursorPayload{Key: cursor.Key, ID: cursor.ID})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(p.signCursor(r, encoded))
+}
+
+// decodeCursor returns the cursor in a token issued by encodeCursor for the
+// list at the path of r
+func (p Pagination) decodeCursor(r *http.Request, token string) (repository.Cursor, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return repository.Cursor{}, errors.New("malformed cursor")
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, p.signCursor(r, encoded)) {
+		return repository.Cursor{}, errors.New("invalid cursor signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return repository.Cursor{}, err
+	}
+
+	var cursor cursorPayload
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return repository.Cursor{}, err
+	}
+
+	return repository.Cursor{Key: cursor.Key, ID: cursor.ID}, nil
+}
+
+// signCursor signs an encoded cursor for the list at the path of r, ordered as
+// its sort, order and search query parameters ask, so it cannot be forged or
+// used on another list or order
+func (p Pagination) signCursor(r *http.Request, encoded string) []byte {
+	signed := url.Values{
+		"sort":   {r.URL.Query().Get("sort")},
+		"order":  {r.URL.Query().Get("order")},
+		"q":      {r.URL.Query().Get("q")},
+		"cursor": {encoded},
+	}
+
+	mac := hmac.New(sha256.New, p.CursorSecret)
+	mac.Write([]byte(r.URL.Path + "?" + signed.Encode()))
+	return mac.Sum(nil)
+}
+
+// pageResponse is a page of a list along with what is needed to navigate it
+type pageResponse[T any] struct {
+	Items      []T       `json:"items"`
+	Total      int64     `json:"total"`
+	Limit      int       `json:"limit"`
+	Offset     int       `json:"offset"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	Links      pageLinks `json:"links"`
+}
+
+// pageLinks holds the URLs of the neighboring pages, if there are any
+type pageLinks struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// writePage writes the items fetched for page out of total, linking to the
+// next and previous pages in the body and in an RFC 8288 Link header. The
+// cursor of the next page points at the last item written, as given by
+// cursorOf. Pages selected by cursor only link forward.
+func writePage[T any](w http.ResponseWriter, r *http.Request, p Pagination, page repository.Page, items []T, total int64, cursorOf func(item T) repository.Cursor) {
+	// page asked for one item more than is written
+	limit := page.Limit - 1
+	offset := page.Skip()
+	hasNext := len(items) > limit
+	if hasNext {
+		items = items[:limit]
+	}
+	if items == nil {
+		items = []T{}
+	}
+
+	var nextCursor string
+	var links pageLinks
+	if hasNext && len(items) > 0 {
+		nextCursor = p.encodeCursor(r, cursorOf(items[len(items)-1]))
+
+		if page.After != nil {
+			links.Next = pageURL(r, limit, "cursor", nextCursor)
+		} else {
+			links.Next = pageURL(r, limit, "offset", strconv.Itoa(offset+limit))
+		}
+	}
+	if page.After == nil && offset > 0 {
+		links.Prev = pageURL(r, limit, "offset", strconv.Itoa(max(offset-limit, 0)))
+	}
+
+	var header []string
+	if links.Next != "" {
+		header = append(header, `<`+links.Next+`>; rel="next"`)
+	}
+	if links.Prev != "" {
+		header = append(header, `<`+links.Prev+`>; rel="prev"`)
+	}
+	if len(header) > 0 {
+		w.Header().Set("Link", strings.Join(header, ", "))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(pageResponse[T]{
+		Items:      items,
+		Total:      total,
+		Limit:      limit,
+		Offset:     offset,
+		NextCursor: nextCursor,
+		Links:      links,
+	})
+}
+
+// pageURL returns the URL of r with its limit and position replaced, keeping
+// any other query parameters. The position is either an offset or a cursor.
+func pageURL(r *http.Request, limit int, position, value string) string {
+	query := r.URL.Query()
+	query.Del("offset")
+	query.Del("cursor")
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set(position, value)
+
+	return r.URL.Path + "?" + query.Encode()
+}
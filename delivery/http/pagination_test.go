@@ -0,0 +1,123 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+func TestPaginationPage(t *testing.T) {
+	p := Pagination{DefaultLimit: 10, MaxLimit: 50, CursorSecret: []byte("secret")}
+
+	tests := []struct {
+		query      string
+		wantLimit  int
+		wantOffset int
+	}{
+		{query: "", wantLimit: 10},
+		{query: "limit=5&offset=20", wantLimit: 5, wantOffset: 20},
+		{query: "limit=500", wantLimit: 50},
+		{query: "limit=0", wantLimit: 10},
+		{query: "limit=-3&offset=-1", wantLimit: 10},
+		{query: "limit=ten&offset=x", wantLimit: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/tasks?"+tt.query, nil)
+			page, ok := p.page(httptest.NewRecorder(), r)
+			if !ok {
+				t.Fatal("page() rejected the request")
+			}
+
+			// Pages ask for one item more than the client did
+			if page.Limit != tt.wantLimit+1 || page.Offset != tt.wantOffset {
+				t.Errorf("page() = limit %d offset %d, want limit %d offset %d", page.Limit-1, page.Offset, tt.wantLimit, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestPaginationPageNeverEmpty(t *testing.T) {
+	// Configuration rejects such limits, but a zero value must not loop forever
+	var p Pagination
+
+	page, ok := p.page(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/tasks", nil))
+	if !ok || page.Limit != 2 {
+		t.Errorf("page() = limit %d, want 1", page.Limit-1)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	p := Pagination{DefaultLimit: 10, CursorSecret: []byte("secret")}
+	issuedOn := httptest.NewRequest(http.MethodGet, "/tasks?sort=title&order=desc&q=milk", nil)
+	cursor := repository.Cursor{Key: "Buy milk", ID: 42}
+	token := p.encodeCursor(issuedOn, cursor)
+	payload, signature, _ := strings.Cut(token, ".")
+	otherPayload, _, _ := strings.Cut(p.encodeCursor(issuedOn, repository.Cursor{Key: "Buy milk", ID: 1}), ".")
+
+	tests := []struct {
+		name    string
+		p       Pagination
+		target  string
+		token   string
+		wantErr bool
+	}{
+		{name: "same list", p: p, target: "/tasks?sort=title&order=desc&q=milk", token: token},
+		{name: "other page size", p: p, target: "/tasks?sort=title&order=desc&q=milk&limit=3", token: token},
+		{name: "other list", p: p, target: "/users?sort=title&order=desc&q=milk", token: token, wantErr: true},
+		{name: "other sort", p: p, target: "/tasks?sort=id&order=desc&q=milk", token: token, wantErr: true},
+		{name: "other order", p: p, target: "/tasks?sort=title&order=asc&q=milk", token: token, wantErr: true},
+		{name: "other search", p: p, target: "/tasks?sort=title&order=desc&q=eggs", token: token, wantErr: true},
+		{
+			name:    "other secret",
+			p:       Pagination{CursorSecret: []byte("other")},
+			target:  "/tasks?sort=title&order=desc&q=milk",
+			token:   token,
+			wantErr: true,
+		},
+		{
+			name:    "forged payload",
+			p:       p,
+			target:  "/tasks?sort=title&order=desc&q=milk",
+			token:   otherPayload + "." + signature,
+			wantErr: true,
+		},
+		{name: "forged signature", p: p, target: "/tasks?sort=title&order=desc&q=milk", token: payload + ".AAAA", wantErr: true},
+		{name: "no signature", p: p, target: "/tasks?sort=title&order=desc&q=milk", token: payload, wantErr: true},
+		{name: "garbage", p: p, target: "/tasks?sort=title&order=desc&q=milk", token: "!!.!!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.p.decodeCursor(httptest.NewRequest(http.MethodGet, tt.target, nil), tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("decodeCursor() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+			if got != cursor {
+				t.Errorf("decodeCursor() = %+v, want %+v", got, cursor)
+			}
+		})
+	}
+}
+
+func TestPaginationPageRejectsInvalidCursor(t *testing.T) {
+	p := Pagination{DefaultLimit: 10, CursorSecret: []byte("secret")}
+	w := httptest.NewRecorder()
+
+	if _, ok := p.page(w, httptest.NewRequest(http.MethodGet, "/tasks?cursor=forged.AAAA", nil)); ok {
+		t.Fatal("page() accepted a forged cursor")
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
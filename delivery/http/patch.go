@@ -0,0 +1,457 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+)
+
+// Media types of the patch documents PATCH endpoints accept
+const (
+	// mergePatchType is a JSON Merge Patch (RFC 7396)
+	mergePatchType = "application/merge-patch+json"
+	// jsonPatchType is a JSON Patch (RFC 6902)
+	jsonPatchType = "application/json-patch+json"
+)
+
+// applyPatch applies the patch document in the request body to the JSON
+// representation of current and returns the top-level members the patch
+// changed, mapped to their new value, which is nil if the member was removed.
+// It responds with a problem document and returns false if the request carries
+// no patch document it can apply.
+func applyPatch(w http.ResponseWriter, r *http.Request, current any) (map[string]any, bool) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != mergePatchType && mediaType != jsonPatchType {
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		writeProblem(w, r, "Content-Type must be "+mergePatchType+" or "+jsonPatchType, http.StatusUnsupportedMediaType)
+		return nil, false
+	}
+
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	original, _ := decodeJSON(encoded)
+	document, _ := decodeJSON(encoded)
+
+	if mediaType == mergePatchType {
+		var patch any
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		if err := decoder.Decode(&patch); err != nil {
+			writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+			return nil, false
+		}
+
+		document = mergePatch(document, patch)
+	} else {
+		var operations []jsonPatchOperation
+		if err := json.NewDecoder(r.Body).Decode(&operations); err != nil {
+			writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+			return nil, false
+		}
+
+		for i, operation := range operations {
+			if err := operation.validate(); err != nil {
+				writeProblem(w, r, fmt.Sprintf("Invalid operation %d: %v", i, err), http.StatusBadRequest)
+				return nil, false
+			}
+		}
+
+		// The operations apply atomically, so one failing leaves the resource untouched
+		for i, operation := range operations {
+			if document, err = operation.apply(document); err != nil {
+				writeProblem(w, r, fmt.Sprintf("Operation %d cannot be applied: %v", i, err), http.StatusConflict)
+				return nil, false
+			}
+		}
+	}
+
+	patched, ok := document.(map[string]any)
+	if !ok {
+		writeProblem(w, r, "The patched document must be a JSON object", http.StatusUnprocessableEntity)
+		return nil, false
+	}
+
+	changes := make(map[string]any)
+	members := original.(map[string]any)
+	for name, value := range members {
+		if other, found := patched[name]; !found || !jsonEqual(value, other) {
+			changes[name] = other
+		}
+	}
+	for name, value := range patched {
+		if _, found := members[name]; !found {
+			changes[name] = value
+		}
+	}
+
+	return changes, true
+}
+
+// patchValue decodes the new value of a member changed by a patch, recording an
+// invalid_format error with message if it does not fit a T. A removed member
+// decodes to the zero value.
+func patchValue[T any](name string, value any, message string, errs *entity.ValidationErrors) *T {
+	encoded, _ := json.Marshal(value)
+	decoded := new(T)
+	if err := json.Unmarshal(encoded, decoded); err != nil {
+		*errs = append(*errs, entity.FieldError{Field: name, Code: entity.ValidationInvalidFormat, Message: message})
+		return nil
+	}
+
+	return decoded
+}
+
+// readOnlyMember records an error for a member a patch cannot change
+func readOnlyMember(name string, errs *entity.ValidationErrors) {
+	*errs = append(*errs, entity.FieldError{Field: name, Code: entity.ValidationReadOnly, Message: "cannot be changed"})
+}
+
+// decodeJSON decodes a JSON value, keeping numbers as json.Number so large IDs
+// survive unchanged
+func decodeJSON(data []byte) (any, error) {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// mergePatch applies a JSON Merge Patch to target and returns the result. Objects
+// are merged member by member, a null member removes the member from target, and
+// any other value replaces the target value.
+func mergePatch(target, patch any) any {
+	members, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	result, ok := target.(map[string]any)
+	if !ok {
+		result = make(map[string]any, len(members))
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(result, name)
+		} else {
+			result[name] = mergePatch(result[name], value)
+		}
+	}
+
+	return result
+}
+
+// jsonPatchOperation is an operation of a JSON Patch
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// validate checks that the operation is well-formed
+func (o jsonPatchOperation) validate() error {
+	switch o.Op {
+	case "add", "remove", "replace", "move", "copy", "test":
+	default:
+		return fmt.Errorf("unknown op %q", o.Op)
+	}
+
+	if o.Path == nil {
+		return errors.New("missing path")
+	}
+	if _, err := parseJSONPointer(*o.Path); err != nil {
+		return err
+	}
+
+	switch o.Op {
+	case "add", "replace", "test":
+		if o.Value == nil {
+			return errors.New("missing value")
+		}
+	case "move", "copy":
+		if o.From == nil {
+			return errors.New("missing from")
+		}
+		if _, err := parseJSONPointer(*o.From); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply applies the validated operation to document and returns the result
+func (o jsonPatchOperation) apply(document any) (any, error) {
+	path, _ := parseJSONPointer(*o.Path)
+
+	switch o.Op {
+	case "add":
+		value, _ := decodeJSON(o.Value)
+		return path.add(document, value)
+	case "remove":
+		return path.remove(document)
+	case "replace":
+		if _, err := path.get(document); err != nil {
+			return nil, err
+		}
+		value, _ := decodeJSON(o.Value)
+		document, _ = path.remove(document)
+		return path.add(document, value)
+	case "move":
+		from, _ := parseJSONPointer(*o.From)
+		if len(from) < len(path) && slices.Equal(from, path[:len(from)]) {
+			return nil, fmt.Errorf("cannot move %s into itself", *o.From)
+		}
+		value, err := from.get(document)
+		if err != nil {
+			return nil, err
+		}
+		document, _ = from.remove(document)
+		return path.add(document, value)
+	case "copy":
+		from, _ := parseJSONPointer(*o.From)
+		value, err := from.get(document)
+		if err != nil {
+			return nil, err
+		}
+		// Copy the value so later operations do not change it in both places
+		encoded, _ := json.Marshal(value)
+		value, _ = decodeJSON(encoded)
+		return path.add(document, value)
+	default: // test
+		actual, err := path.get(document)
+		if err != nil {
+			return nil, err
+		}
+		expected, _ := decodeJSON(o.Value)
+		if !jsonEqual(actual, expected) {
+			return nil, fmt.Errorf("the value at %s is not the expected one", *o.Path)
+		}
+		return document, nil
+	}
+}
+
+// jsonPointer is a parsed JSON Pointer (RFC 6901): the member names and array
+// indexes leading to a value, outermost first
+type jsonPointer []string
+
+// Escaping of the characters of JSON Pointer tokens that have a special meaning
+var (
+	pointerTokenEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerTokenUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// parseJSONPointer parses a JSON Pointer, where "" refers to the whole document
+func parseJSONPointer(pointer string) (jsonPointer, error) {
+	if pointer == "" {
+		return jsonPointer{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path %q must start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = pointerTokenUnescaper.Replace(token)
+	}
+
+	return tokens, nil
+}
+
+// String encodes the pointer
+func (p jsonPointer) String() string {
+	var b strings.Builder
+	for _, token := range p {
+		b.WriteString("/")
+		b.WriteString(pointerTokenEscaper.Replace(token))
+	}
+
+	return b.String()
+}
+
+// get returns the value p refers to in document
+func (p jsonPointer) get(document any) (any, error) {
+	for i, token := range p {
+		switch container := document.(type) {
+		case map[string]any:
+			value, found := container[token]
+			if !found {
+				return nil, fmt.Errorf("%s does not exist", p[:i+1])
+			}
+			document = value
+		case []any:
+			index, err := arrayIndex(container, token, false)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", p[:i+1], err)
+			}
+			document = container[index]
+		default:
+			return nil, fmt.Errorf("%s is neither an object nor an array", p[:i])
+		}
+	}
+
+	return document, nil
+}
+
+// add returns document with value added at p: replacing the whole document or
+// an object member, or inserted into an array
+func (p jsonPointer) add(document, value any) (any, error) {
+	if len(p) == 0 {
+		return value, nil
+	}
+
+	return p.edit(document, func(container any, token string) (any, error) {
+		switch container := container.(type) {
+		case map[string]any:
+			container[token] = value
+			return container, nil
+		case []any:
+			index, err := arrayIndex(container, token, true)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", p, err)
+			}
+			return slices.Insert(container, index, value), nil
+		default:
+			return nil, fmt.Errorf("%s is neither an object nor an array", p[:len(p)-1])
+		}
+	})
+}
+
+// remove returns document without the value p refers to
+func (p jsonPointer) remove(document any) (any, error) {
+	if len(p) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+
+	return p.edit(document, func(container any, token string) (any, error) {
+		switch container := container.(type) {
+		case map[string]any:
+			if _, found := container[token]; !found {
+				return nil, fmt.Errorf("%s does not exist", p)
+			}
+			delete(container, token)
+			return container, nil
+		case []any:
+			index, err := arrayIndex(container, token, false)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", p, err)
+			}
+			return slices.Delete(container, index, index+1), nil
+		default:
+			return nil, fmt.Errorf("%s is neither an object nor an array", p[:len(p)-1])
+		}
+	})
+}
+
+// edit returns document with the object or array containing the value p refers
+// to replaced by the result of change, which receives it along with the last
+// token of p
+func (p jsonPointer) edit(document any, change func(container any, token string) (any, error)) (any, error) {
+	if len(p) == 1 {
+		return change(document, p[0])
+	}
+
+	child, err := p[:1].get(document)
+	if err != nil {
+		return nil, err
+	}
+	child, err = p[1:].edit(child, change)
+	if err != nil {
+		return nil, err
+	}
+
+	// Arrays may have been reallocated, so store the child again
+	switch container := document.(type) {
+	case map[string]any:
+		container[p[0]] = child
+	case []any:
+		index, _ := arrayIndex(container, p[0], false)
+		container[index] = child
+	}
+
+	return document, nil
+}
+
+// arrayIndex parses a JSON Pointer token referring to an element of array.
+// With end, "-" and the length of array refer to the position after the last
+// element.
+func arrayIndex(array []any, token string, end bool) (int, error) {
+	if token == "-" && end {
+		return len(array), nil
+	}
+
+	// Indexes are plain decimal numbers without leading zeros
+	index, err := strconv.Atoi(token)
+	if err != nil || (len(token) > 1 && token[0] == '0') || token[0] == '+' || token[0] == '-' {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+	if index > len(array) || (index == len(array) && !end) {
+		return 0, fmt.Errorf("index %d is out of bounds", index)
+	}
+
+	return index, nil
+}
+
+// jsonEqual reports whether two decoded JSON values are equal, comparing
+// numbers by value and objects regardless of member order
+func jsonEqual(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for name, value := range a {
+			other, found := b[name]
+			if !found || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		return ok && slices.EqualFunc(a, b, jsonEqual)
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		return ok && numberEqual(a, b)
+	default:
+		return a == b
+	}
+}
+
+// numberEqual reports whether two JSON numbers have the same value. Integers
+// are compared exactly and other numbers as float64, so that 1 equals 1.0 and
+// 1e2 equals 100. Numbers out of the range of float64 only equal the same
+// literal; they are never expanded, since a client picks their exponent.
+func numberEqual(a, b json.Number) bool {
+	if x, err := a.Int64(); err == nil {
+		if y, err := b.Int64(); err == nil {
+			return x == y
+		}
+	}
+
+	x, errX := a.Float64()
+	y, errY := b.Float64()
+	if errX != nil || errY != nil {
+		return a == b
+	}
+
+	return x == y
+}
@@ -0,0 +1,343 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONEqualNumbers(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "1", b: "1", want: true},
+		{a: "1", b: "1.0", want: true},
+		{a: "1e2", b: "100", want: true},
+		{a: "-0", b: "0", want: true},
+		{a: "0.1", b: "0.10", want: true},
+		{a: "1", b: "2", want: false},
+		{a: "9007199254740993", b: "9007199254740992", want: false},
+		{a: "1e100000000", b: "1e100000000", want: true},
+		{a: "1e100000000", b: "1e100000001", want: false},
+		{a: "1e100000000", b: "1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"="+tt.b, func(t *testing.T) {
+			if got := jsonEqual(json.Number(tt.a), json.Number(tt.b)); got != tt.want {
+				t.Errorf("jsonEqual(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// mustDecodeJSON decodes a JSON value the way the patch code does
+func mustDecodeJSON(t *testing.T, data string) any {
+	t.Helper()
+
+	value, err := decodeJSON([]byte(data))
+	if err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+
+	return value
+}
+
+func TestMergePatch(t *testing.T) {
+	// The examples of RFC 7396, appendix A
+	tests := []struct {
+		target, patch, want string
+	}{
+		{target: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{target: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{target: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{target: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{target: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{target: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{target: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		{target: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{target: `{"a":"foo"}`, patch: `null`, want: `null`},
+		{target: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{target: `{"e":null}`, patch: `{"a":1}`, want: `{"e":null,"a":1}`},
+		{target: `[1,2]`, patch: `{"a":"b","c":null}`, want: `{"a":"b"}`},
+		{target: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target+" "+tt.patch, func(t *testing.T) {
+			got := mergePatch(mustDecodeJSON(t, tt.target), mustDecodeJSON(t, tt.patch))
+			if want := mustDecodeJSON(t, tt.want); !jsonEqual(got, want) {
+				t.Errorf("mergePatch() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	// Mostly the examples of RFC 6902, appendix A
+	tests := []struct {
+		name       string
+		document   string
+		operations string
+		want       string
+		// wantErr tells that validating or applying an operation fails
+		wantErr bool
+	}{
+		{
+			name:       "add an object member",
+			document:   `{"foo":"bar"}`,
+			operations: `[{"op":"add","path":"/baz","value":"qux"}]`,
+			want:       `{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			name:       "add an array element",
+			document:   `{"foo":["bar","baz"]}`,
+			operations: `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			want:       `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			name:       "append to an array",
+			document:   `{"foo":["bar"]}`,
+			operations: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			want:       `{"foo":["bar",["abc","def"]]}`,
+		},
+		{
+			name:       "remove an object member",
+			document:   `{"baz":"qux","foo":"bar"}`,
+			operations: `[{"op":"remove","path":"/baz"}]`,
+			want:       `{"foo":"bar"}`,
+		},
+		{
+			name:       "remove an array element",
+			document:   `{"foo":["bar","qux","baz"]}`,
+			operations: `[{"op":"remove","path":"/foo/1"}]`,
+			want:       `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:       "replace a value",
+			document:   `{"baz":"qux","foo":"bar"}`,
+			operations: `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			want:       `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			name:       "move a value",
+			document:   `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			operations: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			want:       `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:       "move an array element",
+			document:   `{"foo":["all","grass","cows","eat"]}`,
+			operations: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			want:       `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			name:       "copy a value that is changed later",
+			document:   `{"foo":{"bar":1}}`,
+			operations: `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`,
+			want:       `{"foo":{"bar":1},"baz":{"bar":2}}`,
+		},
+		{
+			name:       "test a value",
+			document:   `{"baz":"qux","foo":["a",2,"c"]}`,
+			operations: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`,
+			want:       `{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			name:       "escaped member names",
+			document:   `{"/":9,"~1":10}`,
+			operations: `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`,
+			want:       `{"~1":10}`,
+		},
+		{
+			name:       "test a value that differs",
+			document:   `{"baz":"qux"}`,
+			operations: `[{"op":"test","path":"/baz","value":"bar"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "add to a missing object",
+			document:   `{"foo":"bar"}`,
+			operations: `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "remove a missing member",
+			document:   `{"foo":"bar"}`,
+			operations: `[{"op":"remove","path":"/baz"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "replace a missing member",
+			document:   `{"foo":"bar"}`,
+			operations: `[{"op":"replace","path":"/baz","value":1}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "array index out of bounds",
+			document:   `{"foo":["bar"]}`,
+			operations: `[{"op":"add","path":"/foo/2","value":"baz"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "array index with a leading zero",
+			document:   `{"foo":["bar","baz"]}`,
+			operations: `[{"op":"remove","path":"/foo/01"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "move a value into itself",
+			document:   `{"foo":{"bar":1}}`,
+			operations: `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "unknown op",
+			document:   `{"foo":"bar"}`,
+			operations: `[{"op":"append","path":"/foo","value":"baz"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "missing value",
+			document:   `{"foo":"bar"}`,
+			operations: `[{"op":"add","path":"/baz"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "missing from",
+			document:   `{"foo":"bar"}`,
+			operations: `[{"op":"copy","path":"/baz"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "pointer without a leading slash",
+			document:   `{"foo":"bar"}`,
+			operations: `[{"op":"remove","path":"foo"}]`,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var operations []jsonPatchOperation
+			if err := json.Unmarshal([]byte(tt.operations), &operations); err != nil {
+				t.Fatalf("decoding operations: %v", err)
+			}
+
+			document := mustDecodeJSON(t, tt.document)
+			var err error
+			for _, operation := range operations {
+				if err = operation.validate(); err != nil {
+					break
+				}
+				if document, err = operation.apply(document); err != nil {
+					break
+				}
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("patch resulted in %v, want an error", document)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("patch error = %v", err)
+			}
+			if want := mustDecodeJSON(t, tt.want); !jsonEqual(document, want) {
+				t.Errorf("patch resulted in %v, want %s", document, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	current := map[string]any{"title": "Buy milk", "status": "pending", "version": 3}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		// want holds the changed members, encoded as JSON
+		want       string
+		wantStatus int
+	}{
+		{
+			name:        "merge patch",
+			contentType: mergePatchType,
+			body:        `{"title":"Buy eggs","status":"pending","due_date":null}`,
+			want:        `{"title":"Buy eggs"}`,
+		},
+		{
+			name:        "merge patch removing a member",
+			contentType: mergePatchType,
+			body:        `{"status":null}`,
+			want:        `{"status":null}`,
+		},
+		{
+			name:        "json patch",
+			contentType: jsonPatchType + "; charset=utf-8",
+			body:        `[{"op":"test","path":"/version","value":3},{"op":"add","path":"/due_date","value":"2030-01-01"}]`,
+			want:        `{"due_date":"2030-01-01"}`,
+		},
+		{
+			name:        "json patch failing a test",
+			contentType: jsonPatchType,
+			body:        `[{"op":"replace","path":"/title","value":"x"},{"op":"test","path":"/version","value":2}]`,
+			wantStatus:  http.StatusConflict,
+		},
+		{
+			name:        "invalid json patch",
+			contentType: jsonPatchType,
+			body:        `[{"op":"add","path":"/title"}]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "patch replacing the document",
+			contentType: mergePatchType,
+			body:        `["not","an","object"]`,
+			wantStatus:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "malformed body",
+			contentType: mergePatchType,
+			body:        `{"title":`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "plain json",
+			contentType: "application/json",
+			body:        `{"title":"Buy eggs"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/tasks/1", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			changes, ok := applyPatch(w, r, current)
+			if tt.wantStatus != 0 {
+				if ok || w.Code != tt.wantStatus {
+					t.Errorf("applyPatch() = %v with status %d, want status %d", changes, w.Code, tt.wantStatus)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("applyPatch() failed with status %d: %s", w.Code, w.Body)
+			}
+
+			encoded, _ := json.Marshal(changes)
+			if !jsonEqual(mustDecodeJSON(t, string(encoded)), mustDecodeJSON(t, tt.want)) {
+				t.Errorf("applyPatch() changed %s, want %s", encoded, tt.want)
+			}
+		})
+	}
+}
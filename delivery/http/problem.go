@@ -0,0 +1,82 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/delivery/http/middleware"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Problem types identifying errors more precisely than their status. Every
+// other problem has the type about:blank.
+const (
+	problemTypeValidation      = "/problems/validation-error"
+	problemTypeVersionConflict = "/problems/version-conflict"
+	problemTypeUserHasTasks    = "/problems/user-has-tasks"
+	problemTypeTooManyAttempts = "/problems/too-many-attempts"
+)
+
+// writeProblem responds with a problem document with status and detail
+func writeProblem(w http.ResponseWriter, r *http.Request, detail string, status int) {
+	middleware.WriteProblem(w, r, middleware.Problem{
+		Status: status,
+		Detail: detail,
+	})
+}
+
+// writeError responds with the problem document matching err, using the
+// status code of its domain error kind. Errors of no known kind are reported as
+// 500 Internal Server Error without revealing their message, which ErrorHandler
+// logs instead.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case writeAccessError(w, r, err):
+	case writeValidationErrors(w, r, err):
+	case writeThrottledError(w, r, err):
+	case errors.Is(err, repository.ErrVersionConflict):
+		middleware.WriteProblem(w, r, middleware.Problem{
+			Type:   problemTypeVersionConflict,
+			Title:  "Version conflict",
+			Status: conflictStatus(r),
+			Detail: "The resource was changed since it was read; fetch it again and retry",
+		})
+	case errors.Is(err, repository.ErrUserHasTasks):
+		middleware.WriteProblem(w, r, middleware.Problem{
+			Type:   problemTypeUserHasTasks,
+			Title:  "User has tasks",
+			Status: http.StatusConflict,
+			Detail: "The user still owns tasks; delete them first or choose another on_tasks policy",
+		})
+	default:
+		status := errorStatus(err)
+		detail := err.Error()
+		switch status {
+		case http.StatusUnauthorized:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+		case http.StatusInternalServerError:
+			middleware.RecordError(r.Context(), err)
+			detail = "An unexpected error occurred"
+		}
+		writeProblem(w, r, detail, status)
+	}
+}
+
+// errorStatus returns the status code matching the domain error kind of err
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrUnauthorized):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Handlers holds the HTTP handlers the route table dispatches to
+type Handlers struct {
+	Users    *UserHandler
+	Tasks    *TaskHandler
+	Auth     *AuthHandler
+	APIKeys  *APIKeyHandler
+	Sessions *SessionHandler
+	TOTP     *TOTPHandler
+	Accounts *AccountHandler
+	Audit    *AuditHandler
+}
+
+// route is an entry of the route table
+type route struct {
+	pattern string
+	handler http.HandlerFunc
+
+	// public routes can be called without authentication
+	public bool
+	// csrfExempt routes may be called with a session cookie but without a CSRF
+	// token. Logging in replaces the session, and the account endpoints act on
+	// the emailed token in their body rather than on the session, so there is
+	// nothing to forge.
+	csrfExempt bool
+}
+
+// Router dispatches requests to the handlers of the route table. Requests for
+// a known path with another method are answered with 405 Method Not Allowed
+// and an Allow header.
+type Router struct {
+	mux    *http.ServeMux
+	routes map[string]route
+}
+
+// NewRouter creates a router serving every route of the API
+func NewRouter(h Handlers) *Router {
+	routes := []route{
+		{pattern: "GET /health", handler: handleHealth, public: true},
+
+		{pattern: "POST /auth/login", handler: h.Auth.handleLogin, public: true},
+		{pattern: "POST /auth/refresh", handler: h.Auth.handleRefresh, public: true},
+		{pattern: "GET /auth/me", handler: h.Auth.handleMe},
+
+		{pattern: "POST /auth/session", handler: h.Sessions.createSession, public: true, csrfExempt: true},
+		{pattern: "GET /auth/session", handler: h.Sessions.getSession},
+		{pattern: "DELETE /auth/session", handler: h.Sessions.deleteSession},
+		{pattern: "GET /sessions", handler: h.Sessions.getSessions},
+		{pattern: "DELETE /sessions", handler: h.Sessions.revokeSessions},
+
+		{pattern: "POST /auth/totp", handler: h.TOTP.handleEnroll},
+		{pattern: "POST /auth/totp/confirm", handler: h.TOTP.handleConfirm},
+		{pattern: "POST /auth/totp/recovery-codes", handler: h.TOTP.handleRecoveryCodes},
+		{pattern: "POST /auth/totp/disable", handler: h.TOTP.handleDisable},
+
+		{pattern: "POST /auth/verify-email/send", handler: h.Accounts.handleSendVerification},
+		{pattern: "POST /auth/verify-email", handler: h.Accounts.handleVerifyEmail, public: true, csrfExempt: true},
+		{pattern: "POST /auth/forgot-password", handler: h.Accounts.handleForgotPassword, public: true, csrfExempt: true},
+		{pattern: "POST /auth/reset-password", handler: h.Accounts.handleResetPassword, public: true, csrfExempt: true},
+
+		{pattern: "GET /api-keys", handler: h.APIKeys.getAPIKeys},
+		{pattern: "POST /api-keys", handler: h.APIKeys.createAPIKey},
+		{pattern: "DELETE /api-keys/{id}", handler: withID("Invalid API key ID", h.APIKeys.revokeAPIKey)},
+
+		{pattern: "GET /users", handler: h.Users.getUsers},
+		{pattern: "POST /users", handler: h.Users.createUser, public: true}, // Sign-up
+		{pattern: "GET /users/trash", handler: h.Users.getDeletedUsers},
+		{pattern: "GET /users/{id}", handler: withID("Invalid user ID", h.Users.getUserByID)},
+		{pattern: "PUT /users/{id}", handler: withID("Invalid user ID", h.Users.updateUser)},
+		{pattern: "PATCH /users/{id}", handler: withID("Invalid user ID", h.Users.patchUser)},
+		{pattern: "DELETE /users/{id}", handler: withID("Invalid user ID", h.Users.deleteUser)},
+		{pattern: "PUT /users/{id}/password", handler: withID("Invalid user ID", h.Users.changePassword)},
+		{pattern: "PUT /users/{id}/role", handler: withID("Invalid user ID", h.Users.changeRole)},
+		{pattern: "POST /users/{id}/restore", handler: withID("Invalid user ID", h.Users.restoreUser)},
+		{pattern: "POST /users/{id}/unlock", handler: withID("Invalid user ID", h.Users.unlockUser)},
+		{pattern: "GET /users/{id}/tasks", handler: withID("Invalid user ID", h.Tasks.getTasksByUserID)},
+
+		{pattern: "GET /tasks", handler: h.Tasks.getTasks},
+		{pattern: "POST /tasks", handler: h.Tasks.createTask},
+		{pattern: "GET /tasks/trash", handler: h.Tasks.getDeletedTasks},
+		{pattern: "GET /tasks/search", handler: h.Tasks.searchTasks},
+		{pattern: "GET /tasks/{id}", handler: withID("Invalid task ID", h.Tasks.getTaskByID)},
+		{pattern: "PUT /tasks/{id}", handler: withID("Invalid task ID", h.Tasks.updateTask)},
+		{pattern: "PATCH /tasks/{id}", handler: withID("Invalid task ID", h.Tasks.patchTask)},
+		{pattern: "DELETE /tasks/{id}", handler: withID("Invalid task ID", h.Tasks.deleteTask)},
+		{pattern: "PUT /tasks/{id}/in-progress", handler: withID("Invalid task ID", h.Tasks.markTaskInProgress)},
+		{pattern: "PUT /tasks/{id}/completed", handler: withID("Invalid task ID", h.Tasks.markTaskCompleted)},
+		{pattern: "POST /tasks/{id}/restore", handler: withID("Invalid task ID", h.Tasks.restoreTask)},
+
+		{pattern: "GET /audit", handler: h.Audit.handleAudit},
+	}
+
+	router := &Router{
+		mux:    http.NewServeMux(),
+		routes: make(map[string]route, len(routes)),
+	}
+	for _, route := range routes {
+		router.mux.HandleFunc(route.pattern, route.handler)
+		router.routes[route.pattern] = route
+	}
+
+	return router
+}
+
+// ServeHTTP dispatches the request to the handler of the matching route
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
+
+// IsPublicRoute reports whether a request targets an endpoint that does not
+// require authentication. Requests matching no route are let through to be
+// answered with 404 Not Found or 405 Method Not Allowed.
+func (rt *Router) IsPublicRoute(r *http.Request) bool {
+	_, pattern := rt.mux.Handler(r)
+	return pattern == "" || rt.routes[pattern].public
+}
+
+// RequiresCSRFToken reports whether a request authenticated by a session cookie
+// must carry the session's CSRF token. Every state-changing endpoint requires
+// it unless its route is marked exempt, including all of /users and /tasks.
+func (rt *Router) RequiresCSRFToken(r *http.Request) bool {
+	_, pattern := rt.mux.Handler(r)
+	return !rt.routes[pattern].csrfExempt
+}
+
+// handleHealth handles GET /health
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// withID adapts a handler of a route with an {id} wildcard, answering 400 Bad
+// Request with message if the wildcard is not a number
+func withID(message string, handle func(w http.ResponseWriter, r *http.Request, id uint64)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+		if err != nil {
+			writeProblem(w, r, message, http.StatusBadRequest)
+			return
+		}
+
+		handle(w, r, id)
+	}
+}
@@ -0,0 +1,188 @@
+package http
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/usecase"
+)
+
+// SessionHandler represents the HTTP handler for cookie session operations
+type SessionHandler struct {
+	sessionUseCase *usecase.SessionUseCase
+	// cookie holds the name and attributes of the session cookie
+	cookie http.Cookie
+}
+
+// NewSessionHandler creates a new session handler issuing cookies shaped like cookie
+func NewSessionHandler(sessionUseCase *usecase.SessionUseCase, cookie http.Cookie) *SessionHandler {
+	return &SessionHandler{
+		sessionUseCase: sessionUseCase,
+		cookie:         cookie,
+	}
+}
+
+// sessionResponse is the body returned to the owner of a session. It is the only
+// place the session's CSRF token is handed out.
+type sessionResponse struct {
+	*entity.Session
+	CSRFToken string `json:"csrf_token"`
+}
+
+// createSession handles POST /auth/session
+func (h *SessionHandler) createSession(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+		TOTPCode string `json:"totp_code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Log in
+	session, token, err := h.sessionUseCase.Login(r.Context(), req.Email, req.Password, req.TOTPCode, r.UserAgent(), clientIP(r))
+	if writeLoginError(w, r, err) {
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Set the session cookie
+	cookie := h.cookie
+	cookie.Value = token
+	cookie.Expires = session.ExpiresAt
+	http.SetCookie(w, &cookie)
+
+	// Return session
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sessionResponse{Session: session, CSRFToken: session.CSRFToken})
+}
+
+// getSession handles GET /auth/session
+func (h *SessionHandler) getSession(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(h.cookie.Name)
+	if err != nil {
+		writeProblem(w, r, "No active session", http.StatusUnauthorized)
+		return
+	}
+
+	// Get the current session
+	_, session, err := h.sessionUseCase.Authenticate(r.Context(), cookie.Value)
+	if err != nil {
+		writeProblem(w, r, "No active session", http.StatusUnauthorized)
+		return
+	}
+
+	// Return session
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(sessionResponse{Session: session, CSRFToken: session.CSRFToken})
+}
+
+// deleteSession handles DELETE /auth/session
+func (h *SessionHandler) deleteSession(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(h.cookie.Name); err == nil {
+		// Log out
+		if err := h.sessionUseCase.Logout(r.Context(), cookie.Value); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	h.clearCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getSessions handles GET /sessions
+func (h *SessionHandler) getSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.targetUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Get sessions
+	sessions, err := h.sessionUseCase.List(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return sessions
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// revokeSessions handles DELETE /sessions
+func (h *SessionHandler) revokeSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.targetUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Revoke sessions
+	revoked, err := h.sessionUseCase.RevokeAll(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// The caller's own session is gone too
+	if principal, _ := usecase.PrincipalFromContext(r.Context()); principal.UserID == userID {
+		h.clearCookie(w)
+	}
+
+	// Return the number of revoked sessions
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"revoked": revoked})
+}
+
+// targetUserID returns the user named by the user_id query parameter, defaulting
+// to the caller. It writes an error response and returns false if there is none.
+func (h *SessionHandler) targetUserID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	principal, ok := usecase.PrincipalFromContext(r.Context())
+	if !ok {
+		writeAccessError(w, r, usecase.ErrUnauthenticated)
+		return 0, false
+	}
+
+	userIDStr := r.URL.Query().Get("user_id")
+	if userIDStr == "" {
+		return principal.UserID, true
+	}
+
+	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, "Invalid user ID", http.StatusBadRequest)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// clearCookie tells the browser to drop the session cookie
+func (h *SessionHandler) clearCookie(w http.ResponseWriter) {
+	cookie := h.cookie
+	cookie.MaxAge = -1
+	http.SetCookie(w, &cookie)
+}
+
+// clientIP returns the address of the client that sent a request
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -2,199 +2,158 @@ package http
 
 import (
 	"encoding/json"
+	"maps"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/search"
 	"github.com/dimasbagussusilo/go-clean-boilerplate/usecase"
 )
 
 // TaskHandler represents the HTTP handler for task operations
 type TaskHandler struct {
 	taskUseCase *usecase.TaskUseCase
+	pagination  Pagination
 }
 
 // NewTaskHandler creates a new task handler
-func NewTaskHandler(taskUseCase *usecase.TaskUseCase) *TaskHandler {
+func NewTaskHandler(taskUseCase *usecase.TaskUseCase, pagination Pagination) *TaskHandler {
 	return &TaskHandler{
 		taskUseCase: taskUseCase,
+		pagination:  pagination,
 	}
 }
 
-// RegisterRoutes registers the task routes
-func (h *TaskHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/tasks", h.handleTasks)
-	mux.HandleFunc("/tasks/", h.handleTaskByID)
-	mux.HandleFunc("/users/", h.handleTasksByUserID)
-}
-
-// handleTasks handles the /tasks endpoint
-func (h *TaskHandler) handleTasks(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		h.getTasks(w, r)
-	case http.MethodPost:
-		h.createTask(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
-
-// handleTaskByID handles the /tasks/{id} endpoint
-func (h *TaskHandler) handleTaskByID(w http.ResponseWriter, r *http.Request) {
-	// Extract ID from URL
-	path := strings.TrimPrefix(r.URL.Path, "/tasks/")
-
-	// Handle status update endpoints
-	if strings.HasSuffix(path, "/in-progress") {
-		id, err := strconv.ParseUint(strings.TrimSuffix(path, "/in-progress"), 10, 64)
-		if err != nil {
-			http.Error(w, "Invalid task ID", http.StatusBadRequest)
-			return
-		}
-
-		if r.Method == http.MethodPut {
-			h.markTaskInProgress(w, r, id)
-			return
-		}
-
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// getTasks handles GET /tasks
+func (h *TaskHandler) getTasks(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	filter, sort, err := taskQuery(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if strings.HasSuffix(path, "/completed") {
-		id, err := strconv.ParseUint(strings.TrimSuffix(path, "/completed"), 10, 64)
-		if err != nil {
-			http.Error(w, "Invalid task ID", http.StatusBadRequest)
-			return
-		}
-
-		if r.Method == http.MethodPut {
-			h.markTaskCompleted(w, r, id)
-			return
-		}
-
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	page, ok := h.pagination.page(w, r)
+	if !ok {
 		return
 	}
 
-	// Handle regular task endpoints
-	id, err := strconv.ParseUint(path, 10, 64)
+	// Get tasks
+	tasks, total, err := h.taskUseCase.List(r.Context(), filter, sort, page)
 	if err != nil {
-		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
-	switch r.Method {
-	case http.MethodGet:
-		h.getTaskByID(w, r, id)
-	case http.MethodPut:
-		h.updateTask(w, r, id)
-	case http.MethodDelete:
-		h.deleteTask(w, r, id)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
+	// Return tasks
+	writePage(w, r, h.pagination, page, tasks, total, sort.Cursor)
 }
 
-// handleTasksByUserID handles the /users/{id}/tasks endpoint
-func (h *TaskHandler) handleTasksByUserID(w http.ResponseWriter, r *http.Request) {
-	// Extract user ID from URL
-	path := strings.TrimPrefix(r.URL.Path, "/users/")
-	if !strings.Contains(path, "/tasks") {
-		// Not a task endpoint, let the user handler handle it
+// getDeletedTasks handles GET /tasks/trash
+func (h *TaskHandler) getDeletedTasks(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	page, ok := h.pagination.page(w, r)
+	if !ok {
 		return
 	}
 
-	userIDStr := strings.Split(path, "/tasks")[0]
-	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	// Get deleted tasks
+	tasks, total, err := h.taskUseCase.ListDeleted(r.Context(), page)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
-	if r.Method == http.MethodGet {
-		h.getTasksByUserID(w, r, userID)
-		return
-	}
-
-	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// Return tasks
+	writePage(w, r, h.pagination, page, tasks, total, deletedTaskCursor)
 }
 
-// getTasks handles GET /tasks
-func (h *TaskHandler) getTasks(w http.ResponseWriter, r *http.Request) {
+// searchTasks handles GET /tasks/search
+func (h *TaskHandler) searchTasks(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-
-	limit := 10 // Default limit
-	if limitStr != "" {
-		parsedLimit, err := strconv.Atoi(limitStr)
-		if err == nil && parsedLimit > 0 {
-			limit = parsedLimit
+	query := r.URL.Query().Get("q")
+	var errs entity.ValidationErrors
+	if strings.TrimSpace(query) == "" {
+		errs = append(errs, entity.FieldError{Field: "q", Code: entity.ValidationRequired, Message: "is required"})
+	}
+	// Results are ordered by relevance, so the ordering of task lists does not apply
+	for _, param := range []string{"sort", "order"} {
+		if r.URL.Query().Has(param) {
+			errs = append(errs, entity.FieldError{
+				Field:   param,
+				Code:    entity.ValidationUnsupported,
+				Message: "does not apply to search results, which are ordered by relevance",
+			})
 		}
 	}
-
-	offset := 0 // Default offset
-	if offsetStr != "" {
-		parsedOffset, err := strconv.Atoi(offsetStr)
-		if err == nil && parsedOffset >= 0 {
-			offset = parsedOffset
-		}
+	if len(errs) > 0 {
+		writeError(w, r, errs)
+		return
 	}
 
-	// Get tasks
-	tasks, err := h.taskUseCase.List(r.Context(), limit, offset)
+	filter, _, err := taskQuery(r)
 	if err != nil {
-		http.Error(w, "Failed to get tasks: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	// Return tasks
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(tasks)
+	page, ok := h.pagination.page(w, r)
+	if !ok {
+		return
+	}
+
+	// Search tasks
+	matches, total, err := h.taskUseCase.Search(r.Context(), query, filter, page)
 	if err != nil {
+		writeError(w, r, err)
 		return
 	}
+
+	// Return matches with highlighted snippets
+	terms := search.Terms(query)
+	results := make([]*taskSearchResult, len(matches))
+	for i, match := range matches {
+		results[i] = &taskSearchResult{
+			Task: match.Task,
+			Rank: match.Rank,
+			Highlights: taskHighlights{
+				Title:       search.Highlight(match.Task.Title, terms),
+				Description: search.Snippet(match.Task.Description, terms, snippetWords),
+			},
+		}
+	}
+	writePage(w, r, h.pagination, page, results, total, taskSearchCursor)
 }
 
 // getTasksByUserID handles GET /users/{id}/tasks
 func (h *TaskHandler) getTasksByUserID(w http.ResponseWriter, r *http.Request, userID uint64) {
 	// Parse query parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-
-	limit := 10 // Default limit
-	if limitStr != "" {
-		parsedLimit, err := strconv.Atoi(limitStr)
-		if err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
+	filter, sort, err := taskQuery(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	offset := 0 // Default offset
-	if offsetStr != "" {
-		parsedOffset, err := strconv.Atoi(offsetStr)
-		if err == nil && parsedOffset >= 0 {
-			offset = parsedOffset
-		}
+	page, ok := h.pagination.page(w, r)
+	if !ok {
+		return
 	}
 
 	// Get tasks by user ID
-	tasks, err := h.taskUseCase.GetByUserID(r.Context(), userID, limit, offset)
+	tasks, total, err := h.taskUseCase.GetByUserID(r.Context(), userID, filter, sort, page)
 	if err != nil {
-		http.Error(w, "Failed to get tasks: "+err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	// Return tasks
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(tasks)
-	if err != nil {
-		return
-	}
+	writePage(w, r, h.pagination, page, tasks, total, sort.Cursor)
 }
 
 // getTaskByID handles GET /tasks/{id}
@@ -202,12 +161,13 @@ func (h *TaskHandler) getTaskByID(w http.ResponseWriter, r *http.Request, id uin
 	// Get task
 	task, err := h.taskUseCase.GetByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Task not found", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
 	// Return task
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, task.Version)
 	err = json.NewEncoder(w).Encode(task)
 	if err != nil {
 		return
@@ -225,13 +185,7 @@ func (h *TaskHandler) createTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	// Validate request
-	if req.Title == "" || req.UserID == 0 {
-		http.Error(w, "Title and user_id are required", http.StatusBadRequest)
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
@@ -244,12 +198,13 @@ func (h *TaskHandler) createTask(w http.ResponseWriter, r *http.Request) {
 		req.DueDate,
 	)
 	if err != nil {
-		http.Error(w, "Failed to create task: "+err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	// Return task
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, task.Version)
 	w.WriteHeader(http.StatusCreated)
 	err = json.NewEncoder(w).Encode(task)
 	if err != nil {
@@ -268,13 +223,13 @@ func (h *TaskHandler) updateTask(w http.ResponseWriter, r *http.Request, id uint
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// Validate request
-	if req.Title == "" {
-		http.Error(w, "Title is required", http.StatusBadRequest)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeProblem(w, r, err.Error(), http.StatusPreconditionFailed)
 		return
 	}
 
@@ -286,14 +241,79 @@ func (h *TaskHandler) updateTask(w http.ResponseWriter, r *http.Request, id uint
 		req.Description,
 		req.Status,
 		req.DueDate,
+		version,
 	)
 	if err != nil {
-		http.Error(w, "Failed to update task: "+err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	// Return task
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, task.Version)
+	err = json.NewEncoder(w).Encode(task)
+	if err != nil {
+		return
+	}
+}
+
+// patchTask handles PATCH /tasks/{id}
+func (h *TaskHandler) patchTask(w http.ResponseWriter, r *http.Request, id uint64) {
+	task, err := h.taskUseCase.GetByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeProblem(w, r, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
+	// Apply the patch only to the version it was computed against
+	if version == 0 {
+		version = task.Version
+	}
+
+	changes, ok := applyPatch(w, r, task)
+	if !ok {
+		return
+	}
+
+	// Collect the changed fields
+	var patch usecase.TaskPatch
+	var errs entity.ValidationErrors
+	for _, name := range slices.Sorted(maps.Keys(changes)) {
+		value := changes[name]
+		switch name {
+		case "title":
+			patch.Title = patchValue[string](name, value, "must be a string", &errs)
+		case "description":
+			patch.Description = patchValue[string](name, value, "must be a string", &errs)
+		case "status":
+			patch.Status = patchValue[entity.TaskStatus](name, value, "must be a string", &errs)
+		case "due_date":
+			patch.DueDate = patchValue[*time.Time](name, value, "must be an RFC 3339 timestamp or null", &errs)
+		default:
+			readOnlyMember(name, &errs)
+		}
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	// Patch task
+	task, err = h.taskUseCase.Patch(r.Context(), id, patch, version)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return task
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, task.Version)
 	err = json.NewEncoder(w).Encode(task)
 	if err != nil {
 		return
@@ -302,9 +322,16 @@ func (h *TaskHandler) updateTask(w http.ResponseWriter, r *http.Request, id uint
 
 // deleteTask handles DELETE /tasks/{id}
 func (h *TaskHandler) deleteTask(w http.ResponseWriter, r *http.Request, id uint64) {
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeProblem(w, r, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
 	// Delete task
-	if err := h.taskUseCase.Delete(r.Context(), id); err != nil {
-		http.Error(w, "Failed to delete task: "+err.Error(), http.StatusBadRequest)
+	err = h.taskUseCase.Delete(r.Context(), id, version)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -315,14 +342,21 @@ func (h *TaskHandler) deleteTask(w http.ResponseWriter, r *http.Request, id uint
 // markTaskInProgress handles PUT /tasks/{id}/in-progress
 func (h *TaskHandler) markTaskInProgress(w http.ResponseWriter, r *http.Request, id uint64) {
 	// Mark task as in progress
-	task, err := h.taskUseCase.MarkInProgress(r.Context(), id)
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		http.Error(w, "Failed to mark task as in progress: "+err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
+	task, err := h.taskUseCase.MarkInProgress(r.Context(), id, version)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	// Return task
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, task.Version)
 	err = json.NewEncoder(w).Encode(task)
 	if err != nil {
 		return
@@ -332,16 +366,163 @@ func (h *TaskHandler) markTaskInProgress(w http.ResponseWriter, r *http.Request,
 // markTaskCompleted handles PUT /tasks/{id}/completed
 func (h *TaskHandler) markTaskCompleted(w http.ResponseWriter, r *http.Request, id uint64) {
 	// Mark task as completed
-	task, err := h.taskUseCase.MarkCompleted(r.Context(), id)
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		http.Error(w, "Failed to mark task as completed: "+err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
+	task, err := h.taskUseCase.MarkCompleted(r.Context(), id, version)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	// Return task
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, task.Version)
 	err = json.NewEncoder(w).Encode(task)
 	if err != nil {
 		return
 	}
 }
+
+// restoreTask handles POST /tasks/{id}/restore
+func (h *TaskHandler) restoreTask(w http.ResponseWriter, r *http.Request, id uint64) {
+	// Restore task
+	task, err := h.taskUseCase.Restore(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return task
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, task.Version)
+	err = json.NewEncoder(w).Encode(task)
+	if err != nil {
+		return
+	}
+}
+
+// deletedTaskCursor returns the cursor of a task in the trash
+func deletedTaskCursor(task *entity.Task) repository.Cursor {
+	return repository.TimeCursor(*task.DeletedAt, task.ID)
+}
+
+// snippetWords is the number of words of a task description shown around the
+// first match of a search
+const snippetWords = 20
+
+// taskSearchResult is a task found by a search
+type taskSearchResult struct {
+	Task       *entity.Task   `json:"task"`
+	Rank       float64        `json:"rank"`
+	Highlights taskHighlights `json:"highlights"`
+}
+
+// taskHighlights holds the title of a task found by a search and a snippet of
+// its description around the first match, if any, as HTML with the words
+// matching the query wrapped in <mark> tags
+type taskHighlights struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// taskSearchCursor returns the cursor of a search result in a list ordered by relevance
+func taskSearchCursor(result *taskSearchResult) repository.Cursor {
+	return repository.RankCursor(result.Rank, result.Task.ID)
+}
+
+// taskQuery parses the filter and sort query parameters of task lists,
+// reporting every invalid parameter as entity.ValidationErrors
+func taskQuery(r *http.Request) (repository.TaskFilter, repository.TaskSort, error) {
+	query := r.URL.Query()
+	var filter repository.TaskFilter
+	var sort repository.TaskSort
+	var errs entity.ValidationErrors
+
+	// Statuses may be repeated or separated by commas
+	for _, value := range query["status"] {
+		for _, status := range strings.Split(value, ",") {
+			filter.Statuses = append(filter.Statuses, entity.TaskStatus(strings.TrimSpace(status)))
+		}
+	}
+	if slices.ContainsFunc(filter.Statuses, func(status entity.TaskStatus) bool { return !status.Valid() }) {
+		errs = append(errs, entity.FieldError{
+			Field:   "status",
+			Code:    entity.ValidationInvalidEnum,
+			Message: "must be one or more of pending, in_progress, completed",
+		})
+	}
+
+	if value := query.Get("user_id"); value != "" {
+		userID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || userID == 0 {
+			errs = append(errs, entity.FieldError{
+				Field:   "user_id",
+				Code:    entity.ValidationInvalidFormat,
+				Message: "must be a user ID",
+			})
+		}
+		filter.UserID = userID
+	}
+
+	filter.DueAfter = timeParam(query, "due_after", &errs)
+	filter.DueBefore = timeParam(query, "due_before", &errs)
+	filter.CreatedAfter = timeParam(query, "created_after", &errs)
+	filter.CreatedBefore = timeParam(query, "created_before", &errs)
+	filter.TitleContains = query.Get("title")
+
+	if value := query.Get("sort"); value != "" {
+		sort.Field = repository.TaskSortField(value)
+		if !sort.Field.Valid() {
+			errs = append(errs, entity.FieldError{
+				Field:   "sort",
+				Code:    entity.ValidationInvalidEnum,
+				Message: "must be one of id, created_at, updated_at, due_date, title",
+			})
+		}
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		sort.Descending = true
+	default:
+		errs = append(errs, entity.FieldError{
+			Field:   "order",
+			Code:    entity.ValidationInvalidEnum,
+			Message: "must be asc or desc",
+		})
+	}
+
+	if len(errs) > 0 {
+		return repository.TaskFilter{}, repository.TaskSort{}, errs
+	}
+
+	return filter, sort, nil
+}
+
+// timeParam parses the query parameter name as an RFC 3339 timestamp or a
+// date, which stands for midnight UTC. It returns nil if the parameter is
+// missing and records an invalid value in errs.
+func timeParam(query url.Values, name string, errs *entity.ValidationErrors) *time.Time {
+	value := query.Get(name)
+	if value == "" {
+		return nil
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+
+	*errs = append(*errs, entity.FieldError{
+		Field:   name,
+		Code:    entity.ValidationInvalidFormat,
+		Message: "must be an RFC 3339 timestamp or a date such as 2026-10-19",
+	})
+	return nil
+}
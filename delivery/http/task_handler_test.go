@@ -0,0 +1,54 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/delivery/http/middleware"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+)
+
+func TestSearchTasksRejectsInvalidQueries(t *testing.T) {
+	h := NewTaskHandler(nil, Pagination{DefaultLimit: 10, MaxLimit: 100, CursorSecret: []byte("secret")})
+
+	tests := []struct {
+		query string
+		want  []entity.FieldError
+	}{
+		{query: "", want: []entity.FieldError{{Field: "q", Code: entity.ValidationRequired}}},
+		{query: "q=milk&sort=title", want: []entity.FieldError{{Field: "sort", Code: entity.ValidationUnsupported}}},
+		{
+			query: "q=milk&sort=title&order=desc",
+			want:  []entity.FieldError{{Field: "sort", Code: entity.ValidationUnsupported}, {Field: "order", Code: entity.ValidationUnsupported}},
+		},
+		{
+			query: "q=+&order=",
+			want:  []entity.FieldError{{Field: "q", Code: entity.ValidationRequired}, {Field: "order", Code: entity.ValidationUnsupported}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.searchTasks(w, httptest.NewRequest(http.MethodGet, "/tasks/search?"+tt.query, nil))
+
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+			}
+			var problem middleware.Problem
+			if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+				t.Fatalf("decoding problem: %v", err)
+			}
+			if len(problem.Errors) != len(tt.want) {
+				t.Fatalf("errors = %+v, want %+v", problem.Errors, tt.want)
+			}
+			for i, want := range tt.want {
+				if got := problem.Errors[i]; got.Field != want.Field || got.Code != want.Code {
+					t.Errorf("error %d = %s %s, want %s %s", i, got.Field, got.Code, want.Field, want.Code)
+				}
+			}
+		})
+	}
+}
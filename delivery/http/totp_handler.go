@@ -0,0 +1,159 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/usecase"
+)
+
+// TOTPHandler represents the HTTP handler for two-factor authentication operations
+type TOTPHandler struct {
+	userUseCase *usecase.UserUseCase
+}
+
+// NewTOTPHandler creates a new two-factor authentication handler
+func NewTOTPHandler(userUseCase *usecase.UserUseCase) *TOTPHandler {
+	return &TOTPHandler{
+		userUseCase: userUseCase,
+	}
+}
+
+// totpRequest is the body of the two-factor endpoints. Each reads the fields it needs.
+type totpRequest struct {
+	Password string `json:"password"`
+	TOTPCode string `json:"totp_code"`
+}
+
+// totpEnrollmentResponse is the body returned when two-factor enrollment starts
+type totpEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// recoveryCodesResponse is the body returned when recovery codes are issued
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// handleEnroll handles POST /auth/totp
+func (h *TOTPHandler) handleEnroll(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTOTPRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Start enrollment
+	enrollment, err := h.userUseCase.EnrollTOTP(r.Context(), req.Password)
+	if writeTOTPError(w, r, err) {
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return secret
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(totpEnrollmentResponse{Secret: enrollment.Secret, URI: enrollment.URI})
+}
+
+// handleConfirm handles POST /auth/totp/confirm
+func (h *TOTPHandler) handleConfirm(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTOTPRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Confirm enrollment
+	codes, err := h.userUseCase.ConfirmTOTP(r.Context(), req.TOTPCode)
+	if writeTOTPError(w, r, err) {
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeRecoveryCodes(w, codes)
+}
+
+// handleRecoveryCodes handles POST /auth/totp/recovery-codes
+func (h *TOTPHandler) handleRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTOTPRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Replace recovery codes
+	codes, err := h.userUseCase.RegenerateRecoveryCodes(r.Context(), req.Password, req.TOTPCode)
+	if writeTOTPError(w, r, err) {
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeRecoveryCodes(w, codes)
+}
+
+// handleDisable handles POST /auth/totp/disable
+func (h *TOTPHandler) handleDisable(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTOTPRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Disable two-factor authentication
+	err := h.userUseCase.DisableTOTP(r.Context(), req.Password, req.TOTPCode)
+	if writeTOTPError(w, r, err) {
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return success
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeTOTPRequest parses the body of a two-factor request. It writes an error
+// response and returns false if it is invalid.
+func decodeTOTPRequest(w http.ResponseWriter, r *http.Request) (totpRequest, bool) {
+	var req totpRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+
+	return req, true
+}
+
+// writeTOTPError responds with 403 Forbidden if err reports a wrong password or
+// two-factor code, which is not an authentication failure for a signed-in
+// user, and reports whether it did
+func writeTOTPError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidCredentials):
+		writeProblem(w, r, "Password is incorrect", http.StatusForbidden)
+	case errors.Is(err, usecase.ErrInvalidTOTPCode):
+		writeProblem(w, r, "Invalid two-factor code", http.StatusForbidden)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// writeRecoveryCodes writes newly issued recovery codes
+func writeRecoveryCodes(w http.ResponseWriter, codes []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(recoveryCodesResponse{RecoveryCodes: codes})
+}
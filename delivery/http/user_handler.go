@@ -2,102 +2,73 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
-	"strings"
 
+	"github.com/dimasbagussusilo/go-clean-boilerplate/delivery/http/middleware"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
 	"github.com/dimasbagussusilo/go-clean-boilerplate/usecase"
 )
 
 // UserHandler represents the HTTP handler for user operations
 type UserHandler struct {
-	userUseCase *usecase.UserUseCase
+	userUseCase    *usecase.UserUseCase
+	accountUseCase *usecase.AccountUseCase
+	authUseCase    *usecase.AuthUseCase
+	pagination     Pagination
 }
 
 // NewUserHandler creates a new user handler
-func NewUserHandler(userUseCase *usecase.UserUseCase) *UserHandler {
+func NewUserHandler(userUseCase *usecase.UserUseCase, accountUseCase *usecase.AccountUseCase, authUseCase *usecase.AuthUseCase, pagination Pagination) *UserHandler {
 	return &UserHandler{
-		userUseCase: userUseCase,
+		userUseCase:    userUseCase,
+		accountUseCase: accountUseCase,
+		authUseCase:    authUseCase,
+		pagination:     pagination,
 	}
 }
 
-// RegisterRoutes registers the user routes
-func (h *UserHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/users", h.handleUsers)
-	mux.HandleFunc("/users/", h.handleUserByID)
-}
-
-// handleUsers handles the /users endpoint
-func (h *UserHandler) handleUsers(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		h.getUsers(w, r)
-	case http.MethodPost:
-		h.createUser(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
-
-// handleUserByID handles the /users/{id} endpoint
-func (h *UserHandler) handleUserByID(w http.ResponseWriter, r *http.Request) {
-	// Extract ID from URL
-	path := strings.TrimPrefix(r.URL.Path, "/users/")
-	if path == "" {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+// getUsers handles GET /users
+func (h *UserHandler) getUsers(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	page, ok := h.pagination.page(w, r)
+	if !ok {
 		return
 	}
 
-	id, err := strconv.ParseUint(path, 10, 64)
+	// Get users
+	users, total, err := h.userUseCase.List(r.Context(), page)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
-	switch r.Method {
-	case http.MethodGet:
-		h.getUserByID(w, r, id)
-	case http.MethodPut:
-		h.updateUser(w, r, id)
-	case http.MethodDelete:
-		h.deleteUser(w, r, id)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
+	// Return users
+	writePage(w, r, h.pagination, page, users, total, userCursor)
 }
 
-// getUsers handles GET /users
-func (h *UserHandler) getUsers(w http.ResponseWriter, r *http.Request) {
+// getDeletedUsers handles GET /users/trash
+func (h *UserHandler) getDeletedUsers(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-
-	limit := 10 // Default limit
-	if limitStr != "" {
-		parsedLimit, err := strconv.Atoi(limitStr)
-		if err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
-	}
-
-	offset := 0 // Default offset
-	if offsetStr != "" {
-		parsedOffset, err := strconv.Atoi(offsetStr)
-		if err == nil && parsedOffset >= 0 {
-			offset = parsedOffset
-		}
+	page, ok := h.pagination.page(w, r)
+	if !ok {
+		return
 	}
 
-	// Get users
-	users, err := h.userUseCase.List(r.Context(), limit, offset)
+	// Get deleted users
+	users, total, err := h.userUseCase.ListDeleted(r.Context(), page)
 	if err != nil {
-		http.Error(w, "Failed to get users: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
 	// Return users
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(users)
+	writePage(w, r, h.pagination, page, users, total, deletedUserCursor)
 }
 
 // getUserByID handles GET /users/{id}
@@ -105,12 +76,13 @@ func (h *UserHandler) getUserByID(w http.ResponseWriter, r *http.Request, id uin
 	// Get user
 	user, err := h.userUseCase.GetByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
 	// Return user
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, user.Version)
 	json.NewEncoder(w).Encode(user)
 }
 
@@ -126,13 +98,7 @@ func (h *UserHandler) createUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	// Validate request
-	if req.Username == "" || req.Email == "" || req.Password == "" {
-		http.Error(w, "Username, email, and password are required", http.StatusBadRequest)
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
@@ -146,12 +112,19 @@ func (h *UserHandler) createUser(w http.ResponseWriter, r *http.Request) {
 		req.LastName,
 	)
 	if err != nil {
-		http.Error(w, "Failed to create user: "+err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
+	// Ask the new user to verify their email. If the message cannot be sent,
+	// they can request another one from /auth/verify-email/send.
+	if err := h.accountUseCase.SendVerification(r.Context(), user); err != nil {
+		middleware.RecordError(r.Context(), fmt.Errorf("sending verification email: %w", err))
+	}
+
 	// Return user
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, user.Version)
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(user)
 }
@@ -167,13 +140,13 @@ func (h *UserHandler) updateUser(w http.ResponseWriter, r *http.Request, id uint
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// Validate request
-	if req.Username == "" || req.Email == "" {
-		http.Error(w, "Username and email are required", http.StatusBadRequest)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeProblem(w, r, err.Error(), http.StatusPreconditionFailed)
 		return
 	}
 
@@ -185,25 +158,217 @@ func (h *UserHandler) updateUser(w http.ResponseWriter, r *http.Request, id uint
 		req.Email,
 		req.FirstName,
 		req.LastName,
+		version,
 	)
 	if err != nil {
-		http.Error(w, "Failed to update user: "+err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
+		return
+	}
+
+	// Return user
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, user.Version)
+	json.NewEncoder(w).Encode(user)
+}
+
+// patchUser handles PATCH /users/{id}
+func (h *UserHandler) patchUser(w http.ResponseWriter, r *http.Request, id uint64) {
+	user, err := h.userUseCase.GetByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeProblem(w, r, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
+	// Apply the patch only to the version it was computed against
+	if version == 0 {
+		version = user.Version
+	}
+
+	changes, ok := applyPatch(w, r, user)
+	if !ok {
+		return
+	}
+
+	// Collect the changed fields. The role and password have endpoints of their own.
+	var patch usecase.UserPatch
+	var errs entity.ValidationErrors
+	for _, name := range slices.Sorted(maps.Keys(changes)) {
+		value := changes[name]
+		switch name {
+		case "username":
+			patch.Username = patchValue[string](name, value, "must be a string", &errs)
+		case "email":
+			patch.Email = patchValue[string](name, value, "must be a string", &errs)
+		case "first_name":
+			patch.FirstName = patchValue[string](name, value, "must be a string", &errs)
+		case "last_name":
+			patch.LastName = patchValue[string](name, value, "must be a string", &errs)
+		default:
+			readOnlyMember(name, &errs)
+		}
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	// Patch user
+	user, err = h.userUseCase.Patch(r.Context(), id, patch, version)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return user
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, user.Version)
+	json.NewEncoder(w).Encode(user)
+}
+
+// changePassword handles PUT /users/{id}/password
+func (h *UserHandler) changePassword(w http.ResponseWriter, r *http.Request, id uint64) {
+	// Parse request body
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Change password
+	tokens, err := h.authUseCase.ChangePassword(r.Context(), id, req.CurrentPassword, req.NewPassword)
+	if errors.Is(err, usecase.ErrInvalidCredentials) {
+		writeProblem(w, r, "Current password is incorrect", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Replace the access token the caller used, which stopped working
+	if tokens != nil {
+		writeTokens(w, tokens)
+		return
+	}
+
+	// Return success
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// changeRole handles PUT /users/{id}/role
+func (h *UserHandler) changeRole(w http.ResponseWriter, r *http.Request, id uint64) {
+	// Parse request body
+	var req struct {
+		Role entity.Role `json:"role"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeProblem(w, r, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
+	// Change role
+	user, err := h.userUseCase.ChangeRole(r.Context(), id, req.Role, version)
+	if errors.Is(err, usecase.ErrOwnRole) {
+		writeProblem(w, r, "You cannot change your own role", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	// Return user
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, user.Version)
 	json.NewEncoder(w).Encode(user)
 }
 
+// unlockUser handles POST /users/{id}/unlock
+func (h *UserHandler) unlockUser(w http.ResponseWriter, r *http.Request, id uint64) {
+	// Unlock user
+	err := h.userUseCase.Unlock(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return success
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // deleteUser handles DELETE /users/{id}
 func (h *UserHandler) deleteUser(w http.ResponseWriter, r *http.Request, id uint64) {
+	// Parse query parameters
+	policy, err := usecase.ParseDeletePolicy(r.URL.Query().Get("on_tasks"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var reassignTo uint64
+	if policy == usecase.DeletePolicyReassign {
+		reassignTo, err = strconv.ParseUint(r.URL.Query().Get("reassign_to"), 10, 64)
+		if err != nil {
+			writeProblem(w, r, "Invalid reassign_to user ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeProblem(w, r, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
 	// Delete user
-	if err := h.userUseCase.Delete(r.Context(), id); err != nil {
-		http.Error(w, "Failed to delete user: "+err.Error(), http.StatusBadRequest)
+	err = h.userUseCase.Delete(r.Context(), id, version, policy, reassignTo)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	// Return success
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// restoreUser handles POST /users/{id}/restore
+func (h *UserHandler) restoreUser(w http.ResponseWriter, r *http.Request, id uint64) {
+	// Restore user
+	user, err := h.userUseCase.Restore(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return user
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, user.Version)
+	json.NewEncoder(w).Encode(user)
+}
+
+// userCursor returns the cursor of a user in a list ordered by ID
+func userCursor(user *entity.User) repository.Cursor {
+	return repository.IDCursor(user.ID)
+}
+
+// deletedUserCursor returns the cursor of a user in the trash
+func deletedUserCursor(user *entity.User) repository.Cursor {
+	return repository.TimeCursor(*user.DeletedAt, user.ID)
+}
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/delivery/http/middleware"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+)
+
+// writeValidationErrors responds with 422 Unprocessable Entity listing every
+// invalid field if err carries entity.ValidationErrors, and reports whether it did
+func writeValidationErrors(w http.ResponseWriter, r *http.Request, err error) bool {
+	var errs entity.ValidationErrors
+	if !errors.As(err, &errs) {
+		return false
+	}
+
+	middleware.WriteProblem(w, r, middleware.Problem{
+		Type:   problemTypeValidation,
+		Title:  "Validation failed",
+		Status: http.StatusUnprocessableEntity,
+		Detail: "One or more fields are invalid",
+		Errors: errs,
+	})
+
+	return true
+}
@@ -0,0 +1,119 @@
+package entity
+
+import (
+	"slices"
+	"time"
+)
+
+// APIKeyNameMaxLength is the longest name an API key may have
+const APIKeyNameMaxLength = 100
+
+// APIKeyScope limits what a request authenticated with an API key may do
+type APIKeyScope string
+
+const (
+	// APIKeyScopeTasksRead allows reading tasks
+	APIKeyScopeTasksRead APIKeyScope = "tasks:read"
+	// APIKeyScopeTasksWrite allows creating, changing and deleting tasks
+	APIKeyScopeTasksWrite APIKeyScope = "tasks:write"
+	// APIKeyScopeUsersAdmin allows managing users and the trash
+	APIKeyScopeUsersAdmin APIKeyScope = "users:admin"
+)
+
+// scopePermissions lists the permissions each API key scope grants
+var scopePermissions = map[APIKeyScope][]Permission{
+	APIKeyScopeTasksRead:  {PermissionTaskRead},
+	APIKeyScopeTasksWrite: {PermissionTaskCreate, PermissionTaskUpdate, PermissionTaskDelete},
+	APIKeyScopeUsersAdmin: {
+		PermissionUserRead, PermissionUserList, PermissionUserCreate, PermissionUserUpdate,
+		PermissionUserPassword, PermissionUserDelete, PermissionUserTrash, PermissionUserRole,
+		PermissionUserAPIKeys, PermissionUserSessions, PermissionUserTOTP, PermissionUserUnlock, PermissionAuditRead,
+		PermissionTaskTrash, PermissionTaskReassign,
+	},
+}
+
+// Valid reports whether the scope is one of the known scopes
+func (s APIKeyScope) Valid() bool {
+	_, ok := scopePermissions[s]
+	return ok
+}
+
+// Grants reports whether the scope allows permission
+func (s APIKeyScope) Grants(permission Permission) bool {
+	return slices.Contains(scopePermissions[s], permission)
+}
+
+// APIKey represents a long-lived credential a user issues to non-interactive callers.
+// Only a hash of the key is stored; Prefix is kept in clear to identify it.
+type APIKey struct {
+	ID         uint64        `json:"id"`
+	UserID     uint64        `json:"user_id"`
+	Name       string        `json:"name"`
+	Prefix     string        `json:"prefix"`
+	Hash       string        `json:"-"` // Hash is not exposed in JSON
+	Scopes     []APIKeyScope `json:"scopes"`
+	ExpiresAt  *time.Time    `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time    `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	RevokedAt  *time.Time    `json:"revoked_at,omitempty"`
+}
+
+// NewAPIKey creates a new API key
+func NewAPIKey(userID uint64, name string, scopes []APIKeyScope, expiresAt *time.Time) *APIKey {
+	return &APIKey{
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+}
+
+// Validate validates the API key entity and reports every invalid field as ValidationErrors
+func (k *APIKey) Validate() error {
+	var errs ValidationErrors
+
+	if errs.required("name", k.Name) {
+		errs.maxLength("name", k.Name, APIKeyNameMaxLength)
+	}
+
+	if len(k.Scopes) == 0 {
+		errs.required("scopes", "")
+	}
+	for _, scope := range k.Scopes {
+		if !scope.Valid() {
+			errs = append(errs, FieldError{
+				Field:   "scopes",
+				Code:    ValidationInvalidEnum,
+				Message: "must only contain tasks:read, tasks:write, users:admin",
+			})
+			break
+		}
+	}
+
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(k.CreatedAt) {
+		errs = append(errs, FieldError{
+			Field:   "expires_at",
+			Code:    ValidationExpiryInPast,
+			Message: "must be in the future",
+		})
+	}
+
+	return errs.err()
+}
+
+// Active reports whether the key can still be used at the given time
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// scopesGrant reports whether any of scopes allows permission
+func scopesGrant(scopes []APIKeyScope, permission Permission) bool {
+	for _, scope := range scopes {
+		if scope.Grants(permission) {
+			return true
+		}
+	}
+
+	return false
+}
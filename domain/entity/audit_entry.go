@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"time"
+)
+
+// AuditAction names a security-relevant event recorded in the audit log
+type AuditAction string
+
+const (
+	// AuditActionAccountLocked records that an account was locked after too many failed logins
+	AuditActionAccountLocked AuditAction = "account_locked"
+	// AuditActionAddressLocked records that a client address was locked out after too many failed logins
+	AuditActionAddressLocked AuditAction = "address_locked"
+	// AuditActionAccountUnlocked records that an admin lifted the lockout of an account
+	AuditActionAccountUnlocked AuditAction = "account_unlocked"
+)
+
+// AuditEntry represents a single event in the audit log. ActorID is the user
+// who caused it, if anyone did, and UserID the account it concerns, if any.
+type AuditEntry struct {
+	ID        uint64      `json:"id"`
+	Action    AuditAction `json:"action"`
+	ActorID   *uint64     `json:"actor_id,omitempty"`
+	UserID    *uint64     `json:"user_id,omitempty"`
+	Email     string      `json:"email,omitempty"`
+	IPAddress string      `json:"ip_address,omitempty"`
+	Detail    string      `json:"detail,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// NewAuditEntry creates a new audit entry for action
+func NewAuditEntry(action AuditAction) *AuditEntry {
+	return &AuditEntry{
+		Action:    action,
+		CreatedAt: time.Now(),
+	}
+}
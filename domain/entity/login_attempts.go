@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+)
+
+// LoginAttempts counts the recent failed logins for an account or a client
+// address, which Key tells apart, and whether they are locked out
+type LoginAttempts struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+// Locked reports whether logins are refused until after now
+func (a *LoginAttempts) Locked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
+
+// Stale reports whether the attempts no longer count at now: their lockout
+// ended, or they were never locked and the last failure is older than window
+func (a *LoginAttempts) Stale(now time.Time, window time.Duration) bool {
+	if a.LockedUntil != nil {
+		return !a.Locked(now)
+	}
+
+	return now.Sub(a.LastFailureAt) >= window
+}
@@ -0,0 +1,25 @@
+package entity
+
+// Principal represents the authenticated caller of a request
+type Principal struct {
+	UserID uint64 `json:"user_id"`
+	Role   Role   `json:"role"`
+	// APIKeyID is set when the caller authenticated with an API key, whose
+	// scopes then further restrict what the role allows
+	APIKeyID uint64        `json:"api_key_id,omitempty"`
+	Scopes   []APIKeyScope `json:"scopes,omitempty"`
+
+	// SessionID is set when the caller authenticated with a session cookie
+	SessionID string `json:"-"`
+	// TokenID is set when the caller authenticated with an access token
+	TokenID string `json:"-"`
+}
+
+// Scope returns the records the principal may apply permission to
+func (p *Principal) Scope(permission Permission) Scope {
+	if p.APIKeyID != 0 && !scopesGrant(p.Scopes, permission) {
+		return ScopeNone
+	}
+
+	return p.Role.Scope(permission)
+}
@@ -0,0 +1,107 @@
+package entity
+
+// Role determines what a user is allowed to do
+type Role string
+
+const (
+	// RoleAdmin may act on every user and task
+	RoleAdmin Role = "admin"
+	// RoleMember manages their own account and tasks
+	RoleMember Role = "member"
+	// RoleViewer may read tasks but not change them
+	RoleViewer Role = "viewer"
+)
+
+// Valid reports whether the role is one of the known roles
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleMember, RoleViewer:
+		return true
+	default:
+		return false
+	}
+}
+
+// Permission names an operation guarded by the permission matrix
+type Permission string
+
+const (
+	// PermissionUserRead covers looking up a single user
+	PermissionUserRead Permission = "users:read"
+	// PermissionUserList covers listing users
+	PermissionUserList Permission = "users:list"
+	// PermissionUserCreate covers creating users on behalf of others
+	PermissionUserCreate Permission = "users:create"
+	// PermissionUserUpdate covers changing a user's profile
+	PermissionUserUpdate Permission = "users:update"
+	// PermissionUserPassword covers changing a user's password
+	PermissionUserPassword Permission = "users:password"
+	// PermissionUserDelete covers moving a user to the trash
+	PermissionUserDelete Permission = "users:delete"
+	// PermissionUserTrash covers listing and restoring trashed users
+	PermissionUserTrash Permission = "users:trash"
+	// PermissionUserRole covers changing a user's role
+	PermissionUserRole Permission = "users:role"
+	// PermissionUserAPIKeys covers creating, listing and revoking a user's API keys
+	PermissionUserAPIKeys Permission = "users:api_keys"
+	// PermissionUserSessions covers listing and revoking a user's sessions
+	PermissionUserSessions Permission = "users:sessions"
+	// PermissionUserTOTP covers setting up and disabling a user's two-factor authentication
+	PermissionUserTOTP Permission = "users:totp"
+	// PermissionUserUnlock covers lifting the lockout of an account after failed logins
+	PermissionUserUnlock Permission = "users:unlock"
+	// PermissionAuditRead covers reading the audit log
+	PermissionAuditRead Permission = "audit:read"
+	// PermissionTaskRead covers looking up and listing tasks
+	PermissionTaskRead Permission = "tasks:read"
+	// PermissionTaskCreate covers creating tasks
+	PermissionTaskCreate Permission = "tasks:create"
+	// PermissionTaskUpdate covers changing a task and its status
+	PermissionTaskUpdate Permission = "tasks:update"
+	// PermissionTaskDelete covers moving a task to the trash
+	PermissionTaskDelete Permission = "tasks:delete"
+	// PermissionTaskTrash covers listing and restoring trashed tasks
+	PermissionTaskTrash Permission = "tasks:trash"
+	// PermissionTaskReassign covers handing a deleted user's tasks to another user
+	PermissionTaskReassign Permission = "tasks:reassign"
+)
+
+// Scope tells which records a role may apply a permission to
+type Scope int
+
+const (
+	// ScopeNone denies the permission
+	ScopeNone Scope = iota
+	// ScopeOwn grants the permission on the user's own account and tasks
+	ScopeOwn
+	// ScopeAll grants the permission on every record
+	ScopeAll
+)
+
+// permissions is the permission matrix. Missing entries deny the permission.
+var permissions = map[Permission]map[Role]Scope{
+	PermissionUserRead:     {RoleAdmin: ScopeAll, RoleMember: ScopeOwn, RoleViewer: ScopeOwn},
+	PermissionUserList:     {RoleAdmin: ScopeAll},
+	PermissionUserCreate:   {RoleAdmin: ScopeAll},
+	PermissionUserUpdate:   {RoleAdmin: ScopeAll, RoleMember: ScopeOwn},
+	PermissionUserPassword: {RoleAdmin: ScopeAll, RoleMember: ScopeOwn, RoleViewer: ScopeOwn},
+	PermissionUserDelete:   {RoleAdmin: ScopeAll, RoleMember: ScopeOwn},
+	PermissionUserTrash:    {RoleAdmin: ScopeAll},
+	PermissionUserRole:     {RoleAdmin: ScopeAll},
+	PermissionUserAPIKeys:  {RoleAdmin: ScopeAll, RoleMember: ScopeOwn, RoleViewer: ScopeOwn},
+	PermissionUserSessions: {RoleAdmin: ScopeAll, RoleMember: ScopeOwn, RoleViewer: ScopeOwn},
+	PermissionUserTOTP:     {RoleAdmin: ScopeOwn, RoleMember: ScopeOwn, RoleViewer: ScopeOwn},
+	PermissionUserUnlock:   {RoleAdmin: ScopeAll},
+	PermissionAuditRead:    {RoleAdmin: ScopeAll},
+	PermissionTaskRead:     {RoleAdmin: ScopeAll, RoleMember: ScopeOwn, RoleViewer: ScopeAll},
+	PermissionTaskCreate:   {RoleAdmin: ScopeAll, RoleMember: ScopeOwn},
+	PermissionTaskUpdate:   {RoleAdmin: ScopeAll, RoleMember: ScopeOwn},
+	PermissionTaskDelete:   {RoleAdmin: ScopeAll, RoleMember: ScopeOwn},
+	PermissionTaskTrash:    {RoleAdmin: ScopeAll},
+	PermissionTaskReassign: {RoleAdmin: ScopeAll},
+}
+
+// Scope returns the records the role may apply permission to
+func (r Role) Scope(permission Permission) Scope {
+	return permissions[permission][r]
+}
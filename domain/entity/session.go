@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"time"
+)
+
+// Session represents a browser login kept on the server and referenced by a cookie.
+// Only a hash of the cookie token is stored; ID identifies the session in listings.
+type Session struct {
+	ID         string    `json:"id"`
+	TokenHash  string    `json:"-"` // TokenHash is not exposed in JSON
+	CSRFToken  string    `json:"-"` // CSRFToken is only handed to the session's owner
+	UserID     uint64    `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// NewSession creates a new session for a user that ends after lifetime at the latest
+func NewSession(userID uint64, userAgent, ipAddress string, lifetime time.Duration) *Session {
+	now := time.Now()
+	return &Session{
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(lifetime),
+	}
+}
+
+// Active reports whether the session can still be used at the given time,
+// given that it ends after being idle for idleTimeout
+func (s *Session) Active(now time.Time, idleTimeout time.Duration) bool {
+	return now.Before(s.ExpiresAt) && now.Before(s.LastSeenAt.Add(idleTimeout))
+}
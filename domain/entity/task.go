@@ -16,6 +16,22 @@ const (
 	TaskStatusCompleted TaskStatus = "completed"
 )
 
+// Field length limits for tasks
+const (
+	TaskTitleMaxLength       = 200
+	TaskDescriptionMaxLength = 5000
+)
+
+// Valid reports whether the status is one of the known task statuses
+func (s TaskStatus) Valid() bool {
+	switch s {
+	case TaskStatusPending, TaskStatusInProgress, TaskStatusCompleted:
+		return true
+	default:
+		return false
+	}
+}
+
 // Task represents the task entity
 type Task struct {
 	ID          uint64     `json:"id"`
@@ -24,8 +40,10 @@ type Task struct {
 	Status      TaskStatus `json:"status"`
 	UserID      uint64     `json:"user_id"`
 	DueDate     *time.Time `json:"due_date,omitempty"`
+	Version     uint64     `json:"version"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 }
 
 // NewTask creates a new task
@@ -42,10 +60,35 @@ func NewTask(title, description string, userID uint64, dueDate *time.Time) *Task
 	}
 }
 
-// Validate validates the task entity
+// Validate validates the task entity and reports every invalid field as ValidationErrors
 func (t *Task) Validate() error {
-	// TODO: Implement validation logic
-	return nil
+	var errs ValidationErrors
+
+	if errs.required("title", t.Title) {
+		errs.maxLength("title", t.Title, TaskTitleMaxLength)
+	}
+	errs.maxLength("description", t.Description, TaskDescriptionMaxLength)
+
+	if t.Status == "" {
+		errs.required("status", string(t.Status))
+	} else if !t.Status.Valid() {
+		errs = append(errs, FieldError{
+			Field:   "status",
+			Code:    ValidationInvalidEnum,
+			Message: "must be one of pending, in_progress, completed",
+		})
+	}
+
+	if t.UserID == 0 {
+		errs = append(errs, FieldError{Field: "user_id", Code: ValidationRequired, Message: "is required"})
+	}
+
+	// A due date can never lie before the moment the task was created
+	if t.DueDate != nil && t.DueDate.Before(t.CreatedAt) {
+		errs = append(errs, FieldError{Field: "due_date", Code: ValidationDueDateInPast, Message: "must not be before the task was created"})
+	}
+
+	return errs.err()
 }
 
 // MarkInProgress marks the task as in progress
@@ -1,19 +1,48 @@
 package entity
 
 import (
+	"fmt"
 	"time"
 )
 
+// Field length limits for users
+const (
+	UserUsernameMaxLength = 50
+	UserEmailMaxLength    = 254
+	UserNameMaxLength     = 100
+
+	// UserPasswordMaxBytes is the longest password every supported hash algorithm accepts
+	UserPasswordMaxBytes = 72
+)
+
 // User represents the user entity
 type User struct {
-	ID        uint64    `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"` // Password is not exposed in JSON
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint64 `json:"id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	Password  string `json:"-"` // Password is not exposed in JSON
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Role      Role   `json:"role"`
+
+	// EmailVerifiedAt is set once the user proves they own Email
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+
+	// TOTPSecret is set once enrollment starts; TOTPEnabled once it is confirmed
+	TOTPSecret  string `json:"-"`
+	TOTPEnabled bool   `json:"totp_enabled"`
+	// TOTPLastStep is the time step of the last accepted code, which cannot be used again
+	TOTPLastStep int64 `json:"-"`
+	// RecoveryCodes holds the hashes of the unused recovery codes
+	RecoveryCodes []string `json:"-"`
+
+	// TokenVersion is raised to invalidate every access and refresh token issued before
+	TokenVersion uint64 `json:"-"`
+
+	Version   uint64     `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 // NewUser creates a new user
@@ -22,21 +51,60 @@ func NewUser(username, email, password, firstName, lastName string) *User {
 	return &User{
 		Username:  username,
 		Email:     email,
-		Password:  password, // Note: In a real application, this should be hashed
+		Password:  password, // Replaced by its hash before the user is stored
 		FirstName: firstName,
 		LastName:  lastName,
+		Role:      RoleMember,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 }
 
-// Validate validates the user entity
+// Validate validates the user entity and reports every invalid field as ValidationErrors
 func (u *User) Validate() error {
-	// TODO: Implement validation logic
-	return nil
+	var errs ValidationErrors
+
+	if errs.required("username", u.Username) {
+		errs.maxLength("username", u.Username, UserUsernameMaxLength)
+	}
+
+	if errs.required("email", u.Email) && errs.maxLength("email", u.Email, UserEmailMaxLength) {
+		errs.email("email", u.Email)
+	}
+
+	errs.required("password", u.Password)
+	errs.maxLength("first_name", u.FirstName, UserNameMaxLength)
+	errs.maxLength("last_name", u.LastName, UserNameMaxLength)
+
+	if u.Role == "" {
+		errs.required("role", string(u.Role))
+	} else if !u.Role.Valid() {
+		errs = append(errs, FieldError{
+			Field:   "role",
+			Code:    ValidationInvalidEnum,
+			Message: "must be one of admin, member, viewer",
+		})
+	}
+
+	return errs.err()
+}
+
+// ValidatePassword validates a plaintext password before it is hashed, reporting problems under field
+func ValidatePassword(field, password string) error {
+	var errs ValidationErrors
+
+	if errs.required(field, password) && len(password) > UserPasswordMaxBytes {
+		errs = append(errs, FieldError{
+			Field:   field,
+			Code:    ValidationMaxLength,
+			Message: fmt.Sprintf("must be at most %d bytes", UserPasswordMaxBytes),
+		})
+	}
+
+	return errs.err()
 }
 
 // FullName returns the user's full name
 func (u *User) FullName() string {
 	return u.FirstName + " " + u.LastName
-}
\ No newline at end of file
+}
@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"time"
+)
+
+// TokenPurpose tells what a user token can be exchanged for
+type TokenPurpose string
+
+const (
+	// TokenPurposeEmailVerification confirms that a user owns their email address
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+	// TokenPurposePasswordReset lets a user who lost their password choose a new one
+	TokenPurposePasswordReset TokenPurpose = "password_reset"
+)
+
+// UserToken represents a single-use secret mailed to a user. Only a hash of
+// the secret is stored, and it is tied to the address it was sent to.
+type UserToken struct {
+	ID        uint64       `json:"id"`
+	UserID    uint64       `json:"user_id"`
+	Purpose   TokenPurpose `json:"purpose"`
+	TokenHash string       `json:"-"` // TokenHash is not exposed in JSON
+	Email     string       `json:"email"`
+	CreatedAt time.Time    `json:"created_at"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    *time.Time   `json:"used_at,omitempty"`
+}
+
+// NewUserToken creates a new token sent to email that expires after lifetime
+func NewUserToken(userID uint64, purpose TokenPurpose, email string, lifetime time.Duration) *UserToken {
+	now := time.Now()
+	return &UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+	}
+}
+
+// Active reports whether the token can still be used at the given time
+func (t *UserToken) Active(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,136 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+)
+
+// ValidationCode identifies the rule a field value violates
+type ValidationCode string
+
+const (
+	// ValidationRequired means the field must not be empty
+	ValidationRequired ValidationCode = "required"
+	// ValidationMaxLength means the field is longer than allowed
+	ValidationMaxLength ValidationCode = "max_length"
+	// ValidationInvalidEmail means the field is not a valid email address
+	ValidationInvalidEmail ValidationCode = "invalid_email"
+	// ValidationInvalidEnum means the field is not one of the allowed values
+	ValidationInvalidEnum ValidationCode = "invalid_enum"
+	// ValidationDueDateInPast means the due date lies before the task was created
+	ValidationDueDateInPast ValidationCode = "due_date_in_past"
+	// ValidationExpiryInPast means the expiry time is not in the future
+	ValidationExpiryInPast ValidationCode = "expiry_in_past"
+	// ValidationNotFound means the field refers to a record that does not exist
+	ValidationNotFound ValidationCode = "not_found"
+	// ValidationInvalidFormat means the field cannot be parsed, such as a malformed timestamp
+	ValidationInvalidFormat ValidationCode = "invalid_format"
+	// ValidationReadOnly means the field cannot be changed by the request
+	ValidationReadOnly ValidationCode = "read_only"
+	// ValidationUnsupported means the parameter does not apply to the request
+	ValidationUnsupported ValidationCode = "unsupported"
+)
+
+// FieldError describes a single invalid field
+type FieldError struct {
+	Field   string         `json:"field"`
+	Code    ValidationCode `json:"code"`
+	Message string         `json:"message"`
+}
+
+// Error implements the error interface
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors lists every invalid field of an entity
+type ValidationErrors []FieldError
+
+// Error implements the error interface
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fieldErr.Error())
+	}
+
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Unwrap makes validation errors match domain.ErrValidation
+func (e ValidationErrors) Unwrap() error {
+	return domain.ErrValidation
+}
+
+// MergeValidationErrors combines the results of several validations into a single
+// ValidationErrors, dropping duplicates. Any other error is returned unchanged.
+func MergeValidationErrors(errs ...error) error {
+	var merged ValidationErrors
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		var fieldErrs ValidationErrors
+		if !errors.As(err, &fieldErrs) {
+			return err
+		}
+
+		for _, fieldErr := range fieldErrs {
+			if !slices.Contains(merged, fieldErr) {
+				merged = append(merged, fieldErr)
+			}
+		}
+	}
+
+	return merged.err()
+}
+
+// required records an error if value is empty
+func (e *ValidationErrors) required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		*e = append(*e, FieldError{Field: field, Code: ValidationRequired, Message: "is required"})
+		return false
+	}
+
+	return true
+}
+
+// maxLength records an error if value has more than max characters
+func (e *ValidationErrors) maxLength(field, value string, max int) bool {
+	if utf8.RuneCountInString(value) > max {
+		*e = append(*e, FieldError{
+			Field:   field,
+			Code:    ValidationMaxLength,
+			Message: fmt.Sprintf("must be at most %d characters", max),
+		})
+		return false
+	}
+
+	return true
+}
+
+// email records an error if value is not a bare email address
+func (e *ValidationErrors) email(field, value string) bool {
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Address != value {
+		*e = append(*e, FieldError{Field: field, Code: ValidationInvalidEmail, Message: "must be a valid email address"})
+		return false
+	}
+
+	return true
+}
+
+// err returns the collected errors, or nil if there are none
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
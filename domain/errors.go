@@ -0,0 +1,43 @@
+// Package domain defines the kinds of errors every layer reports failures with
+package domain
+
+import (
+	"errors"
+)
+
+// Kinds of errors. Every error the repositories and use cases return for a
+// failure the caller can act on matches one of them with errors.Is, so the
+// delivery layer can respond appropriately without inspecting messages.
+var (
+	// ErrNotFound means the requested record does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the operation clashes with the current state of a record
+	ErrConflict = errors.New("conflict")
+	// ErrValidation means the input of the operation is invalid
+	ErrValidation = errors.New("validation failed")
+	// ErrForbidden means the caller may not perform the operation
+	ErrForbidden = errors.New("forbidden")
+	// ErrUnauthorized means the caller could not be authenticated
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// Error is an error with a message of its own that belongs to one of the kinds above
+type Error struct {
+	Kind    error
+	Message string
+}
+
+// NewError creates an error of the given kind
+func NewError(kind error, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the kind of the error
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
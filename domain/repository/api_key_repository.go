@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+)
+
+// APIKeyRepository represents the API key repository contract
+type APIKeyRepository interface {
+	// GetByID retrieves an API key by its ID, including revoked and expired keys
+	GetByID(ctx context.Context, id uint64) (*entity.APIKey, error)
+
+	// GetByPrefix retrieves an API key by its unique prefix, including revoked and expired keys
+	GetByPrefix(ctx context.Context, prefix string) (*entity.APIKey, error)
+
+	// ListByUserID retrieves every API key owned by a user, newest first
+	ListByUserID(ctx context.Context, userID uint64) ([]*entity.APIKey, error)
+
+	// Create creates a new API key
+	Create(ctx context.Context, key *entity.APIKey) error
+
+	// Revoke marks an API key as revoked at the given time. Revoking a key twice keeps the first time.
+	Revoke(ctx context.Context, id uint64, at time.Time) error
+
+	// RevokeByUserID marks every API key of a user not revoked yet as revoked at
+	// the given time and returns how many there were
+	RevokeByUserID(ctx context.Context, userID uint64, at time.Time) (int64, error)
+
+	// MarkUsed records the time an API key was last used
+	MarkUsed(ctx context.Context, id uint64, at time.Time) error
+}
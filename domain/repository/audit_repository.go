@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+)
+
+// AuditRepository represents the audit log store contract
+type AuditRepository interface {
+	// Create appends an entry to the audit log
+	Create(ctx context.Context, entry *entity.AuditEntry) error
+
+	// List retrieves a page of audit entries, newest first
+	List(ctx context.Context, page Page) ([]*entity.AuditEntry, error)
+
+	// Count counts the audit entries
+	Count(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+)
+
+// ErrVersionConflict is returned by Update when the version being written no
+// longer matches the stored one, meaning someone else modified the record first
+var ErrVersionConflict = domain.NewError(domain.ErrConflict, "version conflict")
+
+// ErrUserHasTasks is returned by UserRepository.Delete when the user still owns
+// tasks that are not in the trash
+var ErrUserHasTasks = domain.NewError(domain.ErrConflict, "user has tasks")
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+)
+
+// LoginAttemptRepository represents the failed login counter store contract.
+// Counters change only through atomic operations, so concurrent logins, even
+// on separate instances, cannot miss each other's failures.
+type LoginAttemptRepository interface {
+	// Get retrieves the attempts recorded under key, or empty attempts if there are none
+	Get(ctx context.Context, key string) (*entity.LoginAttempts, error)
+
+	// Increment adds a failure at now to the attempts recorded under key and
+	// returns the result. Attempts that are stale at now, as told by
+	// LoginAttempts.Stale with window, start over first.
+	Increment(ctx context.Context, key string, now time.Time, window time.Duration) (*entity.LoginAttempts, error)
+
+	// Decrement takes back a failure added by Increment, never going below zero
+	Decrement(ctx context.Context, key string) error
+
+	// Lock locks the attempts recorded under key out until until, unless they
+	// are locked at now already, and reports whether it did
+	Lock(ctx context.Context, key string, now, until time.Time) (bool, error)
+
+	// Delete forgets the attempts recorded under key
+	Delete(ctx context.Context, key string) error
+
+	// Purge removes attempts whose last failure was before cutoff and that are
+	// not locked past it, and returns how many there were
+	Purge(ctx context.Context, cutoff time.Time) (int64, error)
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+)
+
+// Page selects a page of an ordered list. If After is set, the page starts
+// right after the record it points to and Offset is ignored.
+type Page struct {
+	Limit  int
+	Offset int
+	After  *Cursor
+}
+
+// Cursor points at a record of an ordered list by the value of the list's sort
+// key and the record's ID, which orders records with equal sort keys. Lists
+// ordered by ID alone leave Key empty.
+type Cursor struct {
+	Key string
+	ID  uint64
+}
+
+// errInvalidCursor is returned when a cursor does not fit the list it is used on
+var errInvalidCursor = domain.NewError(domain.ErrValidation, "invalid cursor")
+
+// IDCursor returns the cursor of a record in a list ordered by ID
+func IDCursor(id uint64) Cursor {
+	return Cursor{ID: id}
+}
+
+// TimeCursor returns the cursor of a record in a list ordered by a timestamp
+func TimeCursor(t time.Time, id uint64) Cursor {
+	return Cursor{Key: t.UTC().Format(time.RFC3339Nano), ID: id}
+}
+
+// RankCursor returns the cursor of a record in a list ordered by relevance
+func RankCursor(rank float64, id uint64) Cursor {
+	return Cursor{Key: strconv.FormatFloat(rank, 'g', -1, 64), ID: id}
+}
+
+// Skip returns how many records to skip before the page: Offset, or none when
+// the page starts after a cursor
+func (p Page) Skip() int {
+	if p.After != nil {
+		return 0
+	}
+
+	return p.Offset
+}
+
+// AfterID returns the ID a page of a list ordered by ID starts after, or 0 if
+// it has no cursor
+func (p Page) AfterID() uint64 {
+	if p.After == nil {
+		return 0
+	}
+
+	return p.After.ID
+}
+
+// AfterTime returns the timestamp and ID a page of a list ordered by a
+// timestamp starts after, or nil if it has no cursor
+func (p Page) AfterTime() (*time.Time, uint64, error) {
+	if p.After == nil {
+		return nil, 0, nil
+	}
+
+	t, err := p.After.Time()
+	if err != nil || t == nil {
+		return nil, 0, errInvalidCursor
+	}
+
+	return t, p.After.ID, nil
+}
+
+// Time returns the timestamp in the key of a TimeCursor, or nil if the key is
+// empty because the record has no such timestamp
+func (c Cursor) Time() (*time.Time, error) {
+	if c.Key == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, c.Key)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	return &t, nil
+}
+
+// Rank returns the relevance in the key of a RankCursor
+func (c Cursor) Rank() (float64, error) {
+	rank, err := strconv.ParseFloat(c.Key, 64)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+
+	return rank, nil
+}
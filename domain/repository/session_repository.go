@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+)
+
+// SessionRepository represents the session store contract
+type SessionRepository interface {
+	// GetByTokenHash retrieves a session by the hash of its cookie token, including expired sessions
+	GetByTokenHash(ctx context.Context, tokenHash string) (*entity.Session, error)
+
+	// ListByUserID retrieves every stored session of a user, most recently used first
+	ListByUserID(ctx context.Context, userID uint64) ([]*entity.Session, error)
+
+	// Create creates a new session
+	Create(ctx context.Context, session *entity.Session) error
+
+	// Touch records the time a session was last used
+	Touch(ctx context.Context, id string, lastSeenAt time.Time) error
+
+	// Delete removes a session
+	Delete(ctx context.Context, id string) error
+
+	// DeleteByUserID removes every session of a user and returns how many there were
+	DeleteByUserID(ctx context.Context, userID uint64) (int64, error)
+
+	// DeleteOthers removes every session of a user but the one with ID keepID and
+	// returns how many there were
+	DeleteOthers(ctx context.Context, userID uint64, keepID string) (int64, error)
+
+	// Purge removes sessions that expired before now or were last used before idleSince
+	Purge(ctx context.Context, now, idleSince time.Time) (int64, error)
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+)
+
+// TaskFilter selects the tasks of a list. Zero fields select every task.
+// Ranges include their After bound and exclude their Before bound.
+type TaskFilter struct {
+	Statuses      []entity.TaskStatus
+	UserID        uint64
+	DueAfter      *time.Time
+	DueBefore     *time.Time
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// TitleContains matches titles containing it, ignoring case
+	TitleContains string
+}
+
+// TaskSortField is a field tasks can be sorted by
+type TaskSortField string
+
+const (
+	// TaskSortID sorts tasks by ID
+	TaskSortID TaskSortField = "id"
+	// TaskSortCreatedAt sorts tasks by creation time
+	TaskSortCreatedAt TaskSortField = "created_at"
+	// TaskSortUpdatedAt sorts tasks by the time of their last update
+	TaskSortUpdatedAt TaskSortField = "updated_at"
+	// TaskSortDueDate sorts tasks by due date, placing tasks without one last
+	TaskSortDueDate TaskSortField = "due_date"
+	// TaskSortTitle sorts tasks by title, comparing bytes
+	TaskSortTitle TaskSortField = "title"
+)
+
+// Valid reports whether the field is one tasks can be sorted by
+func (f TaskSortField) Valid() bool {
+	switch f {
+	case TaskSortID, TaskSortCreatedAt, TaskSortUpdatedAt, TaskSortDueDate, TaskSortTitle:
+		return true
+	default:
+		return false
+	}
+}
+
+// TaskSort orders a list of tasks. Tasks with equal sort keys are ordered by
+// ID, and the zero value orders tasks by ID alone.
+type TaskSort struct {
+	Field      TaskSortField
+	Descending bool
+}
+
+// Cursor returns the cursor of task in a list ordered by s. The key is a
+// TimeCursor key for timestamps, empty for a missing due date, and the title
+// itself when sorting by title.
+func (s TaskSort) Cursor(task *entity.Task) Cursor {
+	switch s.Field {
+	case TaskSortCreatedAt:
+		return TimeCursor(task.CreatedAt, task.ID)
+	case TaskSortUpdatedAt:
+		return TimeCursor(task.UpdatedAt, task.ID)
+	case TaskSortDueDate:
+		if task.DueDate == nil {
+			return IDCursor(task.ID)
+		}
+		return TimeCursor(*task.DueDate, task.ID)
+	case TaskSortTitle:
+		return Cursor{Key: task.Title, ID: task.ID}
+	default:
+		return IDCursor(task.ID)
+	}
+}
+
+// TaskMatch is a task found by a search along with its relevance to the query,
+// which is higher the better the task matches
+type TaskMatch struct {
+	Task *entity.Task
+	Rank float64
+}
+
+// Cursor returns the cursor of a match in a list of search results
+func (m *TaskMatch) Cursor() Cursor {
+	return RankCursor(m.Rank, m.Task.ID)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
 )
@@ -11,18 +12,53 @@ type TaskRepository interface {
 	// GetByID retrieves a task by its ID
 	GetByID(ctx context.Context, id uint64) (*entity.Task, error)
 
-	// GetByUserID retrieves tasks by user ID
-	GetByUserID(ctx context.Context, userID uint64, limit, offset int) ([]*entity.Task, error)
+	// CountByUserID counts the tasks owned by a user, excluding trashed ones
+	CountByUserID(ctx context.Context, userID uint64) (int64, error)
+
+	// DeleteByUserID moves every task owned by a user to the trash
+	DeleteByUserID(ctx context.Context, userID uint64) (int64, error)
+
+	// ReassignUser transfers every task owned by a user, including trashed ones,
+	// to another user
+	ReassignUser(ctx context.Context, fromUserID, toUserID uint64) (int64, error)
 
 	// Create creates a new task
 	Create(ctx context.Context, task *entity.Task) error
 
-	// Update updates an existing task
+	// Update updates an existing task. It fails with ErrVersionConflict unless
+	// task.Version matches the stored version, and increments it on success.
 	Update(ctx context.Context, task *entity.Task) error
 
-	// Delete deletes a task by its ID
-	Delete(ctx context.Context, id uint64) error
+	// Delete moves a task to the trash. Trashed tasks are excluded from every
+	// other lookup until they are restored. A non-zero version must match the
+	// stored version, otherwise it fails with ErrVersionConflict.
+	Delete(ctx context.Context, id uint64, version uint64) error
+
+	// List retrieves a page of the tasks matching filter, ordered by sort.
+	// Cursors are the ones returned by sort.Cursor.
+	List(ctx context.Context, filter TaskFilter, sort TaskSort, page Page) ([]*entity.Task, error)
+
+	// Count counts the tasks outside the trash matching filter
+	Count(ctx context.Context, filter TaskFilter) (int64, error)
+
+	// Search retrieves a page of the tasks matching filter whose title or
+	// description contains every search term of query, most relevant first and
+	// then by ID. Cursors are the ones returned by TaskMatch.Cursor.
+	Search(ctx context.Context, query string, filter TaskFilter, page Page) ([]*TaskMatch, error)
+
+	// CountSearch counts the tasks Search finds for query and filter
+	CountSearch(ctx context.Context, query string, filter TaskFilter) (int64, error)
+
+	// ListDeleted retrieves a page of the tasks in the trash, most recently
+	// deleted first and then by ID. Cursors are TimeCursors of DeletedAt.
+	ListDeleted(ctx context.Context, page Page) ([]*entity.Task, error)
+
+	// CountDeleted counts the tasks in the trash
+	CountDeleted(ctx context.Context) (int64, error)
+
+	// Restore moves a task out of the trash
+	Restore(ctx context.Context, id uint64) error
 
-	// List retrieves a list of tasks with pagination
-	List(ctx context.Context, limit, offset int) ([]*entity.Task, error)
+	// Purge permanently removes tasks moved to the trash before the given time
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+)
+
+// Transactor represents the unit of work contract shared by the repositories
+type Transactor interface {
+	// WithinTx runs fn atomically. Repository calls made with the context passed
+	// to fn take part in the same transaction, which is committed when fn returns
+	// nil and rolled back otherwise. Nested calls join the outer transaction.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
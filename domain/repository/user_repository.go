@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
 )
@@ -20,12 +21,39 @@ type UserRepository interface {
 	// Create creates a new user
 	Create(ctx context.Context, user *entity.User) error
 
-	// Update updates an existing user
+	// Bootstrap records that the first user signed up and reports whether this
+	// call was the one to do so, which is true only once ever, whatever happens
+	// to that user later. Concurrent calls wait for each other, so it has to run
+	// in the transaction creating the user, to be undone if that fails.
+	Bootstrap(ctx context.Context) (bool, error)
+
+	// Update updates an existing user. It fails with ErrVersionConflict unless
+	// user.Version matches the stored version, and increments it on success.
 	Update(ctx context.Context, user *entity.User) error
 
-	// Delete deletes a user by their ID
-	Delete(ctx context.Context, id uint64) error
+	// Delete moves a user to the trash. Trashed users are excluded from every
+	// other lookup until they are restored, but keep their email and username.
+	// It fails with ErrUserHasTasks while the user owns tasks outside the trash.
+	// A non-zero version must match the stored version, otherwise it fails with
+	// ErrVersionConflict.
+	Delete(ctx context.Context, id uint64, version uint64) error
+
+	// List retrieves a page of users, ordered by ID
+	List(ctx context.Context, page Page) ([]*entity.User, error)
+
+	// Count counts the users outside the trash
+	Count(ctx context.Context) (int64, error)
+
+	// ListDeleted retrieves a page of the users in the trash, most recently
+	// deleted first and then by ID. Cursors are TimeCursors of DeletedAt.
+	ListDeleted(ctx context.Context, page Page) ([]*entity.User, error)
+
+	// CountDeleted counts the users in the trash
+	CountDeleted(ctx context.Context) (int64, error)
+
+	// Restore moves a user out of the trash
+	Restore(ctx context.Context, id uint64) error
 
-	// List retrieves a list of users with pagination
-	List(ctx context.Context, limit, offset int) ([]*entity.User, error)
+	// Purge permanently removes users moved to the trash before the given time
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
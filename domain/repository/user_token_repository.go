@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+)
+
+// UserTokenRepository represents the email verification and password reset token store contract
+type UserTokenRepository interface {
+	// GetByTokenHash retrieves a token by the hash of its secret, including used and expired tokens
+	GetByTokenHash(ctx context.Context, tokenHash string) (*entity.UserToken, error)
+
+	// Create creates a new token
+	Create(ctx context.Context, token *entity.UserToken) error
+
+	// MarkUsed records that a token was used. It fails if the token was already used.
+	MarkUsed(ctx context.Context, id uint64, at time.Time) error
+
+	// DeleteByUserID removes every token of a user issued for purpose
+	DeleteByUserID(ctx context.Context, userID uint64, purpose entity.TokenPurpose) error
+
+	// Purge removes tokens that were used or expired before now and returns how many there were
+	Purge(ctx context.Context, now time.Time) (int64, error)
+}
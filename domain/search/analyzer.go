@@ -0,0 +1,111 @@
+// Package search breaks text into the terms full-text search matches on and
+// highlights the words matching a query
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// stopWords are words too common to be worth searching for
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"but": true, "by": true, "for": true, "from": true, "if": true, "in": true, "into": true,
+	"is": true, "it": true, "no": true, "not": true, "of": true, "on": true, "or": true,
+	"so": true, "that": true, "the": true, "then": true, "there": true, "this": true,
+	"to": true, "was": true, "were": true, "will": true, "with": true,
+}
+
+// token is a word of a text, located by its byte offsets
+type token struct {
+	word       string
+	start, end int
+}
+
+// tokenize splits text into its words, which are runs of letters and digits,
+// lowercasing them
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWordRune := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWordRune && start < 0:
+			start = i
+		case !isWordRune && start >= 0:
+			tokens = append(tokens, token{word: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{word: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+
+	return tokens
+}
+
+// Words returns the words of text worth searching for: lowercased, in order,
+// without stop words
+func Words(text string) []string {
+	var words []string
+	for _, t := range tokenize(text) {
+		if !stopWords[t.word] {
+			words = append(words, t.word)
+		}
+	}
+
+	return words
+}
+
+// Terms returns the search terms of text: its Words reduced to their stems, so
+// that "fixes", "fixed" and "fixing" all match "fix"
+func Terms(text string) []string {
+	words := Words(text)
+	for i, word := range words {
+		words[i] = Stem(word)
+	}
+
+	return words
+}
+
+// Stem reduces a lowercase English word to a crude stem by removing common
+// inflectional suffixes. It is far simpler than a real stemmer, but maps the
+// usual forms of a word onto the same stem.
+func Stem(word string) string {
+	doubled := false
+	for _, suffix := range []struct{ from, to string }{
+		{"ies", "y"},
+		{"sses", "ss"},
+		{"ing", ""},
+		{"ed", ""},
+		{"s", ""},
+	} {
+		stem, ok := strings.CutSuffix(word, suffix.from)
+		if !ok || utf8.RuneCountInString(stem+suffix.to) < 3 {
+			continue
+		}
+
+		// Words like "class", "status" and "analysis" are not plurals
+		if suffix.from == "s" && strings.ContainsAny(stem[len(stem)-1:], "isu") {
+			break
+		}
+
+		word = stem + suffix.to
+		doubled = suffix.from == "ing" || suffix.from == "ed"
+		break
+	}
+
+	// "fixes" and "release" lose their final e like "fixed" and "released" did
+	if utf8.RuneCountInString(word) > 3 && strings.HasSuffix(word, "e") {
+		word = strings.TrimSuffix(word, "e")
+	}
+
+	// "running" and "planned" lose the consonant doubled before the suffix
+	if n := len(word); doubled && utf8.RuneCountInString(word) > 3 && word[n-1] < utf8.RuneSelf &&
+		word[n-1] == word[n-2] && !strings.ContainsRune("aeiouylsz", rune(word[n-1])) {
+		word = word[:n-1]
+	}
+
+	return word
+}
@@ -0,0 +1,82 @@
+package search
+
+import (
+	"html"
+	"slices"
+	"strings"
+)
+
+// Highlight returns text as HTML, wrapping the words matching terms, as
+// returned by Terms, in <mark> tags
+func Highlight(text string, terms []string) string {
+	var b strings.Builder
+	writeHighlighted(&b, text, tokenize(text), 0, len(text), matcher(terms))
+
+	return b.String()
+}
+
+// Snippet returns at most the given number of words of text around its first
+// word matching terms, highlighted like Highlight does, with an ellipsis where
+// text was cut. It returns "" if no word of text matches.
+func Snippet(text string, terms []string, words int) string {
+	tokens := tokenize(text)
+	match := matcher(terms)
+	first := slices.IndexFunc(tokens, func(t token) bool { return match(t.word) })
+	if first < 0 {
+		return ""
+	}
+
+	// Show a few words before the match for context
+	end := min(max(first-words/4, 0)+words, len(tokens))
+	start := max(end-words, 0)
+
+	from, to := tokens[start].start, tokens[end-1].end
+	if start == 0 {
+		from = 0
+	}
+	if end == len(tokens) {
+		to = len(text)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	writeHighlighted(&b, text, tokens[start:end], from, to, match)
+	if to < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
+
+// writeHighlighted writes text[from:to] escaped as HTML, wrapping the given
+// tokens within it that match in <mark> tags
+func writeHighlighted(b *strings.Builder, text string, tokens []token, from, to int, match func(word string) bool) {
+	written := from
+	for _, t := range tokens {
+		if !match(t.word) {
+			continue
+		}
+
+		b.WriteString(html.EscapeString(text[written:t.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[t.start:t.end]))
+		b.WriteString("</mark>")
+		written = t.end
+	}
+	b.WriteString(html.EscapeString(text[written:to]))
+}
+
+// matcher returns a function reporting whether a lowercase word matches one of
+// terms
+func matcher(terms []string) func(word string) bool {
+	stems := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		stems[term] = true
+	}
+
+	return func(word string) bool {
+		return !stopWords[word] && stems[Stem(word)]
+	}
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+)
+
+// Mail is a plain text email message
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer represents the outgoing email contract
+type Mailer interface {
+	// Send delivers a message, returning once it was handed off
+	Send(ctx context.Context, mail Mail) error
+}
@@ -0,0 +1,15 @@
+// Package service declares the contracts of domain services implemented in the infrastructure layer
+package service
+
+// PasswordHasher represents the password hashing contract
+type PasswordHasher interface {
+	// Hash returns an encoded hash of the password, including its parameters
+	Hash(password string) (string, error)
+
+	// Verify reports whether the password matches the encoded hash
+	Verify(encodedHash, password string) (bool, error)
+
+	// NeedsRehash reports whether the encoded hash was produced with other
+	// parameters than the current ones and should be replaced
+	NeedsRehash(encodedHash string) bool
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"time"
+)
+
+// TokenType distinguishes the purposes a signed token can be issued for
+type TokenType string
+
+const (
+	// TokenTypeAccess authenticates API requests
+	TokenTypeAccess TokenType = "access"
+	// TokenTypeRefresh can only be exchanged for new tokens
+	TokenTypeRefresh TokenType = "refresh"
+)
+
+// TokenClaims holds the verified contents of a token
+type TokenClaims struct {
+	ID        string
+	UserID    uint64
+	Type      TokenType
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+
+	// UserVersion is the token version of the user when the token was issued
+	UserVersion uint64
+}
+
+// TokenManager represents the signed token contract
+type TokenManager interface {
+	// Issue signs a new token of the given type for a user whose token version is userVersion
+	Issue(userID, userVersion uint64, tokenType TokenType) (token string, claims *TokenClaims, err error)
+
+	// Parse verifies a token's signature, expiry and type and returns its claims
+	Parse(token string, tokenType TokenType) (*TokenClaims, error)
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"time"
+)
+
+// TOTP represents the time-based one-time password contract (RFC 6238)
+type TOTP interface {
+	// GenerateSecret returns a new random shared secret, encoded in base32
+	GenerateSecret() (string, error)
+
+	// URI returns the otpauth:// URI authenticator apps enroll a secret from
+	URI(secret, accountName string) string
+
+	// Validate checks a code against the secret at time t and returns the time
+	// step it was generated for, so callers can refuse to accept it twice
+	Validate(secret, code string, t time.Time) (step int64, ok bool)
+}
@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/service"
+)
+
+// Ensure LogMailer and FileMailer implement service.Mailer
+var (
+	_ service.Mailer = (*LogMailer)(nil)
+	_ service.Mailer = (*FileMailer)(nil)
+)
+
+// LogMailer writes messages to a logger instead of sending them, for local development
+type LogMailer struct {
+	logger *log.Logger
+}
+
+// NewLogMailer creates a mailer that writes messages to logger
+func NewLogMailer(logger *log.Logger) *LogMailer {
+	return &LogMailer{
+		logger: logger,
+	}
+}
+
+// Send writes the message to the log
+func (m *LogMailer) Send(_ context.Context, mail service.Mail) error {
+	m.logger.Printf("Mail to %s: %s\n%s", mail.To, mail.Subject, mail.Body)
+	return nil
+}
+
+// FileMailer appends messages to a file instead of sending them, for local
+// development and end-to-end tests that need to read the links they contain
+type FileMailer struct {
+	mu   sync.Mutex
+	path string
+	from string
+}
+
+// NewFileMailer creates a mailer that appends messages from the given sender to the file at path
+func NewFileMailer(path, from string) *FileMailer {
+	return &FileMailer{
+		path: path,
+		from: from,
+	}
+}
+
+// Send appends the message to the file, followed by a blank line
+func (m *FileMailer) Send(_ context.Context, mail service.Mail) error {
+	message, err := format(m.from, mail, time.Now())
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(message, "\r\n\r\n"...)); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
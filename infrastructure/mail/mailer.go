@@ -0,0 +1,58 @@
+// Package mail delivers account emails through SMTP, or to a log or file during development
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/config"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/service"
+)
+
+// Supported mail drivers
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+	DriverSMTP = "smtp"
+)
+
+// New creates the mailer for the driver named in cfg.Driver. The log driver
+// writes messages to logger.
+func New(cfg config.MailConfig, logger *log.Logger) (service.Mailer, error) {
+	switch cfg.Driver {
+	case DriverLog:
+		return NewLogMailer(logger), nil
+	case DriverFile:
+		return NewFileMailer(cfg.File, cfg.From), nil
+	case DriverSMTP:
+		return NewSMTPMailer(cfg), nil
+	default:
+		return nil, fmt.Errorf("unsupported mail driver %q", cfg.Driver)
+	}
+}
+
+// format renders a message in the Internet Message Format (RFC 5322)
+func format(from string, mail service.Mail, date time.Time) ([]byte, error) {
+	// Line breaks in header fields would let a recipient or subject inject headers
+	if strings.ContainsAny(from+mail.To+mail.Subject, "\r\n") {
+		return nil, errors.New("mail header contains a line break")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", mail.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", mail.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(mail.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return buf.Bytes(), nil
+}
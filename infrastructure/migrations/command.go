@@ -0,0 +1,90 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// Usage describes the arguments accepted by Run
+const Usage = `usage: migrate <command>
+
+commands:
+  up               apply all pending migrations
+  down [steps]     roll back the last applied migration, or the given number of them
+  goto <version>   migrate up or down to the given version (0 rolls back everything)
+  status           list migrations and when they were applied`
+
+// Run executes a migrate command with the given arguments and reports progress to out
+func Run(ctx context.Context, m *Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(Usage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		report(out, "applied", applied)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = parsed
+		}
+
+		rolledBack, err := m.Down(ctx, steps)
+		report(out, "rolled back", rolledBack)
+		return err
+	case "goto":
+		if len(args) < 2 {
+			return errors.New("goto requires a version")
+		}
+
+		version, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+
+		changed, err := m.Goto(ctx, version)
+		report(out, "migrated", changed)
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q\n\n%s", args[0], Usage)
+	}
+}
+
+// report prints the migrations affected by a command
+func report(out io.Writer, action string, migrations []Migration) {
+	if len(migrations) == 0 {
+		fmt.Fprintln(out, "no migrations to run")
+		return
+	}
+
+	for _, migration := range migrations {
+		fmt.Fprintf(out, "%s %d_%s\n", action, migration.Version, migration.Name)
+	}
+}
@@ -0,0 +1,38 @@
+package migrations
+
+// dialect holds the driver-specific statements used for bookkeeping
+type dialect struct {
+	createTable string
+	insert      string
+	delete      string
+	lock        string
+	unlock      string
+}
+
+// dialects maps database drivers to their bookkeeping statements. PostgreSQL
+// migrations hold an advisory lock so concurrent replicas cannot race each other;
+// SQLite serializes writers on its own.
+var dialects = map[string]dialect{
+	"postgres": {
+		createTable: `
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version    BIGINT PRIMARY KEY,
+				name       TEXT        NOT NULL,
+				applied_at TIMESTAMPTZ NOT NULL
+			)`,
+		insert: `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+		delete: `DELETE FROM schema_migrations WHERE version = $1`,
+		lock:   `SELECT pg_advisory_lock(7241935001)`,
+		unlock: `SELECT pg_advisory_unlock(7241935001)`,
+	},
+	"sqlite": {
+		createTable: `
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version    INTEGER PRIMARY KEY,
+				name       TEXT     NOT NULL,
+				applied_at DATETIME NOT NULL
+			)`,
+		insert: `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		delete: `DELETE FROM schema_migrations WHERE version = ?`,
+	},
+}
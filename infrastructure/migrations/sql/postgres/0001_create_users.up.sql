@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS users (
+    id         BIGSERIAL PRIMARY KEY,
+    username   TEXT        NOT NULL,
+    email      TEXT        NOT NULL,
+    password   TEXT        NOT NULL,
+    first_name TEXT        NOT NULL DEFAULT '',
+    last_name  TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT users_username_key UNIQUE (username),
+    CONSTRAINT users_email_key UNIQUE (email)
+);
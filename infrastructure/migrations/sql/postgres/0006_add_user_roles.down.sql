@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE users SET admin = TRUE WHERE role = 'admin';
+
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'member'
+    CHECK (role IN ('admin', 'member', 'viewer'));
+
+UPDATE users SET role = 'admin' WHERE admin;
+
+ALTER TABLE users DROP COLUMN admin;
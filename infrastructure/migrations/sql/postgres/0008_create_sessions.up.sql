@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id           VARCHAR(32) PRIMARY KEY,
+    token_hash   VARCHAR(64) NOT NULL UNIQUE,
+    csrf_token   TEXT        NOT NULL,
+    user_id      BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    user_agent   TEXT        NOT NULL DEFAULT '',
+    ip_address   TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
@@ -0,0 +1,5 @@
+ALTER TABLE users
+    DROP COLUMN recovery_codes,
+    DROP COLUMN totp_last_step,
+    DROP COLUMN totp_enabled,
+    DROP COLUMN totp_secret;
@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS tasks_status_idx ON tasks (status) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS tasks_due_date_idx ON tasks (due_date, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS tasks_created_at_idx ON tasks (created_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS tasks_updated_at_idx ON tasks (updated_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS tasks_title_idx ON tasks ((title COLLATE "C"), id) WHERE deleted_at IS NULL;
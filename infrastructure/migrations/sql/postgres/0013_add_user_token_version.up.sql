@@ -0,0 +1,2 @@
+-- Raised whenever every token issued to a user has to stop working
+ALTER TABLE users ADD COLUMN token_version BIGINT NOT NULL DEFAULT 0;
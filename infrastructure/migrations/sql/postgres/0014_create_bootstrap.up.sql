@@ -0,0 +1,10 @@
+-- Holds a single row once the first user signed up and became the administrator
+CREATE TABLE IF NOT EXISTS bootstrap (
+    id         SMALLINT    PRIMARY KEY CHECK (id = 1),
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- Installations that already have users are bootstrapped
+INSERT INTO bootstrap (id, created_at)
+SELECT 1, MIN(created_at) FROM users HAVING COUNT(*) > 0
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,19 @@
+-- Failed login counters, keyed by account or client address, shared by every instance
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key             VARCHAR(320) PRIMARY KEY,
+    failures        INTEGER      NOT NULL CHECK (failures >= 0),
+    last_failure_at TIMESTAMPTZ  NOT NULL,
+    locked_until    TIMESTAMPTZ
+);
+
+-- Entries outlive the users and addresses they concern, so user_id has no foreign key
+CREATE TABLE IF NOT EXISTS audit_log (
+    id         BIGSERIAL PRIMARY KEY,
+    action     VARCHAR(32)  NOT NULL,
+    actor_id   BIGINT,
+    user_id    BIGINT,
+    email      VARCHAR(254) NOT NULL DEFAULT '',
+    ip_address VARCHAR(64)  NOT NULL DEFAULT '',
+    detail     TEXT         NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ  NOT NULL
+);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS tasks_deleted_at_idx;
+DROP INDEX IF EXISTS users_deleted_at_idx;
+
+-- Trashed rows would reappear once the column is gone, so remove them for good
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+DELETE FROM tasks WHERE user_id IN (SELECT id FROM users WHERE deleted_at IS NOT NULL);
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE tasks DROP COLUMN deleted_at;
+ALTER TABLE users DROP COLUMN deleted_at;
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN deleted_at TEXT;
+ALTER TABLE tasks ADD COLUMN deleted_at TEXT;
+
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS tasks_deleted_at_idx ON tasks (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN admin INTEGER NOT NULL DEFAULT 0;
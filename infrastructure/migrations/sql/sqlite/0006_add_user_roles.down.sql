@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN admin INTEGER NOT NULL DEFAULT 0;
+
+UPDATE users SET admin = 1 WHERE role = 'admin';
+
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'member'
+    CHECK (role IN ('admin', 'member', 'viewer'));
+
+UPDATE users SET role = 'admin' WHERE admin <> 0;
+
+ALTER TABLE users DROP COLUMN admin;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         TEXT    NOT NULL,
+    prefix       TEXT    NOT NULL UNIQUE,
+    hash         TEXT    NOT NULL,
+    scopes       TEXT    NOT NULL,
+    expires_at   TEXT,
+    last_used_at TEXT,
+    created_at   TEXT    NOT NULL,
+    revoked_at   TEXT
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id           TEXT    PRIMARY KEY,
+    token_hash   TEXT    NOT NULL UNIQUE,
+    csrf_token   TEXT    NOT NULL,
+    user_id      INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    user_agent   TEXT    NOT NULL DEFAULT '',
+    ip_address   TEXT    NOT NULL DEFAULT '',
+    created_at   TEXT    NOT NULL,
+    last_seen_at TEXT    NOT NULL,
+    expires_at   TEXT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS tasks_title_idx;
+DROP INDEX IF EXISTS tasks_updated_at_idx;
+DROP INDEX IF EXISTS tasks_created_at_idx;
+DROP INDEX IF EXISTS tasks_due_date_idx;
+DROP INDEX IF EXISTS tasks_status_idx;
@@ -0,0 +1,19 @@
+-- Failed login counters, keyed by account or client address, shared by every instance
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key             TEXT    PRIMARY KEY,
+    failures        INTEGER NOT NULL CHECK (failures >= 0),
+    last_failure_at TEXT    NOT NULL,
+    locked_until    TEXT
+);
+
+-- Entries outlive the users and addresses they concern, so user_id has no foreign key
+CREATE TABLE IF NOT EXISTS audit_log (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    action     TEXT    NOT NULL,
+    actor_id   INTEGER,
+    user_id    INTEGER,
+    email      TEXT    NOT NULL DEFAULT '',
+    ip_address TEXT    NOT NULL DEFAULT '',
+    detail     TEXT    NOT NULL DEFAULT '',
+    created_at TEXT    NOT NULL
+);
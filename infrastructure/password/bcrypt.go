@@ -0,0 +1,45 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// validateBcryptCost rejects costs bcrypt does not accept
+func validateBcryptCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("invalid bcrypt cost %d: must be between %d and %d", cost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return nil
+}
+
+// hashBcrypt hashes a password with bcrypt at the given cost
+func hashBcrypt(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// verifyBcrypt reports whether the password matches a bcrypt hash
+func verifyBcrypt(encodedHash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// bcryptCost returns the cost a bcrypt hash was produced with
+func bcryptCost(encodedHash string) (int, error) {
+	return bcrypt.Cost([]byte(encodedHash))
+}
@@ -0,0 +1,121 @@
+// Package password hashes user passwords with argon2id or bcrypt
+package password
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/config"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/service"
+)
+
+// Supported hashing algorithms
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// Ensure Hasher implements service.PasswordHasher
+var _ service.PasswordHasher = (*Hasher)(nil)
+
+// Hasher hashes new passwords with the configured algorithm and verifies
+// hashes produced by any supported algorithm. At most a configured number of
+// passwords are hashed at once, so argon2id cannot use up the memory of the
+// process under a burst of logins.
+type Hasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     argon2Params
+	// slots holds a value for every hash being computed
+	slots chan struct{}
+}
+
+// NewHasher creates a password hasher from the given configuration
+func NewHasher(cfg config.PasswordConfig) (*Hasher, error) {
+	h := &Hasher{
+		algorithm:  cfg.Algorithm,
+		bcryptCost: cfg.BcryptCost,
+		argon2: argon2Params{
+			memory:      cfg.Argon2Memory,
+			iterations:  cfg.Argon2Iterations,
+			parallelism: cfg.Argon2Parallelism,
+		},
+		slots: make(chan struct{}, max(cfg.MaxConcurrentHashes, 1)),
+	}
+
+	switch h.algorithm {
+	case AlgorithmArgon2id:
+		if err := h.argon2.validate(); err != nil {
+			return nil, err
+		}
+	case AlgorithmBcrypt:
+		if err := validateBcryptCost(h.bcryptCost); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", h.algorithm)
+	}
+
+	return h, nil
+}
+
+// Hash returns an encoded hash of the password using the configured algorithm
+func (h *Hasher) Hash(password string) (string, error) {
+	defer h.acquire()()
+
+	if h.algorithm == AlgorithmBcrypt {
+		return hashBcrypt(password, h.bcryptCost)
+	}
+
+	return hashArgon2id(password, h.argon2)
+}
+
+// Verify reports whether the password matches the encoded hash
+func (h *Hasher) Verify(encodedHash, password string) (bool, error) {
+	switch {
+	case isArgon2id(encodedHash):
+		defer h.acquire()()
+		return verifyArgon2id(encodedHash, password)
+	case isBcrypt(encodedHash):
+		defer h.acquire()()
+		return verifyBcrypt(encodedHash, password)
+	default:
+		// Users created before passwords were hashed still hold the plaintext;
+		// NeedsRehash reports them so they are hashed on their next login
+		return subtle.ConstantTimeCompare([]byte(encodedHash), []byte(password)) == 1, nil
+	}
+}
+
+// NeedsRehash reports whether the encoded hash differs from what Hash would produce
+func (h *Hasher) NeedsRehash(encodedHash string) bool {
+	switch {
+	case h.algorithm == AlgorithmArgon2id && isArgon2id(encodedHash):
+		params, _, _, err := decodeArgon2id(encodedHash)
+		return err != nil || params != h.argon2
+	case h.algorithm == AlgorithmBcrypt && isBcrypt(encodedHash):
+		cost, err := bcryptCost(encodedHash)
+		return err != nil || cost != h.bcryptCost
+	default:
+		return true
+	}
+}
+
+// acquire waits until fewer than the maximum number of hashes are being
+// computed and returns the function ending the current one
+func (h *Hasher) acquire() func() {
+	h.slots <- struct{}{}
+	return func() { <-h.slots }
+}
+
+// isArgon2id reports whether the encoded hash was produced by argon2id
+func isArgon2id(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$argon2id$")
+}
+
+// isBcrypt reports whether the encoded hash was produced by bcrypt
+func isBcrypt(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") ||
+		strings.HasPrefix(encodedHash, "$2b$") ||
+		strings.HasPrefix(encodedHash, "$2y$")
+}
@@ -0,0 +1,142 @@
+// Package persistence wires the repository implementation selected by configuration
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/config"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/migrations"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/repository/memory"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/repository/postgres"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/repository/sqlite"
+)
+
+// Repositories holds the repositories backed by the configured driver
+type Repositories struct {
+	Users      repository.UserRepository
+	Tasks      repository.TaskRepository
+	APIKeys    repository.APIKeyRepository
+	Sessions   repository.SessionRepository
+	UserTokens repository.UserTokenRepository
+	Transactor repository.Transactor
+
+	LoginAttempts repository.LoginAttemptRepository
+	Audit         repository.AuditRepository
+
+	db *sql.DB
+}
+
+// New creates the repositories for the driver named in cfg.Driver
+func New(cfg config.DatabaseConfig) (*Repositories, error) {
+	if cfg.Driver == "memory" {
+		tasks := memory.NewTaskRepository()
+		users := memory.NewUserRepository(tasks)
+		apiKeys := memory.NewAPIKeyRepository()
+		sessions := memory.NewSessionRepository()
+		userTokens := memory.NewUserTokenRepository()
+
+		return &Repositories{
+			Users:      users,
+			Tasks:      tasks,
+			APIKeys:    apiKeys,
+			Sessions:   sessions,
+			UserTokens: userTokens,
+			Transactor: memory.NewTransactor(users, tasks, apiKeys, sessions, userTokens),
+
+			LoginAttempts: memory.NewLoginAttemptRepository(),
+			Audit:         memory.NewAuditRepository(),
+		}, nil
+	}
+
+	db, err := OpenDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.AutoMigrate {
+		if err := migrate(db, cfg.Driver); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	repos, err := newSQLRepositories(db, cfg.Driver)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return repos, nil
+}
+
+// newSQLRepositories creates the repositories of a SQL driver backed by db
+func newSQLRepositories(db *sql.DB, driver string) (*Repositories, error) {
+	switch driver {
+	case "postgres":
+		return &Repositories{
+			Users:      postgres.NewUserRepository(db),
+			Tasks:      postgres.NewTaskRepository(db),
+			APIKeys:    postgres.NewAPIKeyRepository(db),
+			Sessions:   postgres.NewSessionRepository(db),
+			UserTokens: postgres.NewUserTokenRepository(db),
+			Transactor: postgres.NewTransactor(db),
+			db:         db,
+
+			LoginAttempts: postgres.NewLoginAttemptRepository(db),
+			Audit:         postgres.NewAuditRepository(db),
+		}, nil
+	case "sqlite":
+		return &Repositories{
+			Users:      sqlite.NewUserRepository(db),
+			Tasks:      sqlite.NewTaskRepository(db),
+			APIKeys:    sqlite.NewAPIKeyRepository(db),
+			Sessions:   sqlite.NewSessionRepository(db),
+			UserTokens: sqlite.NewUserTokenRepository(db),
+			Transactor: sqlite.NewTransactor(db),
+			db:         db,
+
+			LoginAttempts: sqlite.NewLoginAttemptRepository(db),
+			Audit:         sqlite.NewAuditRepository(db),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+}
+
+// OpenDB opens the SQL database for the driver named in cfg.Driver
+func OpenDB(cfg config.DatabaseConfig) (*sql.DB, error) {
+	switch cfg.Driver {
+	case "postgres":
+		return postgres.NewDB(cfg)
+	case "sqlite":
+		return sqlite.NewDB(cfg)
+	default:
+		return nil, fmt.Errorf("database driver %q is not backed by SQL", cfg.Driver)
+	}
+}
+
+// Close releases the underlying database connections, if any
+func (r *Repositories) Close() error {
+	if r.db == nil {
+		return nil
+	}
+
+	return r.db.Close()
+}
+
+// migrate applies all pending schema migrations
+func migrate(db *sql.DB, driver string) error {
+	migrator, err := migrations.New(db, driver)
+	if err != nil {
+		return err
+	}
+
+	if _, err := migrator.Up(context.Background()); err != nil {
+		return fmt.Errorf("migrate database: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,567 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/config"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// postgresDSNEnv names the variable holding the URL of a PostgreSQL database
+// the conformance test may create schemas in; without it PostgreSQL is skipped
+const postgresDSNEnv = "TEST_POSTGRES_DSN"
+
+// drivers open empty repositories of every driver
+var drivers = []struct {
+	name string
+	// env names a variable the driver is skipped without
+	env  string
+	open func(t *testing.T) *Repositories
+}{
+	{name: "memory", open: openMemory},
+	{name: "sqlite", open: openSQLite},
+	{name: "postgres", env: postgresDSNEnv, open: openPostgres},
+}
+
+func openMemory(t *testing.T) *Repositories {
+	repos, err := New(config.DatabaseConfig{Driver: "memory"})
+	if err != nil {
+		t.Fatalf("creating memory repositories: %v", err)
+	}
+
+	return repos
+}
+
+func openSQLite(t *testing.T) *Repositories {
+	repos, err := New(config.DatabaseConfig{
+		Driver:      "sqlite",
+		Name:        filepath.Join(t.TempDir(), "test.db"),
+		AutoMigrate: true,
+	})
+	if err != nil {
+		t.Fatalf("creating sqlite repositories: %v", err)
+	}
+	t.Cleanup(func() { repos.Close() })
+
+	return repos
+}
+
+// openPostgres migrates a schema of its own, dropped after the test, so tests
+// neither see each other's rows nor touch existing tables
+func openPostgres(t *testing.T) *Repositories {
+	dsn := os.Getenv(postgresDSNEnv)
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("opening postgres: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("conformance_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("creating schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+	// Unknown connection parameters are sent to the server as settings
+	scoped, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", postgresDSNEnv, err)
+	}
+	query := scoped.Query()
+	query.Set("search_path", schema)
+	scoped.RawQuery = query.Encode()
+
+	db, err := sql.Open("postgres", scoped.String())
+	if err != nil {
+		t.Fatalf("opening postgres: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := migrate(db, "postgres"); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	repos, err := newSQLRepositories(db, "postgres")
+	if err != nil {
+		t.Fatalf("creating postgres repositories: %v", err)
+	}
+
+	return repos
+}
+
+// conformance lists behavior every driver has to share
+var conformance = []struct {
+	name string
+	run  func(t *testing.T, ctx context.Context, repos *Repositories)
+}{
+	{name: "users", run: testUsers},
+	{name: "user pages", run: testUserPages},
+	{name: "bootstrap", run: testBootstrap},
+	{name: "tasks", run: testTasks},
+	{name: "transactions", run: testTransactions},
+	{name: "sessions", run: testSessions},
+	{name: "api keys", run: testAPIKeys},
+	{name: "login attempts", run: testLoginAttempts},
+	{name: "audit log", run: testAuditLog},
+}
+
+func TestRepositoryConformance(t *testing.T) {
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			if driver.env != "" && os.Getenv(driver.env) == "" {
+				t.Skip(driver.env + " is not set")
+			}
+
+			for _, c := range conformance {
+				t.Run(c.name, func(t *testing.T) {
+					c.run(t, context.Background(), driver.open(t))
+				})
+			}
+		})
+	}
+}
+
+// createUser stores a new user named name
+func createUser(t *testing.T, ctx context.Context, repos *Repositories, name string) *entity.User {
+	t.Helper()
+
+	user := entity.NewUser(name, name+"@x.io", "hash", name, "")
+	if err := repos.Users.Create(ctx, user); err != nil {
+		t.Fatalf("creating user %s: %v", name, err)
+	}
+
+	return user
+}
+
+// checkKind fails the test unless err is a domain error of kind
+func checkKind(t *testing.T, what string, err, kind error) {
+	t.Helper()
+
+	if !errors.Is(err, kind) {
+		t.Errorf("%s error = %v, want %v", what, err, kind)
+	}
+}
+
+func testUsers(t *testing.T, ctx context.Context, repos *Repositories) {
+	alice := createUser(t, ctx, repos, "alice")
+	if alice.ID == 0 || alice.Version != 1 {
+		t.Fatalf("created user has ID %d and version %d, want an ID and version 1", alice.ID, alice.Version)
+	}
+
+	duplicate := entity.NewUser("other", "alice@x.io", "hash", "Other", "")
+	checkKind(t, "creating a user with a taken email", repos.Users.Create(ctx, duplicate), domain.ErrConflict)
+
+	found, err := repos.Users.GetByEmail(ctx, "alice@x.io")
+	if err != nil || found.ID != alice.ID || found.Username != "alice" {
+		t.Fatalf("GetByEmail() = %+v, %v, want alice", found, err)
+	}
+
+	found.FirstName = "Alicia"
+	if err := repos.Users.Update(ctx, found); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if found.Version != 2 {
+		t.Errorf("version after an update = %d, want 2", found.Version)
+	}
+	checkKind(t, "updating a stale user", repos.Users.Update(ctx, alice), repository.ErrVersionConflict)
+
+	checkKind(t, "deleting a stale user", repos.Users.Delete(ctx, alice.ID, alice.Version), repository.ErrVersionConflict)
+	if err := repos.Users.Delete(ctx, alice.ID, found.Version); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	_, err = repos.Users.GetByID(ctx, alice.ID)
+	checkKind(t, "getting a trashed user", err, domain.ErrNotFound)
+	if count, err := repos.Users.CountDeleted(ctx); err != nil || count != 1 {
+		t.Errorf("CountDeleted() = %d, %v, want 1", count, err)
+	}
+
+	if err := repos.Users.Restore(ctx, alice.ID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if restored, err := repos.Users.GetByID(ctx, alice.ID); err != nil || restored.FirstName != "Alicia" {
+		t.Errorf("GetByID() after restoring = %+v, %v, want Alicia", restored, err)
+	}
+	checkKind(t, "deleting a missing user", repos.Users.Delete(ctx, alice.ID+100, 0), domain.ErrNotFound)
+}
+
+func testUserPages(t *testing.T, ctx context.Context, repos *Repositories) {
+	var ids []uint64
+	for i := range 5 {
+		ids = append(ids, createUser(t, ctx, repos, fmt.Sprintf("user%d", i)).ID)
+	}
+
+	tests := []struct {
+		name string
+		page repository.Page
+		want []uint64
+	}{
+		{name: "first page", page: repository.Page{Limit: 2}, want: ids[:2]},
+		{name: "page by offset", page: repository.Page{Limit: 2, Offset: 2}, want: ids[2:4]},
+		{name: "page by cursor", page: repository.Page{Limit: 2, After: cursorOf(ids[1])}, want: ids[2:4]},
+		{name: "last page", page: repository.Page{Limit: 2, After: cursorOf(ids[3])}, want: ids[4:]},
+	}
+
+	for _, tt := range tests {
+		users, err := repos.Users.List(ctx, tt.page)
+		if err != nil {
+			t.Fatalf("%s: List() error = %v", tt.name, err)
+		}
+		var got []uint64
+		for _, user := range users {
+			got = append(got, user.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: List() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if count, err := repos.Users.Count(ctx); err != nil || count != 5 {
+		t.Errorf("Count() = %d, %v, want 5", count, err)
+	}
+}
+
+// cursorOf returns a cursor after id in a list ordered by ID
+func cursorOf(id uint64) *repository.Cursor {
+	cursor := repository.IDCursor(id)
+	return &cursor
+}
+
+func testBootstrap(t *testing.T, ctx context.Context, repos *Repositories) {
+	for i, want := range []bool{true, false, false} {
+		var got bool
+		err := repos.Transactor.WithinTx(ctx, func(ctx context.Context) error {
+			var err error
+			got, err = repos.Users.Bootstrap(ctx)
+			return err
+		})
+		if err != nil || got != want {
+			t.Errorf("Bootstrap() call %d = %v, %v, want %v", i+1, got, err, want)
+		}
+	}
+}
+
+func testTasks(t *testing.T, ctx context.Context, repos *Repositories) {
+	alice := createUser(t, ctx, repos, "alice")
+
+	milk := entity.NewTask("Buy milk", "Two litres", alice.ID, nil)
+	eggs := entity.NewTask("Buy eggs", "", alice.ID, nil)
+	for _, task := range []*entity.Task{milk, eggs} {
+		if err := repos.Tasks.Create(ctx, task); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	matches, err := repos.Tasks.Search(ctx, "milk", repository.TaskFilter{}, repository.Page{Limit: 10})
+	if err != nil || len(matches) != 1 || matches[0].Task.ID != milk.ID {
+		t.Errorf("Search(milk) = %d matches, %v, want the milk task", len(matches), err)
+	}
+
+	checkKind(t, "deleting a user owning tasks", repos.Users.Delete(ctx, alice.ID, 0), repository.ErrUserHasTasks)
+
+	checkKind(t, "deleting a stale task", repos.Tasks.Delete(ctx, milk.ID, milk.Version+1), repository.ErrVersionConflict)
+	if err := repos.Tasks.Delete(ctx, milk.ID, milk.Version); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if count, err := repos.Tasks.CountByUserID(ctx, alice.ID); err != nil || count != 1 {
+		t.Errorf("CountByUserID() after trashing a task = %d, %v, want 1", count, err)
+	}
+	if matches, err := repos.Tasks.Search(ctx, "milk", repository.TaskFilter{}, repository.Page{Limit: 10}); err != nil || len(matches) != 0 {
+		t.Errorf("Search(milk) after trashing it = %d matches, %v, want none", len(matches), err)
+	}
+	checkKind(t, "deleting a trashed task", repos.Tasks.Delete(ctx, milk.ID, 0), domain.ErrNotFound)
+}
+
+func testTransactions(t *testing.T, ctx context.Context, repos *Repositories) {
+	failure := errors.New("failure")
+
+	err := repos.Transactor.WithinTx(ctx, func(ctx context.Context) error {
+		createUser(t, ctx, repos, "rolledback")
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("WithinTx() error = %v, want %v", err, failure)
+	}
+	_, err = repos.Users.GetByEmail(ctx, "rolledback@x.io")
+	checkKind(t, "getting a user created by a rolled back transaction", err, domain.ErrNotFound)
+
+	err = repos.Transactor.WithinTx(ctx, func(ctx context.Context) error {
+		// Nested transactions join the outer one
+		return repos.Transactor.WithinTx(ctx, func(ctx context.Context) error {
+			createUser(t, ctx, repos, "committed")
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() error = %v", err)
+	}
+	if _, err := repos.Users.GetByEmail(ctx, "committed@x.io"); err != nil {
+		t.Errorf("getting a user created by a committed transaction: %v", err)
+	}
+}
+
+func testSessions(t *testing.T, ctx context.Context, repos *Repositories) {
+	alice := createUser(t, ctx, repos, "alice")
+	bob := createUser(t, ctx, repos, "bob")
+
+	for i, userID := range []uint64{alice.ID, alice.ID, alice.ID, bob.ID} {
+		session := entity.NewSession(userID, "test", "192.0.2.1", time.Hour)
+		session.ID = fmt.Sprintf("session%d", i)
+		session.TokenHash = fmt.Sprintf("hash%d", i)
+		if err := repos.Sessions.Create(ctx, session); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	if deleted, err := repos.Sessions.DeleteOthers(ctx, alice.ID, "session1"); err != nil || deleted != 2 {
+		t.Errorf("DeleteOthers() = %d, %v, want 2", deleted, err)
+	}
+	if sessions, err := repos.Sessions.ListByUserID(ctx, alice.ID); err != nil || len(sessions) != 1 || sessions[0].ID != "session1" {
+		t.Errorf("ListByUserID(alice) = %d sessions, %v, want session1 alone", len(sessions), err)
+	}
+	if sessions, err := repos.Sessions.ListByUserID(ctx, bob.ID); err != nil || len(sessions) != 1 {
+		t.Errorf("ListByUserID(bob) = %d sessions, %v, want 1", len(sessions), err)
+	}
+}
+
+func testAPIKeys(t *testing.T, ctx context.Context, repos *Repositories) {
+	alice := createUser(t, ctx, repos, "alice")
+	bob := createUser(t, ctx, repos, "bob")
+
+	var keys []*entity.APIKey
+	for i, userID := range []uint64{alice.ID, alice.ID, alice.ID, bob.ID} {
+		key := entity.NewAPIKey(userID, "ci", []entity.APIKeyScope{entity.APIKeyScopeTasksRead}, nil)
+		key.Prefix = fmt.Sprintf("prefix%d", i)
+		key.Hash = fmt.Sprintf("hash%d", i)
+		if err := repos.APIKeys.Create(ctx, key); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		keys = append(keys, key)
+	}
+
+	earlier := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+	if err := repos.APIKeys.Revoke(ctx, keys[0].ID, earlier); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	if revoked, err := repos.APIKeys.RevokeByUserID(ctx, alice.ID, later); err != nil || revoked != 2 {
+		t.Errorf("RevokeByUserID() = %d, %v, want 2", revoked, err)
+	}
+
+	want := []*time.Time{&earlier, &later, &later, nil}
+	for i, key := range keys {
+		stored, err := repos.APIKeys.GetByID(ctx, key.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if !sameTime(stored.RevokedAt, want[i]) {
+			t.Errorf("key %d revoked at %v, want %v", i, stored.RevokedAt, want[i])
+		}
+	}
+}
+
+func testLoginAttempts(t *testing.T, ctx context.Context, repos *Repositories) {
+	const key = "account:alice@x.io"
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	window := 15 * time.Minute
+	lockedUntil := start.Add(time.Hour)
+
+	// Every step runs on the attempts the previous ones left
+	steps := []struct {
+		name string
+		do   func() (*entity.LoginAttempts, error)
+		// wantFailures and wantLockedUntil describe the attempts afterwards
+		wantFailures    int
+		wantLockedUntil *time.Time
+	}{
+		{
+			name: "no attempts",
+			do:   func() (*entity.LoginAttempts, error) { return repos.LoginAttempts.Get(ctx, key) },
+		},
+		{
+			name:         "first failure",
+			do:           func() (*entity.LoginAttempts, error) { return repos.LoginAttempts.Increment(ctx, key, start, window) },
+			wantFailures: 1,
+		},
+		{
+			name: "failure within the window",
+			do: func() (*entity.LoginAttempts, error) {
+				return repos.LoginAttempts.Increment(ctx, key, start.Add(window-time.Second), window)
+			},
+			wantFailures: 2,
+		},
+		{
+			name: "failure taken back",
+			do: func() (*entity.LoginAttempts, error) {
+				return get(ctx, repos, key, repos.LoginAttempts.Decrement(ctx, key))
+			},
+			wantFailures: 1,
+		},
+		{
+			name: "lock",
+			do: func() (*entity.LoginAttempts, error) {
+				locked, err := repos.LoginAttempts.Lock(ctx, key, start, lockedUntil)
+				if err == nil && !locked {
+					err = errors.New("Lock() did not lock")
+				}
+				return get(ctx, repos, key, err)
+			},
+			wantFailures:    1,
+			wantLockedUntil: &lockedUntil,
+		},
+		{
+			name: "lock while locked",
+			do: func() (*entity.LoginAttempts, error) {
+				locked, err := repos.LoginAttempts.Lock(ctx, key, start.Add(time.Minute), start.Add(2*time.Hour))
+				if err == nil && locked {
+					err = errors.New("Lock() locked twice")
+				}
+				return get(ctx, repos, key, err)
+			},
+			wantFailures:    1,
+			wantLockedUntil: &lockedUntil,
+		},
+		{
+			name: "failure while locked, past the window",
+			do: func() (*entity.LoginAttempts, error) {
+				return repos.LoginAttempts.Increment(ctx, key, start.Add(30*time.Minute), window)
+			},
+			wantFailures:    2,
+			wantLockedUntil: &lockedUntil,
+		},
+		{
+			name: "failure after the lockout",
+			do: func() (*entity.LoginAttempts, error) {
+				return repos.LoginAttempts.Increment(ctx, key, lockedUntil, window)
+			},
+			wantFailures: 1,
+		},
+		{
+			name: "failure after the window",
+			do: func() (*entity.LoginAttempts, error) {
+				return repos.LoginAttempts.Increment(ctx, key, lockedUntil.Add(window), window)
+			},
+			wantFailures: 1,
+		},
+		{
+			name: "purge before the last failure",
+			do: func() (*entity.LoginAttempts, error) {
+				_, err := repos.LoginAttempts.Purge(ctx, lockedUntil)
+				return get(ctx, repos, key, err)
+			},
+			wantFailures: 1,
+		},
+		{
+			name: "purge after the last failure",
+			do: func() (*entity.LoginAttempts, error) {
+				purged, err := repos.LoginAttempts.Purge(ctx, lockedUntil.Add(time.Hour))
+				if err == nil && purged != 1 {
+					err = fmt.Errorf("Purge() = %d, want 1", purged)
+				}
+				return get(ctx, repos, key, err)
+			},
+		},
+		{
+			name: "failure after purging",
+			do: func() (*entity.LoginAttempts, error) {
+				return repos.LoginAttempts.Increment(ctx, key, lockedUntil.Add(2*time.Hour), window)
+			},
+			wantFailures: 1,
+		},
+		{
+			name: "delete",
+			do: func() (*entity.LoginAttempts, error) {
+				return get(ctx, repos, key, repos.LoginAttempts.Delete(ctx, key))
+			},
+		},
+		{
+			name: "failure taken back below zero",
+			do: func() (*entity.LoginAttempts, error) {
+				return get(ctx, repos, key, repos.LoginAttempts.Decrement(ctx, key))
+			},
+		},
+	}
+
+	for _, step := range steps {
+		attempts, err := step.do()
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if attempts.Key != key || attempts.Failures != step.wantFailures || !sameTime(attempts.LockedUntil, step.wantLockedUntil) {
+			t.Errorf("%s: attempts = %d failures locked until %v, want %d locked until %v",
+				step.name, attempts.Failures, attempts.LockedUntil, step.wantFailures, step.wantLockedUntil)
+		}
+	}
+}
+
+// get returns the attempts recorded under key unless err is set
+func get(ctx context.Context, repos *Repositories, key string, err error) (*entity.LoginAttempts, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return repos.LoginAttempts.Get(ctx, key)
+}
+
+func testAuditLog(t *testing.T, ctx context.Context, repos *Repositories) {
+	adminID, userID := uint64(1), uint64(2)
+	created := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var ids []uint64
+	for i, action := range []entity.AuditAction{entity.AuditActionAccountLocked, entity.AuditActionAddressLocked, entity.AuditActionAccountUnlocked} {
+		entry := entity.NewAuditEntry(action)
+		entry.CreatedAt = created.Add(time.Duration(i) * time.Minute)
+		if action == entity.AuditActionAccountUnlocked {
+			entry.ActorID = &adminID
+			entry.UserID = &userID
+			entry.Email = "alice@x.io"
+		}
+		if err := repos.Audit.Create(ctx, entry); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	entries, err := repos.Audit.List(ctx, repository.Page{Limit: 2})
+	if err != nil || len(entries) != 2 || entries[0].ID != ids[2] || entries[1].ID != ids[1] {
+		t.Fatalf("List() = %d entries, %v, want the newest two first", len(entries), err)
+	}
+	newest := entries[0]
+	if newest.Action != entity.AuditActionAccountUnlocked || newest.Email != "alice@x.io" ||
+		newest.ActorID == nil || *newest.ActorID != adminID || newest.UserID == nil || *newest.UserID != userID ||
+		!newest.CreatedAt.Equal(created.Add(2*time.Minute)) {
+		t.Errorf("newest entry = %+v, want the unlock of user 2 by user 1", newest)
+	}
+	if entries[1].ActorID != nil || entries[1].UserID != nil {
+		t.Errorf("entry without actor and user = %+v", entries[1])
+	}
+
+	for _, page := range []repository.Page{{Limit: 2, Offset: 2}, {Limit: 2, After: cursorOf(ids[1])}} {
+		entries, err := repos.Audit.List(ctx, page)
+		if err != nil || len(entries) != 1 || entries[0].ID != ids[0] {
+			t.Errorf("List(%+v) = %d entries, %v, want the oldest alone", page, len(entries), err)
+		}
+	}
+
+	if count, err := repos.Audit.Count(ctx); err != nil || count != 3 {
+		t.Errorf("Count() = %d, %v, want 3", count, err)
+	}
+}
+
+// sameTime reports whether two optional times are both missing or the same instant
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
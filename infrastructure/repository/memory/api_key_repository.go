@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure APIKeyRepository implements repository.APIKeyRepository
+var _ repository.APIKeyRepository = (*APIKeyRepository)(nil)
+
+// APIKeyRepository is an in-memory implementation of repository.APIKeyRepository
+type APIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[uint64]*entity.APIKey
+	// Auto-increment ID
+	lastID uint64
+	journal
+}
+
+// NewAPIKeyRepository creates a new in-memory API key repository
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{
+		keys:   make(map[uint64]*entity.APIKey),
+		lastID: 0,
+	}
+}
+
+// GetByID retrieves an API key by its ID
+func (r *APIKeyRepository) GetByID(_ context.Context, id uint64) (*entity.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, exists := r.keys[id]
+	if !exists {
+		return nil, domain.NewError(domain.ErrNotFound, "api key not found")
+	}
+
+	return copyAPIKey(key), nil
+}
+
+// GetByPrefix retrieves an API key by its prefix
+func (r *APIKeyRepository) GetByPrefix(_ context.Context, prefix string) (*entity.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.Prefix == prefix {
+			return copyAPIKey(key), nil
+		}
+	}
+
+	return nil, domain.NewError(domain.ErrNotFound, "api key not found")
+}
+
+// ListByUserID retrieves every API key owned by a user, newest first
+func (r *APIKeyRepository) ListByUserID(_ context.Context, userID uint64) ([]*entity.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*entity.APIKey, 0)
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			keys = append(keys, copyAPIKey(key))
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID > keys[j].ID
+	})
+
+	return keys, nil
+}
+
+// Create creates a new API key
+func (r *APIKeyRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		for _, existingKey := range r.keys {
+			if existingKey.Prefix == key.Prefix {
+				return nil, domain.NewError(domain.ErrConflict, "api key prefix already exists")
+			}
+		}
+
+		// Assign ID
+		r.lastID++
+		key.ID = r.lastID
+
+		// Store a copy of the key
+		undo := r.undo(saved(r.keys, key.ID))
+		r.keys[key.ID] = copyAPIKey(key)
+
+		return undo, nil
+	})
+}
+
+// Revoke marks an API key as revoked
+func (r *APIKeyRepository) Revoke(ctx context.Context, id uint64, at time.Time) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		key, exists := r.keys[id]
+		if !exists {
+			return nil, domain.NewError(domain.ErrNotFound, "api key not found")
+		}
+		if key.RevokedAt != nil {
+			return nil, nil
+		}
+
+		undo := r.undo(saved(r.keys, id))
+		key.RevokedAt = &at
+
+		return undo, nil
+	})
+}
+
+// RevokeByUserID marks every API key of a user not revoked yet as revoked
+func (r *APIKeyRepository) RevokeByUserID(ctx context.Context, userID uint64, at time.Time) (int64, error) {
+	var revoked int64
+	err := r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		var ids []uint64
+		for id, key := range r.keys {
+			if key.UserID == userID && key.RevokedAt == nil {
+				ids = append(ids, id)
+			}
+		}
+
+		undo := r.undo(saved(r.keys, ids...))
+		for _, id := range ids {
+			revokedAt := at
+			r.keys[id].RevokedAt = &revokedAt
+		}
+		revoked = int64(len(ids))
+
+		return undo, nil
+	})
+
+	return revoked, err
+}
+
+// MarkUsed records the time an API key was last used
+func (r *APIKeyRepository) MarkUsed(ctx context.Context, id uint64, at time.Time) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		key, exists := r.keys[id]
+		if !exists {
+			return nil, domain.NewError(domain.ErrNotFound, "api key not found")
+		}
+
+		undo := r.undo(saved(r.keys, id))
+		key.LastUsedAt = &at
+
+		return undo, nil
+	})
+}
+
+// copyAPIKey returns a copy of key that shares no memory with it
+func copyAPIKey(key *entity.APIKey) *entity.APIKey {
+	copied := *key
+	copied.Scopes = slices.Clone(key.Scopes)
+	return &copied
+}
+
+// undo returns a function putting back the API keys saved before a write
+func (r *APIKeyRepository) undo(before map[uint64]*entity.APIKey) func() {
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		restore(r.keys, before)
+	}
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure AuditRepository implements repository.AuditRepository
+var _ repository.AuditRepository = (*AuditRepository)(nil)
+
+// AuditRepository is an in-memory implementation of repository.AuditRepository
+type AuditRepository struct {
+	mu      sync.RWMutex
+	entries []*entity.AuditEntry
+	lastID  uint64
+}
+
+// NewAuditRepository creates a new in-memory audit repository
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{}
+}
+
+// Create appends an entry to the audit log
+func (r *AuditRepository) Create(_ context.Context, entry *entity.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Assign ID
+	r.lastID++
+	entry.ID = r.lastID
+
+	// Store a copy of the entry
+	stored := *entry
+	r.entries = append(r.entries, &stored)
+
+	return nil
+}
+
+// List retrieves a page of audit entries, newest first
+func (r *AuditRepository) List(_ context.Context, page repository.Page) ([]*entity.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Entries are stored in ID order, so the newest come last
+	entries := make([]*entity.AuditEntry, 0)
+	skip := page.Skip()
+	afterID := page.AfterID()
+	for i := len(r.entries) - 1; i >= 0 && len(entries) < page.Limit; i-- {
+		if afterID != 0 && r.entries[i].ID >= afterID {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+
+		found := *r.entries[i]
+		entries = append(entries, &found)
+	}
+
+	return entries, nil
+}
+
+// Count counts the audit entries
+func (r *AuditRepository) Count(_ context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.entries)), nil
+}
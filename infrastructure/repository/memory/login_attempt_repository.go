@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure LoginAttemptRepository implements repository.LoginAttemptRepository
+var _ repository.LoginAttemptRepository = (*LoginAttemptRepository)(nil)
+
+// LoginAttemptRepository is an in-memory implementation of repository.LoginAttemptRepository
+type LoginAttemptRepository struct {
+	mu       sync.RWMutex
+	attempts map[string]*entity.LoginAttempts
+}
+
+// NewLoginAttemptRepository creates a new in-memory login attempt repository
+func NewLoginAttemptRepository() *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		attempts: make(map[string]*entity.LoginAttempts),
+	}
+}
+
+// Get retrieves the attempts recorded under key, or empty attempts if there are none
+func (r *LoginAttemptRepository) Get(_ context.Context, key string) (*entity.LoginAttempts, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	attempts, exists := r.attempts[key]
+	if !exists {
+		return &entity.LoginAttempts{Key: key}, nil
+	}
+
+	return copyLoginAttempts(attempts), nil
+}
+
+// Increment adds a failure at now to the attempts recorded under key, starting
+// over if they are stale, and returns the result
+func (r *LoginAttemptRepository) Increment(_ context.Context, key string, now time.Time, window time.Duration) (*entity.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts, exists := r.attempts[key]
+	if !exists || attempts.Stale(now, window) {
+		attempts = &entity.LoginAttempts{Key: key}
+		r.attempts[key] = attempts
+	}
+
+	attempts.Failures++
+	attempts.LastFailureAt = now
+
+	return copyLoginAttempts(attempts), nil
+}
+
+// Decrement takes back a failure added by Increment
+func (r *LoginAttemptRepository) Decrement(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if attempts, exists := r.attempts[key]; exists && attempts.Failures > 0 {
+		attempts.Failures--
+	}
+
+	return nil
+}
+
+// Lock locks the attempts recorded under key out until until unless they are
+// locked at now already, and reports whether it did
+func (r *LoginAttemptRepository) Lock(_ context.Context, key string, now, until time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts, exists := r.attempts[key]
+	if !exists || attempts.Locked(now) {
+		return false, nil
+	}
+
+	attempts.LockedUntil = &until
+
+	return true, nil
+}
+
+// Delete forgets the attempts recorded under key
+func (r *LoginAttemptRepository) Delete(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+
+	return nil
+}
+
+// Purge removes attempts whose last failure was before cutoff and that are not locked past it
+func (r *LoginAttemptRepository) Purge(_ context.Context, cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for key, attempts := range r.attempts {
+		if attempts.LastFailureAt.Before(cutoff) && !attempts.Locked(cutoff) {
+			delete(r.attempts, key)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+// copyLoginAttempts returns a copy of attempts that shares no memory with it
+func copyLoginAttempts(attempts *entity.LoginAttempts) *entity.LoginAttempts {
+	copied := *attempts
+	if attempts.LockedUntil != nil {
+		lockedUntil := *attempts.LockedUntil
+		copied.LockedUntil = &lockedUntil
+	}
+
+	return &copied
+}
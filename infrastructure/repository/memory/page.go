@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// paginate sorts records with compare and returns the page selected by page.
+// Records before the page's cursor must already have been left out.
+func paginate[T any](records []T, page repository.Page, compare func(a, b T) int) []T {
+	slices.SortFunc(records, compare)
+
+	offset := page.Skip()
+	if offset >= len(records) {
+		return records[:0]
+	}
+
+	end := min(offset+page.Limit, len(records))
+	return records[offset:end]
+}
+
+// compareDeleted orders records most recently deleted first and then by ID,
+// like the SQL repositories order the trash
+func compareDeleted(aDeletedAt time.Time, aID uint64, bDeletedAt time.Time, bID uint64) int {
+	if c := bDeletedAt.Compare(aDeletedAt); c != 0 {
+		return c
+	}
+
+	return cmp.Compare(aID, bID)
+}
+
+// deletedAfter reports whether a record comes after the cursor of a page of
+// the trash, given as the deletion time and ID returned by Page.AfterTime
+func deletedAfter(deletedAt time.Time, id uint64, after *time.Time, afterID uint64) bool {
+	return after == nil || compareDeleted(deletedAt, id, *after, afterID) > 0
+}
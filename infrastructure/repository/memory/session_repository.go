@@ -0,0 +1,175 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure SessionRepository implements repository.SessionRepository
+var _ repository.SessionRepository = (*SessionRepository)(nil)
+
+// SessionRepository is an in-memory implementation of repository.SessionRepository
+type SessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[string]*entity.Session
+	journal
+}
+
+// NewSessionRepository creates a new in-memory session repository
+func NewSessionRepository() *SessionRepository {
+	return &SessionRepository{
+		sessions: make(map[string]*entity.Session),
+	}
+}
+
+// GetByTokenHash retrieves a session by the hash of its cookie token
+func (r *SessionRepository) GetByTokenHash(_ context.Context, tokenHash string) (*entity.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, session := range r.sessions {
+		if session.TokenHash == tokenHash {
+			found := *session
+			return &found, nil
+		}
+	}
+
+	return nil, domain.NewError(domain.ErrNotFound, "session not found")
+}
+
+// ListByUserID retrieves every stored session of a user, most recently used first
+func (r *SessionRepository) ListByUserID(_ context.Context, userID uint64) ([]*entity.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sessions := make([]*entity.Session, 0)
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			found := *session
+			sessions = append(sessions, &found)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
+// Create creates a new session
+func (r *SessionRepository) Create(ctx context.Context, session *entity.Session) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if _, exists := r.sessions[session.ID]; exists {
+			return nil, domain.NewError(domain.ErrConflict, "session already exists")
+		}
+
+		// Store a copy of the session
+		undo := r.undo(saved(r.sessions, session.ID))
+		stored := *session
+		r.sessions[session.ID] = &stored
+
+		return undo, nil
+	})
+}
+
+// Touch records the time a session was last used
+func (r *SessionRepository) Touch(ctx context.Context, id string, lastSeenAt time.Time) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		session, exists := r.sessions[id]
+		if !exists {
+			return nil, domain.NewError(domain.ErrNotFound, "session not found")
+		}
+
+		undo := r.undo(saved(r.sessions, id))
+		session.LastSeenAt = lastSeenAt
+
+		return undo, nil
+	})
+}
+
+// Delete removes a session
+func (r *SessionRepository) Delete(ctx context.Context, id string) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if _, exists := r.sessions[id]; !exists {
+			return nil, domain.NewError(domain.ErrNotFound, "session not found")
+		}
+
+		undo := r.undo(saved(r.sessions, id))
+		delete(r.sessions, id)
+
+		return undo, nil
+	})
+}
+
+// DeleteByUserID removes every session of a user
+func (r *SessionRepository) DeleteByUserID(ctx context.Context, userID uint64) (int64, error) {
+	return r.deleteWhere(ctx, func(session *entity.Session) bool {
+		return session.UserID == userID
+	})
+}
+
+// DeleteOthers removes every session of a user but one
+func (r *SessionRepository) DeleteOthers(ctx context.Context, userID uint64, keepID string) (int64, error) {
+	return r.deleteWhere(ctx, func(session *entity.Session) bool {
+		return session.UserID == userID && session.ID != keepID
+	})
+}
+
+// Purge removes sessions that expired before now or were last used before idleSince
+func (r *SessionRepository) Purge(ctx context.Context, now, idleSince time.Time) (int64, error) {
+	return r.deleteWhere(ctx, func(session *entity.Session) bool {
+		return session.ExpiresAt.Before(now) || session.LastSeenAt.Before(idleSince)
+	})
+}
+
+// deleteWhere removes the sessions matching match and returns how many there were
+func (r *SessionRepository) deleteWhere(ctx context.Context, match func(session *entity.Session) bool) (int64, error) {
+	var deleted int64
+	err := r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		var ids []string
+		for id, session := range r.sessions {
+			if match(session) {
+				ids = append(ids, id)
+			}
+		}
+
+		undo := r.undo(saved(r.sessions, ids...))
+		for _, id := range ids {
+			delete(r.sessions, id)
+		}
+		deleted = int64(len(ids))
+
+		return undo, nil
+	})
+
+	return deleted, err
+}
+
+// undo returns a function putting back the sessions saved before a write
+func (r *SessionRepository) undo(before map[string]*entity.Session) func() {
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		restore(r.sessions, before)
+	}
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"math"
+	"slices"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/search"
+)
+
+// taskIndex is an inverted index of the search terms in the titles and
+// descriptions of the tasks outside the trash
+type taskIndex struct {
+	// postings maps each term to the tasks containing it
+	postings map[string]map[uint64]termCount
+	// terms maps each indexed task to its distinct terms, so it can be removed
+	terms map[uint64][]string
+}
+
+// termCount counts the occurrences of a term in a task
+type termCount struct {
+	title       int
+	description int
+}
+
+// newTaskIndex creates an empty task index
+func newTaskIndex() *taskIndex {
+	return &taskIndex{
+		postings: make(map[string]map[uint64]termCount),
+		terms:    make(map[uint64][]string),
+	}
+}
+
+// add indexes a task, replacing any previous version of it
+func (x *taskIndex) add(task *entity.Task) {
+	x.remove(task.ID)
+
+	counts := make(map[string]termCount)
+	for _, term := range search.Terms(task.Title) {
+		count := counts[term]
+		count.title++
+		counts[term] = count
+	}
+	for _, term := range search.Terms(task.Description) {
+		count := counts[term]
+		count.description++
+		counts[term] = count
+	}
+
+	terms := make([]string, 0, len(counts))
+	for term, count := range counts {
+		if x.postings[term] == nil {
+			x.postings[term] = make(map[uint64]termCount)
+		}
+		x.postings[term][task.ID] = count
+		terms = append(terms, term)
+	}
+	x.terms[task.ID] = terms
+}
+
+// remove drops a task from the index
+func (x *taskIndex) remove(id uint64) {
+	for _, term := range x.terms[id] {
+		delete(x.postings[term], id)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+		}
+	}
+	delete(x.terms, id)
+}
+
+// search returns the rank of every task containing all of terms. A task ranks
+// higher the more often it contains the terms, counting its title twice, and
+// the rarer the terms are among all tasks.
+func (x *taskIndex) search(terms []string) map[uint64]float64 {
+	terms = slices.Compact(slices.Sorted(slices.Values(terms)))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var ranks map[uint64]float64
+	for _, term := range terms {
+		postings := x.postings[term]
+		if len(postings) == 0 {
+			return nil
+		}
+
+		// Inverse document frequency of the term
+		idf := math.Log(1 + float64(len(x.terms))/float64(len(postings)))
+
+		next := make(map[uint64]float64, len(postings))
+		for id, count := range postings {
+			rank, found := ranks[id]
+			if ranks != nil && !found {
+				continue
+			}
+			next[id] = rank + float64(2*count.title+count.description)*idf
+		}
+		ranks = next
+	}
+
+	return ranks
+}
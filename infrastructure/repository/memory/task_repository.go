@@ -1,12 +1,17 @@
 package memory
 
 import (
+	"cmp"
 	"context"
-	"errors"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
 	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
 	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/search"
 )
 
 // Ensure TaskRepository implements repository.TaskRepository
@@ -16,14 +21,18 @@ var _ repository.TaskRepository = (*TaskRepository)(nil)
 type TaskRepository struct {
 	mu    sync.RWMutex
 	tasks map[uint64]*entity.Task
+	// Search index of the tasks outside the trash
+	index *taskIndex
 	// Auto-increment ID
 	lastID uint64
+	journal
 }
 
 // NewTaskRepository creates a new in-memory task repository
 func NewTaskRepository() *TaskRepository {
 	return &TaskRepository{
 		tasks:  make(map[uint64]*entity.Task),
+		index:  newTaskIndex(),
 		lastID: 0,
 	}
 }
@@ -34,103 +43,461 @@ func (r *TaskRepository) GetByID(_ context.Context, id uint64) (*entity.Task, er
 	defer r.mu.RUnlock()
 
 	task, exists := r.tasks[id]
-	if !exists {
-		return nil, errors.New("task not found")
+	if !exists || task.DeletedAt != nil {
+		return nil, domain.NewError(domain.ErrNotFound, "task not found")
 	}
 
-	return task, nil
+	// Return a copy so callers cannot modify the stored task without Update
+	found := *task
+	return &found, nil
 }
 
-// GetByUserID retrieves tasks by user ID
-func (r *TaskRepository) GetByUserID(_ context.Context, userID uint64, limit, offset int) ([]*entity.Task, error) {
+// CountByUserID counts the tasks owned by a user, excluding trashed ones
+func (r *TaskRepository) CountByUserID(_ context.Context, userID uint64) (int64, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	// Filter tasks by user ID
-	userTasks := make([]*entity.Task, 0)
+	var count int64
 	for _, task := range r.tasks {
-		if task.UserID == userID {
-			userTasks = append(userTasks, task)
+		if task.DeletedAt == nil && task.UserID == userID {
+			count++
 		}
 	}
 
-	// Apply pagination
-	if offset >= len(userTasks) {
-		return []*entity.Task{}, nil
-	}
+	return count, nil
+}
 
-	end := offset + limit
-	if end > len(userTasks) {
-		end = len(userTasks)
-	}
+// DeleteByUserID moves every task owned by a user to the trash
+func (r *TaskRepository) DeleteByUserID(ctx context.Context, userID uint64) (int64, error) {
+	var deleted int64
+	err := r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		ids := r.ids(func(task *entity.Task) bool {
+			return task.DeletedAt == nil && task.UserID == userID
+		})
+		undo := r.undo(saved(r.tasks, ids...))
 
-	return userTasks[offset:end], nil
+		now := time.Now()
+		for _, id := range ids {
+			deletedAt := now
+			r.tasks[id].DeletedAt = &deletedAt
+			r.index.remove(id)
+		}
+		deleted = int64(len(ids))
+
+		return undo, nil
+	})
+
+	return deleted, err
+}
+
+// ReassignUser transfers every task owned by a user, including trashed ones, to another user
+func (r *TaskRepository) ReassignUser(ctx context.Context, fromUserID, toUserID uint64) (int64, error) {
+	var reassigned int64
+	err := r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		ids := r.ids(func(task *entity.Task) bool {
+			return task.UserID == fromUserID
+		})
+		undo := r.undo(saved(r.tasks, ids...))
+
+		now := time.Now()
+		for _, id := range ids {
+			task := r.tasks[id]
+			task.UserID = toUserID
+			task.UpdatedAt = now
+			task.Version++
+		}
+		reassigned = int64(len(ids))
+
+		return undo, nil
+	})
+
+	return reassigned, err
 }
 
 // Create creates a new task
-func (r *TaskRepository) Create(_ context.Context, task *entity.Task) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func (r *TaskRepository) Create(ctx context.Context, task *entity.Task) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
 
-	// Assign ID
-	r.lastID++
-	task.ID = r.lastID
+		// Assign ID and initial version
+		r.lastID++
+		task.ID = r.lastID
+		task.Version = 1
 
-	// Store task
-	r.tasks[task.ID] = task
+		// Store a copy of the task
+		undo := r.undo(saved(r.tasks, task.ID))
+		stored := *task
+		r.tasks[task.ID] = &stored
+		r.index.add(&stored)
 
-	return nil
+		return undo, nil
+	})
 }
 
 // Update updates an existing task
-func (r *TaskRepository) Update(_ context.Context, task *entity.Task) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func (r *TaskRepository) Update(ctx context.Context, task *entity.Task) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		existing, exists := r.tasks[task.ID]
+		if !exists || existing.DeletedAt != nil {
+			return nil, domain.NewError(domain.ErrNotFound, "task not found")
+		}
+
+		// Reject writes based on a stale version
+		if existing.Version != task.Version {
+			return nil, repository.ErrVersionConflict
+		}
+		task.Version++
+
+		// Update task
+		undo := r.undo(saved(r.tasks, task.ID))
+		stored := *task
+		r.tasks[task.ID] = &stored
+		r.index.add(&stored)
+
+		return undo, nil
+	})
+}
+
+// Delete moves a task to the trash
+func (r *TaskRepository) Delete(ctx context.Context, id uint64, version uint64) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		task, exists := r.tasks[id]
+		if !exists || task.DeletedAt != nil {
+			return nil, domain.NewError(domain.ErrNotFound, "task not found")
+		}
+
+		if version != 0 && task.Version != version {
+			return nil, repository.ErrVersionConflict
+		}
+
+		undo := r.undo(saved(r.tasks, id))
+		now := time.Now()
+		task.DeletedAt = &now
+		r.index.remove(id)
+
+		return undo, nil
+	})
+}
+
+// List retrieves a page of the tasks matching filter, ordered by sort
+func (r *TaskRepository) List(_ context.Context, filter repository.TaskFilter, sort repository.TaskSort, page repository.Page) ([]*entity.Task, error) {
+	compare := compareTasks(sort)
+
+	var after *entity.Task
+	if page.After != nil {
+		var err error
+		after, err = cursorTask(sort, *page.After)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r.filter(page, func(task *entity.Task) bool {
+		return task.DeletedAt == nil && matchesTask(filter, task) && (after == nil || compare(task, after) > 0)
+	}, compare), nil
+}
+
+// Count counts the tasks outside the trash matching filter
+func (r *TaskRepository) Count(_ context.Context, filter repository.TaskFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, task := range r.tasks {
+		if task.DeletedAt == nil && matchesTask(filter, task) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Search retrieves a page of the tasks matching filter that contain every
+// search term of query, most relevant first
+func (r *TaskRepository) Search(_ context.Context, query string, filter repository.TaskFilter, page repository.Page) ([]*repository.TaskMatch, error) {
+	var after *repository.TaskMatch
+	if page.After != nil {
+		rank, err := page.After.Rank()
+		if err != nil {
+			return nil, err
+		}
+		after = &repository.TaskMatch{Task: &entity.Task{ID: page.After.ID}, Rank: rank}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := make([]*repository.TaskMatch, 0)
+	for id, rank := range r.index.search(search.Terms(query)) {
+		match := &repository.TaskMatch{Task: r.tasks[id], Rank: rank}
+		if !matchesTask(filter, match.Task) || (after != nil && compareMatches(match, after) <= 0) {
+			continue
+		}
+
+		found := *match.Task
+		match.Task = &found
+		matches = append(matches, match)
+	}
+
+	return paginate(matches, page, compareMatches), nil
+}
+
+// CountSearch counts the tasks Search finds for query and filter
+func (r *TaskRepository) CountSearch(_ context.Context, query string, filter repository.TaskFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for id := range r.index.search(search.Terms(query)) {
+		if matchesTask(filter, r.tasks[id]) {
+			count++
+		}
+	}
+
+	return count, nil
+}
 
-	if _, exists := r.tasks[task.ID]; !exists {
-		return errors.New("task not found")
+// ListDeleted retrieves a page of the tasks in the trash, most recently deleted first
+func (r *TaskRepository) ListDeleted(_ context.Context, page repository.Page) ([]*entity.Task, error) {
+	after, afterID, err := page.AfterTime()
+	if err != nil {
+		return nil, err
 	}
 
-	// Update task
-	r.tasks[task.ID] = task
+	return r.filter(page, func(task *entity.Task) bool {
+		return task.DeletedAt != nil && deletedAfter(*task.DeletedAt, task.ID, after, afterID)
+	}, compareDeletedTasks), nil
+}
 
-	return nil
+// CountDeleted counts the tasks in the trash
+func (r *TaskRepository) CountDeleted(_ context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, task := range r.tasks {
+		if task.DeletedAt != nil {
+			count++
+		}
+	}
+
+	return count, nil
 }
 
-// Delete deletes a task by its ID
-func (r *TaskRepository) Delete(_ context.Context, id uint64) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// Restore moves a task out of the trash
+func (r *TaskRepository) Restore(ctx context.Context, id uint64) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		task, exists := r.tasks[id]
+		if !exists || task.DeletedAt == nil {
+			return nil, domain.NewError(domain.ErrNotFound, "task not found in trash")
+		}
+
+		undo := r.undo(saved(r.tasks, id))
+		task.DeletedAt = nil
+		r.index.add(task)
+
+		return undo, nil
+	})
+}
+
+// Purge permanently removes tasks moved to the trash before the given time
+func (r *TaskRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		ids := r.ids(func(task *entity.Task) bool {
+			return task.DeletedAt != nil && task.DeletedAt.Before(before)
+		})
+		undo := r.undo(saved(r.tasks, ids...))
+
+		for _, id := range ids {
+			delete(r.tasks, id)
+		}
+		purged = int64(len(ids))
 
-	if _, exists := r.tasks[id]; !exists {
-		return errors.New("task not found")
+		return undo, nil
+	})
+
+	return purged, err
+}
+
+// ids returns the IDs of the tasks matching keep. The caller must hold r.mu.
+func (r *TaskRepository) ids(keep func(task *entity.Task) bool) []uint64 {
+	var ids []uint64
+	for id, task := range r.tasks {
+		if keep(task) {
+			ids = append(ids, id)
+		}
 	}
 
-	delete(r.tasks, id)
+	return ids
+}
+
+// owns reports whether a user owns any task, optionally counting trashed ones
+func (r *TaskRepository) owns(userID uint64, includeDeleted bool) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, task := range r.tasks {
+		if task.UserID == userID && (includeDeleted || task.DeletedAt == nil) {
+			return true
+		}
+	}
 
-	return nil
+	return false
 }
 
-// List retrieves a list of tasks with pagination
-func (r *TaskRepository) List(_ context.Context, limit, offset int) ([]*entity.Task, error) {
+// filter returns copies of the tasks matching keep, sorted by compare, with
+// pagination applied
+func (r *TaskRepository) filter(page repository.Page, keep func(task *entity.Task) bool, compare func(a, b *entity.Task) int) []*entity.Task {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	// Convert map to slice
-	tasks := make([]*entity.Task, 0, len(r.tasks))
+	tasks := make([]*entity.Task, 0)
 	for _, task := range r.tasks {
-		tasks = append(tasks, task)
+		if keep(task) {
+			found := *task
+			tasks = append(tasks, &found)
+		}
 	}
 
-	// Apply pagination
-	if offset >= len(tasks) {
-		return []*entity.Task{}, nil
+	return paginate(tasks, page, compare)
+}
+
+// matchesTask reports whether filter selects task
+func matchesTask(filter repository.TaskFilter, task *entity.Task) bool {
+	if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, task.Status) {
+		return false
+	}
+	if filter.UserID != 0 && task.UserID != filter.UserID {
+		return false
 	}
+	if !inRange(task.DueDate, filter.DueAfter, filter.DueBefore) || !inRange(&task.CreatedAt, filter.CreatedAfter, filter.CreatedBefore) {
+		return false
+	}
+
+	return filter.TitleContains == "" || strings.Contains(strings.ToLower(task.Title), strings.ToLower(filter.TitleContains))
+}
+
+// inRange reports whether t lies between after, inclusive, and before,
+// exclusive. A missing t lies in no bounded range.
+func inRange(t, after, before *time.Time) bool {
+	if after == nil && before == nil {
+		return true
+	}
+	if t == nil {
+		return false
+	}
+
+	return (after == nil || !t.Before(*after)) && (before == nil || t.Before(*before))
+}
+
+// compareTasks returns a function ordering tasks by sort and then by ID, like
+// the SQL repositories do
+func compareTasks(sort repository.TaskSort) func(a, b *entity.Task) int {
+	return func(a, b *entity.Task) int {
+		var c int
+		switch sort.Field {
+		case repository.TaskSortCreatedAt:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		case repository.TaskSortUpdatedAt:
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		case repository.TaskSortDueDate:
+			// Tasks without a due date come last in either direction
+			switch {
+			case a.DueDate == nil && b.DueDate == nil:
+			case a.DueDate == nil:
+				return 1
+			case b.DueDate == nil:
+				return -1
+			default:
+				c = a.DueDate.Compare(*b.DueDate)
+			}
+		case repository.TaskSortTitle:
+			c = strings.Compare(a.Title, b.Title)
+		}
+
+		if sort.Descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.ID, b.ID)
+	}
+}
 
-	end := offset + limit
-	if end > len(tasks) {
-		end = len(tasks)
+// compareMatches orders search results most relevant first and then by ID
+func compareMatches(a, b *repository.TaskMatch) int {
+	if c := cmp.Compare(b.Rank, a.Rank); c != 0 {
+		return c
 	}
 
-	return tasks[offset:end], nil
+	return cmp.Compare(a.Task.ID, b.Task.ID)
+}
+
+// cursorTask returns a task holding the sort key and ID of a cursor, so the
+// tasks after it can be found with compareTasks
+func cursorTask(sort repository.TaskSort, cursor repository.Cursor) (*entity.Task, error) {
+	task := &entity.Task{ID: cursor.ID}
+
+	switch sort.Field {
+	case repository.TaskSortCreatedAt, repository.TaskSortUpdatedAt, repository.TaskSortDueDate:
+		key, err := cursor.Time()
+		if err != nil {
+			return nil, err
+		}
+
+		// Only the field sorted by is compared
+		task.DueDate = key
+		if key != nil {
+			task.CreatedAt = *key
+			task.UpdatedAt = *key
+		}
+	case repository.TaskSortTitle:
+		task.Title = cursor.Key
+	}
+
+	return task, nil
+}
+
+// compareDeletedTasks orders tasks most recently deleted first
+func compareDeletedTasks(a, b *entity.Task) int {
+	return compareDeleted(*a.DeletedAt, a.ID, *b.DeletedAt, b.ID)
+}
+
+// undo returns a function putting back the tasks saved before a write,
+// together with their search index entries
+func (r *TaskRepository) undo(before map[uint64]*entity.Task) func() {
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		restore(r.tasks, before)
+		for id, task := range before {
+			r.index.remove(id)
+			if task != nil && task.DeletedAt == nil {
+				r.index.add(task)
+			}
+		}
+	}
 }
@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure Transactor implements repository.Transactor
+var _ repository.Transactor = (*Transactor)(nil)
+
+// txKey is the context key holding the undo log of the transaction in progress
+type txKey struct{}
+
+// txLog records how to undo the writes of a transaction
+type txLog struct {
+	transactor *Transactor
+	undo       []func()
+}
+
+// participant is implemented by the in-memory repositories taking part in transactions
+type participant interface {
+	join(t *Transactor)
+}
+
+// Transactor is an in-memory implementation of repository.Transactor.
+// Transactions are serialized with each other and with every write to the
+// participating repositories made outside of them. On failure, the writes of
+// the transaction are undone in reverse order, which leaves everything written
+// before it began as it was. IDs handed out are not taken back, like the
+// sequences of a SQL database.
+type Transactor struct {
+	mu sync.Mutex
+}
+
+// NewTransactor creates a new in-memory transactor spanning the given repositories
+func NewTransactor(repositories ...participant) *Transactor {
+	t := &Transactor{}
+	for _, repo := range repositories {
+		repo.join(t)
+	}
+
+	return t
+}
+
+// WithinTx runs fn while holding the transaction lock, rolling back on error
+func (t *Transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	// Join the transaction already in progress
+	if log, ok := ctx.Value(txKey{}).(*txLog); ok && log.transactor == t {
+		return fn(ctx)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	log := &txLog{transactor: t}
+	if err := fn(context.WithValue(ctx, txKey{}, log)); err != nil {
+		for _, undo := range slices.Backward(log.undo) {
+			undo()
+		}
+		return err
+	}
+
+	return nil
+}
+
+// journal lets a repository take part in the transactions of a Transactor
+type journal struct {
+	transactor *Transactor
+}
+
+// join makes the repository take part in the transactions of t
+func (j *journal) join(t *Transactor) {
+	j.transactor = t
+}
+
+// write runs change, which applies a write and returns a function undoing it,
+// or nil if it changed nothing. Within a transaction the undo function is
+// logged for rollback. Outside of one, change runs under the transaction lock,
+// so that no transaction in progress can be rolled back over its write.
+func (j *journal) write(ctx context.Context, change func() (undo func(), err error)) error {
+	if j.transactor == nil {
+		_, err := change()
+		return err
+	}
+
+	if log, ok := ctx.Value(txKey{}).(*txLog); ok && log.transactor == j.transactor {
+		undo, err := change()
+		if undo != nil {
+			log.undo = append(log.undo, undo)
+		}
+		return err
+	}
+
+	j.transactor.mu.Lock()
+	defer j.transactor.mu.Unlock()
+
+	_, err := change()
+	return err
+}
+
+// saved returns copies of the records stored under keys, with nil for the keys
+// that have none, for restore to put back
+func saved[K comparable, T any](records map[K]*T, keys ...K) map[K]*T {
+	before := make(map[K]*T, len(keys))
+	for _, key := range keys {
+		if record, exists := records[key]; exists {
+			copied := *record
+			before[key] = &copied
+		} else {
+			before[key] = nil
+		}
+	}
+
+	return before
+}
+
+// restore puts the records returned by saved back into records
+func restore[K comparable, T any](records map[K]*T, before map[K]*T) {
+	for key, record := range before {
+		if record == nil {
+			delete(records, key)
+		} else {
+			records[key] = record
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+var errRollback = errors.New("rollback")
+
+// testStore holds the repositories taking part in the transactions of a transactor
+type testStore struct {
+	users      *UserRepository
+	tasks      *TaskRepository
+	apiKeys    *APIKeyRepository
+	sessions   *SessionRepository
+	userTokens *UserTokenRepository
+	transactor *Transactor
+}
+
+func newTestStore(t *testing.T) *testStore {
+	t.Helper()
+
+	s := &testStore{
+		tasks:      NewTaskRepository(),
+		apiKeys:    NewAPIKeyRepository(),
+		sessions:   NewSessionRepository(),
+		userTokens: NewUserTokenRepository(),
+	}
+	s.users = NewUserRepository(s.tasks)
+	s.transactor = NewTransactor(s.users, s.tasks, s.apiKeys, s.sessions, s.userTokens)
+
+	return s
+}
+
+func (s *testStore) createUser(t *testing.T, ctx context.Context, name string) *entity.User {
+	t.Helper()
+
+	user := entity.NewUser(name, name+"@x.io", "hash", name, "")
+	if err := s.users.Create(ctx, user); err != nil {
+		t.Fatalf("creating user %s: %v", name, err)
+	}
+
+	return user
+}
+
+func (s *testStore) createTask(t *testing.T, ctx context.Context, userID uint64, title string) *entity.Task {
+	t.Helper()
+
+	task := entity.NewTask(title, "", userID, nil)
+	if err := s.tasks.Create(ctx, task); err != nil {
+		t.Fatalf("creating task %s: %v", title, err)
+	}
+
+	return task
+}
+
+func TestTransactorRollback(t *testing.T) {
+	tests := []struct {
+		name string
+		// write runs within the transaction that is rolled back
+		write func(t *testing.T, ctx context.Context, s *testStore, user *entity.User, task *entity.Task)
+		// check runs after the rollback
+		check func(t *testing.T, ctx context.Context, s *testStore, user *entity.User, task *entity.Task)
+	}{
+		{
+			name: "created records are removed",
+			write: func(t *testing.T, ctx context.Context, s *testStore, user *entity.User, task *entity.Task) {
+				s.createUser(t, ctx, "bob")
+				s.createTask(t, ctx, user.ID, "Second")
+			},
+			check: func(t *testing.T, ctx context.Context, s *testStore, user *entity.User, task *entity.Task) {
+				if _, err := s.users.GetByEmail(ctx, "bob@x.io"); err == nil {
+					t.Error("user created in the transaction survived the rollback")
+				}
+				if count, _ := s.tasks.Count(ctx, repository.TaskFilter{}); count != 1 {
+					t.Errorf("%d tasks after the rollback, want 1", count)
+				}
+			},
+		},
+		{
+			name: "updates are undone together with the search index",
+			write: func(t *testing.T, ctx context.Context, s *testStore, user *entity.User, task *entity.Task) {
+				user.FirstName = "Changed"
+				if err := s.users.Update(ctx, user); err != nil {
+					t.Fatalf("updating user: %v", err)
+				}
+				task.Title = "Renamed"
+				if err := s.tasks.Update(ctx, task); err != nil {
+					t.Fatalf("updating task: %v", err)
+				}
+			},
+			check: func(t *testing.T, ctx context.Context, s *testStore, user *entity.User, task *entity.Task) {
+				if found, _ := s.users.GetByID(ctx, user.ID); found.FirstName != "alice" || found.Version != 1 {
+					t.Errorf("user is %q at version %d, want %q at version 1", found.FirstName, found.Version, "alice")
+				}
+				if matches, _ := s.tasks.Search(ctx, "first", repository.TaskFilter{}, repository.Page{Limit: 10}); len(matches) != 1 {
+					t.Errorf("search for the original title found %d tasks, want 1", len(matches))
+				}
+				if matches, _ := s.tasks.Search(ctx, "renamed", repository.TaskFilter{}, repository.Page{Limit: 10}); len(matches) != 0 {
+					t.Errorf("search for the rolled back title found %d tasks, want 0", len(matches))
+				}
+			},
+		},
+		{
+			name: "deleted and purged records come back",
+			write: func(t *testing.T, ctx context.Context, s *testStore, user *entity.User, task *entity.Task) {
+				if _, err := s.tasks.DeleteByUserID(ctx, user.ID); err != nil {
+					t.Fatalf("deleting tasks: %v", err)
+				}
+				if err := s.users.Delete(ctx, user.ID, 0); err != nil {
+					t.Fatalf("deleting user: %v", err)
+				}
+				if _, err := s.tasks.Purge(ctx, time.Now().Add(time.Hour)); err != nil {
+					t.Fatalf("purging tasks: %v", err)
+				}
+				if _, err := s.users.Purge(ctx, time.Now().Add(time.Hour)); err != nil {
+					t.Fatalf("purging users: %v", err)
+				}
+			},
+			check: func(t *testing.T, ctx context.Context, s *testStore, user *entity.User, task *entity.Task) {
+				if _, err := s.users.GetByID(ctx, user.ID); err != nil {
+					t.Errorf("getting user after the rollback: %v", err)
+				}
+				if _, err := s.tasks.GetByID(ctx, task.ID); err != nil {
+					t.Errorf("getting task after the rollback: %v", err)
+				}
+				if matches, _ := s.tasks.Search(ctx, "first", repository.TaskFilter{}, repository.Page{Limit: 10}); len(matches) != 1 {
+					t.Errorf("search found %d tasks, want 1", len(matches))
+				}
+			},
+		},
+		{
+			name: "sessions, tokens and API keys are rolled back",
+			write: func(t *testing.T, ctx context.Context, s *testStore, user *entity.User, task *entity.Task) {
+				if err := s.sessions.Create(ctx, &entity.Session{ID: "s2", UserID: user.ID, TokenHash: "s2"}); err != nil {
+					t.Fatalf("creating session: %v", err)
+				}
+				if _, err := s.sessions.DeleteByUserID(ctx, user.ID); err != nil {
+					t.Fatalf("deleting sessions: %v", err)
+				}
+				if err := s.userTokens.MarkUsed(ctx, 1, time.Now()); err != nil {
+					t.Fatalf("marking token used: %v", err)
+				}
+				if err := s.apiKeys.Revoke(ctx, 1, time.Now()); err != nil {
+					t.Fatalf("revoking API key: %v", err)
+				}
+			},
+			check: func(t *testing.T, ctx context.Context, s *testStore, user *entity.User, task *entity.Task) {
+				sessions, _ := s.sessions.ListByUserID(ctx, user.ID)
+				if len(sessions) != 1 || sessions[0].ID != "s1" {
+					t.Errorf("got %d sessions after the rollback, want only s1", len(sessions))
+				}
+				if token, _ := s.userTokens.GetByTokenHash(ctx, "t1"); token.UsedAt != nil {
+					t.Error("token is still marked used after the rollback")
+				}
+				if key, _ := s.apiKeys.GetByID(ctx, 1); key.RevokedAt != nil {
+					t.Error("API key is still revoked after the rollback")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestStore(t)
+
+			user := s.createUser(t, ctx, "alice")
+			task := s.createTask(t, ctx, user.ID, "First")
+			if err := s.sessions.Create(ctx, &entity.Session{ID: "s1", UserID: user.ID, TokenHash: "s1"}); err != nil {
+				t.Fatalf("creating session: %v", err)
+			}
+			if err := s.userTokens.Create(ctx, &entity.UserToken{UserID: user.ID, TokenHash: "t1", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+				t.Fatalf("creating token: %v", err)
+			}
+			if err := s.apiKeys.Create(ctx, &entity.APIKey{UserID: user.ID, Prefix: "k1"}); err != nil {
+				t.Fatalf("creating API key: %v", err)
+			}
+
+			err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+				tt.write(t, ctx, s, user, task)
+				return errRollback
+			})
+			if !errors.Is(err, errRollback) {
+				t.Fatalf("WithinTx() error = %v, want %v", err, errRollback)
+			}
+
+			tt.check(t, ctx, s, user, task)
+		})
+	}
+}
+
+func TestTransactorKeepsWritesOutsideTheTransaction(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	alice := s.createUser(t, ctx, "alice")
+	bob := s.createUser(t, ctx, "bob")
+
+	started := make(chan struct{})
+	outside := make(chan error)
+	err := s.transactor.WithinTx(ctx, func(txCtx context.Context) error {
+		bob.FirstName = "Changed"
+		if err := s.users.Update(txCtx, bob); err != nil {
+			return err
+		}
+
+		// Update another user outside the transaction while it is in progress
+		go func() {
+			close(started)
+			alice.Password = "rehashed"
+			outside <- s.users.Update(ctx, alice)
+		}()
+		<-started
+		// Give the update time to wait for the transaction
+		time.Sleep(20 * time.Millisecond)
+
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithinTx() error = %v, want %v", err, errRollback)
+	}
+	if err := <-outside; err != nil {
+		t.Fatalf("updating user outside the transaction: %v", err)
+	}
+
+	if found, _ := s.users.GetByID(ctx, alice.ID); found.Password != "rehashed" {
+		t.Error("write made outside the transaction was lost in the rollback")
+	}
+	if found, _ := s.users.GetByID(ctx, bob.ID); found.FirstName != "bob" {
+		t.Errorf("user name is %q after the rollback, want %q", found.FirstName, "bob")
+	}
+}
+
+func TestTransactorDoesNotReuseIDs(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	user := s.createUser(t, ctx, "alice")
+
+	var rolledBack uint64
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		rolledBack = s.createTask(t, ctx, user.ID, "Rolled back").ID
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithinTx() error = %v, want %v", err, errRollback)
+	}
+
+	if task := s.createTask(t, ctx, user.ID, "Kept"); task.ID == rolledBack {
+		t.Errorf("task ID %d of the rolled back transaction was reused", task.ID)
+	}
+}
+
+func TestTransactorNestedTransactionsJoin(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+			s.createUser(t, ctx, "alice")
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithinTx() error = %v, want %v", err, errRollback)
+	}
+
+	if count, _ := s.users.Count(ctx); count != 0 {
+		t.Errorf("%d users after rolling back the outer transaction, want 0", count)
+	}
+}
@@ -1,10 +1,13 @@
 package memory
 
 import (
+	"cmp"
 	"context"
-	"errors"
+	"slices"
 	"sync"
+	"time"
 
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
 	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
 	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
 )
@@ -18,13 +21,19 @@ type UserRepository struct {
 	users map[uint64]*entity.User
 	// Auto-increment ID
 	lastID uint64
+	// Tasks referencing the users, checked before deleting or purging a user
+	tasks *TaskRepository
+	// Whether the first user signed up
+	bootstrapped bool
+	journal
 }
 
-// NewUserRepository creates a new in-memory user repository
-func NewUserRepository() *UserRepository {
+// NewUserRepository creates a new in-memory user repository whose users own the given tasks
+func NewUserRepository(tasks *TaskRepository) *UserRepository {
 	return &UserRepository{
 		users:  make(map[uint64]*entity.User),
 		lastID: 0,
+		tasks:  tasks,
 	}
 }
 
@@ -34,11 +43,13 @@ func (r *UserRepository) GetByID(ctx context.Context, id uint64) (*entity.User,
 	defer r.mu.RUnlock()
 
 	user, exists := r.users[id]
-	if !exists {
-		return nil, errors.New("user not found")
+	if !exists || user.DeletedAt != nil {
+		return nil, domain.NewError(domain.ErrNotFound, "user not found")
 	}
 
-	return user, nil
+	// Return a copy so callers cannot modify the stored user without Update
+	found := *user
+	return &found, nil
 }
 
 // GetByEmail retrieves a user by their email
@@ -47,12 +58,13 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*entity.
 	defer r.mu.RUnlock()
 
 	for _, user := range r.users {
-		if user.Email == email {
-			return user, nil
+		if user.DeletedAt == nil && user.Email == email {
+			found := *user
+			return &found, nil
 		}
 	}
 
-	return nil, errors.New("user not found")
+	return nil, domain.NewError(domain.ErrNotFound, "user not found")
 }
 
 // GetByUsername retrieves a user by their username
@@ -61,98 +73,262 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*e
 	defer r.mu.RUnlock()
 
 	for _, user := range r.users {
-		if user.Username == username {
-			return user, nil
+		if user.DeletedAt == nil && user.Username == username {
+			found := *user
+			return &found, nil
 		}
 	}
 
-	return nil, errors.New("user not found")
+	return nil, domain.NewError(domain.ErrNotFound, "user not found")
 }
 
 // Create creates a new user
 func (r *UserRepository) Create(ctx context.Context, user *entity.User) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	// Check if email already exists
-	for _, existingUser := range r.users {
-		if existingUser.Email == user.Email {
-			return errors.New("email already exists")
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		// Check if email already exists, including users in the trash so they can be restored
+		for _, existingUser := range r.users {
+			if existingUser.Email == user.Email {
+				return nil, domain.NewError(domain.ErrConflict, "email already exists")
+			}
+			if existingUser.Username == user.Username {
+				return nil, domain.NewError(domain.ErrConflict, "username already exists")
+			}
 		}
-		if existingUser.Username == user.Username {
-			return errors.New("username already exists")
+
+		// Assign ID and initial version
+		r.lastID++
+		user.ID = r.lastID
+		user.Version = 1
+
+		// Store a copy of the user
+		undo := r.undo(saved(r.users, user.ID))
+		stored := *user
+		stored.RecoveryCodes = slices.Clone(user.RecoveryCodes)
+		r.users[user.ID] = &stored
+
+		return undo, nil
+	})
+}
+
+// Bootstrap records that the first user signed up, unless that happened before
+func (r *UserRepository) Bootstrap(ctx context.Context) (bool, error) {
+	var first bool
+	err := r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if r.bootstrapped {
+			return nil, nil
 		}
-	}
+		r.bootstrapped, first = true, true
 
-	// Assign ID
-	r.lastID++
-	user.ID = r.lastID
+		return func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
 
-	// Store user
-	r.users[user.ID] = user
+			r.bootstrapped = false
+		}, nil
+	})
 
-	return nil
+	return first, err
 }
 
 // Update updates an existing user
 func (r *UserRepository) Update(ctx context.Context, user *entity.User) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
 
-	if _, exists := r.users[user.ID]; !exists {
-		return errors.New("user not found")
-	}
+		existing, exists := r.users[user.ID]
+		if !exists || existing.DeletedAt != nil {
+			return nil, domain.NewError(domain.ErrNotFound, "user not found")
+		}
 
-	// Check if email already exists for another user
-	for id, existingUser := range r.users {
-		if id != user.ID && existingUser.Email == user.Email {
-			return errors.New("email already exists")
+		// Reject writes based on a stale version
+		if existing.Version != user.Version {
+			return nil, repository.ErrVersionConflict
 		}
-		if id != user.ID && existingUser.Username == user.Username {
-			return errors.New("username already exists")
+
+		// Check if email already exists for another user
+		for id, existingUser := range r.users {
+			if id != user.ID && existingUser.Email == user.Email {
+				return nil, domain.NewError(domain.ErrConflict, "email already exists")
+			}
+			if id != user.ID && existingUser.Username == user.Username {
+				return nil, domain.NewError(domain.ErrConflict, "username already exists")
+			}
 		}
-	}
 
-	// Update user
-	r.users[user.ID] = user
+		// Update user
+		undo := r.undo(saved(r.users, user.ID))
+		user.Version++
+		stored := *user
+		stored.RecoveryCodes = slices.Clone(user.RecoveryCodes)
+		r.users[user.ID] = &stored
 
-	return nil
+		return undo, nil
+	})
 }
 
-// Delete deletes a user by their ID
-func (r *UserRepository) Delete(ctx context.Context, id uint64) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// Delete moves a user to the trash
+func (r *UserRepository) Delete(ctx context.Context, id uint64, version uint64) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
 
-	if _, exists := r.users[id]; !exists {
-		return errors.New("user not found")
-	}
+		user, exists := r.users[id]
+		if !exists || user.DeletedAt != nil {
+			return nil, domain.NewError(domain.ErrNotFound, "user not found")
+		}
+
+		if version != 0 && user.Version != version {
+			return nil, repository.ErrVersionConflict
+		}
+
+		// Refuse to orphan tasks, mirroring the SQL repositories
+		if r.tasks.owns(id, false) {
+			return nil, repository.ErrUserHasTasks
+		}
+
+		undo := r.undo(saved(r.users, id))
+		now := time.Now()
+		user.DeletedAt = &now
 
-	delete(r.users, id)
+		return undo, nil
+	})
+}
 
-	return nil
+// List retrieves a page of users, ordered by ID
+func (r *UserRepository) List(ctx context.Context, page repository.Page) ([]*entity.User, error) {
+	afterID := page.AfterID()
+	return r.filter(page, func(user *entity.User) bool {
+		return user.DeletedAt == nil && user.ID > afterID
+	}, compareUserIDs), nil
 }
 
-// List retrieves a list of users with pagination
-func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*entity.User, error) {
+// Count counts the users outside the trash
+func (r *UserRepository) Count(ctx context.Context) (int64, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	// Convert map to slice
-	users := make([]*entity.User, 0, len(r.users))
+	var count int64
 	for _, user := range r.users {
-		users = append(users, user)
+		if user.DeletedAt == nil {
+			count++
+		}
 	}
 
-	// Apply pagination
-	if offset >= len(users) {
-		return []*entity.User{}, nil
+	return count, nil
+}
+
+// ListDeleted retrieves a page of the users in the trash, most recently deleted first
+func (r *UserRepository) ListDeleted(ctx context.Context, page repository.Page) ([]*entity.User, error) {
+	after, afterID, err := page.AfterTime()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.filter(page, func(user *entity.User) bool {
+		return user.DeletedAt != nil && deletedAfter(*user.DeletedAt, user.ID, after, afterID)
+	}, compareDeletedUsers), nil
+}
+
+// CountDeleted counts the users in the trash
+func (r *UserRepository) CountDeleted(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, user := range r.users {
+		if user.DeletedAt != nil {
+			count++
+		}
 	}
 
-	end := offset + limit
-	if end > len(users) {
-		end = len(users)
+	return count, nil
+}
+
+// Restore moves a user out of the trash
+func (r *UserRepository) Restore(ctx context.Context, id uint64) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		user, exists := r.users[id]
+		if !exists || user.DeletedAt == nil {
+			return nil, domain.NewError(domain.ErrNotFound, "user not found in trash")
+		}
+
+		undo := r.undo(saved(r.users, id))
+		user.DeletedAt = nil
+
+		return undo, nil
+	})
+}
+
+// Purge permanently removes users moved to the trash before the given time.
+// Users still referenced by tasks are kept until those tasks are purged.
+func (r *UserRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		var ids []uint64
+		for id, user := range r.users {
+			if user.DeletedAt != nil && user.DeletedAt.Before(before) && !r.tasks.owns(id, true) {
+				ids = append(ids, id)
+			}
+		}
+
+		undo := r.undo(saved(r.users, ids...))
+		for _, id := range ids {
+			delete(r.users, id)
+		}
+		purged = int64(len(ids))
+
+		return undo, nil
+	})
+
+	return purged, err
+}
+
+// filter returns copies of the users matching keep, sorted by compare, with
+// pagination applied
+func (r *UserRepository) filter(page repository.Page, keep func(user *entity.User) bool, compare func(a, b *entity.User) int) []*entity.User {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]*entity.User, 0)
+	for _, user := range r.users {
+		if keep(user) {
+			found := *user
+			users = append(users, &found)
+		}
 	}
 
-	return users[offset:end], nil
+	return paginate(users, page, compare)
+}
+
+// compareUserIDs orders users by ID
+func compareUserIDs(a, b *entity.User) int {
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// compareDeletedUsers orders users most recently deleted first
+func compareDeletedUsers(a, b *entity.User) int {
+	return compareDeleted(*a.DeletedAt, a.ID, *b.DeletedAt, b.ID)
+}
+
+// undo returns a function putting back the users saved before a write
+func (r *UserRepository) undo(before map[uint64]*entity.User) func() {
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		restore(r.users, before)
+	}
 }
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure UserTokenRepository implements repository.UserTokenRepository
+var _ repository.UserTokenRepository = (*UserTokenRepository)(nil)
+
+// UserTokenRepository is an in-memory implementation of repository.UserTokenRepository
+type UserTokenRepository struct {
+	mu     sync.RWMutex
+	tokens map[uint64]*entity.UserToken
+	lastID uint64
+	journal
+}
+
+// NewUserTokenRepository creates a new in-memory user token repository
+func NewUserTokenRepository() *UserTokenRepository {
+	return &UserTokenRepository{
+		tokens: make(map[uint64]*entity.UserToken),
+	}
+}
+
+// GetByTokenHash retrieves a token by the hash of its secret
+func (r *UserTokenRepository) GetByTokenHash(_ context.Context, tokenHash string) (*entity.UserToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			found := *token
+			return &found, nil
+		}
+	}
+
+	return nil, domain.NewError(domain.ErrNotFound, "user token not found")
+}
+
+// Create creates a new token
+func (r *UserTokenRepository) Create(ctx context.Context, token *entity.UserToken) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		for _, existing := range r.tokens {
+			if existing.TokenHash == token.TokenHash {
+				return nil, domain.NewError(domain.ErrConflict, "user token already exists")
+			}
+		}
+
+		// Assign ID
+		r.lastID++
+		token.ID = r.lastID
+
+		// Store a copy of the token
+		undo := r.undo(saved(r.tokens, token.ID))
+		stored := *token
+		r.tokens[token.ID] = &stored
+
+		return undo, nil
+	})
+}
+
+// MarkUsed records that a token was used
+func (r *UserTokenRepository) MarkUsed(ctx context.Context, id uint64, at time.Time) error {
+	return r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		token, exists := r.tokens[id]
+		if !exists || token.UsedAt != nil {
+			return nil, domain.NewError(domain.ErrNotFound, "user token not found")
+		}
+
+		undo := r.undo(saved(r.tokens, id))
+		token.UsedAt = &at
+
+		return undo, nil
+	})
+}
+
+// DeleteByUserID removes every token of a user issued for purpose
+func (r *UserTokenRepository) DeleteByUserID(ctx context.Context, userID uint64, purpose entity.TokenPurpose) error {
+	_, err := r.deleteWhere(ctx, func(token *entity.UserToken) bool {
+		return token.UserID == userID && token.Purpose == purpose
+	})
+
+	return err
+}
+
+// Purge removes tokens that were used or expired before now
+func (r *UserTokenRepository) Purge(ctx context.Context, now time.Time) (int64, error) {
+	return r.deleteWhere(ctx, func(token *entity.UserToken) bool {
+		return token.UsedAt != nil || token.ExpiresAt.Before(now)
+	})
+}
+
+// deleteWhere removes the tokens matching match and returns how many there were
+func (r *UserTokenRepository) deleteWhere(ctx context.Context, match func(token *entity.UserToken) bool) (int64, error) {
+	var deleted int64
+	err := r.write(ctx, func() (func(), error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		var ids []uint64
+		for id, token := range r.tokens {
+			if match(token) {
+				ids = append(ids, id)
+			}
+		}
+
+		undo := r.undo(saved(r.tokens, ids...))
+		for _, id := range ids {
+			delete(r.tokens, id)
+		}
+		deleted = int64(len(ids))
+
+		return undo, nil
+	})
+
+	return deleted, err
+}
+
+// undo returns a function putting back the tokens saved before a write
+func (r *UserTokenRepository) undo(before map[uint64]*entity.UserToken) func() {
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		restore(r.tokens, before)
+	}
+}
@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure LoginAttemptRepository implements repository.LoginAttemptRepository
+var _ repository.LoginAttemptRepository = (*LoginAttemptRepository)(nil)
+
+const loginAttemptColumns = `key, failures, last_failure_at, locked_until`
+
+// LoginAttemptRepository is a PostgreSQL implementation of repository.LoginAttemptRepository
+type LoginAttemptRepository struct {
+	db *sql.DB
+}
+
+// NewLoginAttemptRepository creates a new PostgreSQL login attempt repository
+func NewLoginAttemptRepository(db *sql.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		db: db,
+	}
+}
+
+// Get retrieves the attempts recorded under key, or empty attempts if there are none
+func (r *LoginAttemptRepository) Get(ctx context.Context, key string) (*entity.LoginAttempts, error) {
+	row := executor(ctx, r.db).QueryRowContext(ctx, `SELECT `+loginAttemptColumns+` FROM login_attempts WHERE key = $1`, key)
+	attempts, err := scanLoginAttempts(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &entity.LoginAttempts{Key: key}, nil
+	}
+
+	return attempts, err
+}
+
+// Increment adds a failure at now to the attempts recorded under key, starting
+// over if they are stale, and returns the result
+func (r *LoginAttemptRepository) Increment(ctx context.Context, key string, now time.Time, window time.Duration) (*entity.LoginAttempts, error) {
+	// Every SET expression sees the row as it was, so a stale row starts over
+	// with both its failures and its lockout
+	row := executor(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.locked_until IS NOT NULL AND login_attempts.locked_until <= $2 THEN 1
+				WHEN login_attempts.locked_until IS NULL AND login_attempts.last_failure_at <= $3 THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			locked_until = CASE
+				WHEN login_attempts.locked_until <= $2 THEN NULL
+				ELSE login_attempts.locked_until
+			END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING `+loginAttemptColumns,
+		key, now, now.Add(-window),
+	)
+
+	return scanLoginAttempts(row)
+}
+
+// Decrement takes back a failure added by Increment
+func (r *LoginAttemptRepository) Decrement(ctx context.Context, key string) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE login_attempts SET failures = failures - 1 WHERE key = $1 AND failures > 0`,
+		key,
+	)
+
+	return err
+}
+
+// Lock locks the attempts recorded under key out until until unless they are
+// locked at now already, and reports whether it did
+func (r *LoginAttemptRepository) Lock(ctx context.Context, key string, now, until time.Time) (bool, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE login_attempts SET locked_until = $3
+		WHERE key = $1 AND (locked_until IS NULL OR locked_until <= $2)`,
+		key, now, until,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// Delete forgets the attempts recorded under key
+func (r *LoginAttemptRepository) Delete(ctx context.Context, key string) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx, `DELETE FROM login_attempts WHERE key = $1`, key)
+	return err
+}
+
+// Purge removes attempts whose last failure was before cutoff and that are not locked past it
+func (r *LoginAttemptRepository) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM login_attempts
+		WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until <= $1)`,
+		cutoff,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// scanLoginAttempts scans a single login_attempts row
+func scanLoginAttempts(row scanner) (*entity.LoginAttempts, error) {
+	var attempts entity.LoginAttempts
+	var lockedUntil sql.NullTime
+	err := row.Scan(
+		&attempts.Key,
+		&attempts.Failures,
+		&attempts.LastFailureAt,
+		&lockedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if lockedUntil.Valid {
+		attempts.LockedUntil = &lockedUntil.Time
+	}
+
+	return &attempts, nil
+}
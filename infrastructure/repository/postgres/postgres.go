@@ -0,0 +1,49 @@
+// Package postgres provides PostgreSQL implementations of the domain repositories
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// PostgreSQL error codes the repositories translate
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// versionError tells apart a missing row from a stale version after an
+// optimistic update matched no rows, using a query returning whether id exists
+func versionError(ctx context.Context, q querier, existsQuery string, id uint64, notFound error) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx, existsQuery, id).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return notFound
+	}
+
+	return repository.ErrVersionConflict
+}
+
+// requireRow returns notFound when a statement affected no rows
+func requireRow(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return notFound
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure SessionRepository implements repository.SessionRepository
+var _ repository.SessionRepository = (*SessionRepository)(nil)
+
+const sessionColumns = `id, token_hash, csrf_token, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at`
+
+// SessionRepository is a PostgreSQL implementation of repository.SessionRepository
+type SessionRepository struct {
+	db *sql.DB
+}
+
+// NewSessionRepository creates a new PostgreSQL session repository
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{
+		db: db,
+	}
+}
+
+// GetByTokenHash retrieves a session by the hash of its cookie token
+func (r *SessionRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*entity.Session, error) {
+	row := executor(ctx, r.db).QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE token_hash = $1`, tokenHash)
+	return scanSession(row)
+}
+
+// ListByUserID retrieves every stored session of a user, most recently used first
+func (r *SessionRepository) ListByUserID(ctx context.Context, userID uint64) ([]*entity.Session, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, `
+		SELECT `+sessionColumns+`
+		FROM sessions
+		WHERE user_id = $1
+		ORDER BY last_seen_at DESC, id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]*entity.Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// Create creates a new session
+func (r *SessionRepository) Create(ctx context.Context, session *entity.Session) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO sessions (id, token_hash, csrf_token, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		session.ID,
+		session.TokenHash,
+		session.CSRFToken,
+		session.UserID,
+		session.UserAgent,
+		session.IPAddress,
+		session.CreatedAt,
+		session.LastSeenAt,
+		session.ExpiresAt,
+	)
+
+	return err
+}
+
+// Touch records the time a session was last used
+func (r *SessionRepository) Touch(ctx context.Context, id string, lastSeenAt time.Time) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE sessions SET last_seen_at = $2 WHERE id = $1`,
+		id, lastSeenAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result, domain.NewError(domain.ErrNotFound, "session not found"))
+}
+
+// Delete removes a session
+func (r *SessionRepository) Delete(ctx context.Context, id string) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `DELETE FROM sessions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result, domain.NewError(domain.ErrNotFound, "session not found"))
+}
+
+// DeleteByUserID removes every session of a user
+func (r *SessionRepository) DeleteByUserID(ctx context.Context, userID uint64) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteOthers removes every session of a user but one
+func (r *SessionRepository) DeleteOthers(ctx context.Context, userID uint64, keepID string) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1 AND id <> $2`, userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Purge removes sessions that expired before now or were last used before idleSince
+func (r *SessionRepository) Purge(ctx context.Context, now, idleSince time.Time) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM sessions WHERE expires_at < $1 OR last_seen_at < $2`,
+		now, idleSince,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// scanSession scans a single sessions row
+func scanSession(row scanner) (*entity.Session, error) {
+	var session entity.Session
+	err := row.Scan(
+		&session.ID,
+		&session.TokenHash,
+		&session.CSRFToken,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NewError(domain.ErrNotFound, "session not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
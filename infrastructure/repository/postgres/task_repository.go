@@ -0,0 +1,518 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure TaskRepository implements repository.TaskRepository
+var _ repository.TaskRepository = (*TaskRepository)(nil)
+
+const taskColumns = `id, title, description, status, user_id, due_date, version, created_at, updated_at, deleted_at`
+
+// TaskRepository is a PostgreSQL implementation of repository.TaskRepository
+type TaskRepository struct {
+	db *sql.DB
+}
+
+// NewTaskRepository creates a new PostgreSQL task repository
+func NewTaskRepository(db *sql.DB) *TaskRepository {
+	return &TaskRepository{
+		db: db,
+	}
+}
+
+// GetByID retrieves a task by its ID
+func (r *TaskRepository) GetByID(ctx context.Context, id uint64) (*entity.Task, error) {
+	row := executor(ctx, r.db).QueryRowContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = $1 AND deleted_at IS NULL`, id)
+	return scanTask(row)
+}
+
+// CountByUserID counts the tasks owned by a user, excluding trashed ones
+func (r *TaskRepository) CountByUserID(ctx context.Context, userID uint64) (int64, error) {
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND deleted_at IS NULL`,
+		userID,
+	).Scan(&count)
+
+	return count, err
+}
+
+// DeleteByUserID moves every task owned by a user to the trash
+func (r *TaskRepository) DeleteByUserID(ctx context.Context, userID uint64) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE tasks SET deleted_at = $2 WHERE user_id = $1 AND deleted_at IS NULL`,
+		userID, time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ReassignUser transfers every task owned by a user, including trashed ones, to another user
+func (r *TaskRepository) ReassignUser(ctx context.Context, fromUserID, toUserID uint64) (int64, error) {
+	q := executor(ctx, r.db)
+	if err := lockOwner(ctx, q, toUserID); err != nil {
+		return 0, err
+	}
+
+	result, err := q.ExecContext(ctx, `
+		UPDATE tasks SET user_id = $2, updated_at = $3, version = version + 1 WHERE user_id = $1`,
+		fromUserID, toUserID, time.Now(),
+	)
+	if err != nil {
+		return 0, taskError(err)
+	}
+
+	return result.RowsAffected()
+}
+
+// Create creates a new task
+func (r *TaskRepository) Create(ctx context.Context, task *entity.Task) error {
+	q := executor(ctx, r.db)
+	if err := lockOwner(ctx, q, task.UserID); err != nil {
+		return err
+	}
+
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO tasks (title, description, status, user_id, due_date, version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 1, $6, $7)
+		RETURNING id, version`,
+		task.Title,
+		task.Description,
+		task.Status,
+		task.UserID,
+		task.DueDate,
+		task.CreatedAt,
+		task.UpdatedAt,
+	).Scan(&task.ID, &task.Version)
+
+	return taskError(err)
+}
+
+// Update updates an existing task
+func (r *TaskRepository) Update(ctx context.Context, task *entity.Task) error {
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		UPDATE tasks
+		SET title = $3, description = $4, status = $5, user_id = $6, due_date = $7, updated_at = $8,
+			version = version + 1
+		WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+		RETURNING version`,
+		task.ID,
+		task.Version,
+		task.Title,
+		task.Description,
+		task.Status,
+		task.UserID,
+		task.DueDate,
+		task.UpdatedAt,
+	).Scan(&task.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return versionError(ctx, executor(ctx, r.db), `SELECT EXISTS (SELECT 1 FROM tasks WHERE id = $1 AND deleted_at IS NULL)`, task.ID, domain.NewError(domain.ErrNotFound, "task not found"))
+	}
+
+	return taskError(err)
+}
+
+// Delete moves a task to the trash
+func (r *TaskRepository) Delete(ctx context.Context, id uint64, version uint64) error {
+	q := executor(ctx, r.db)
+	result, err := q.ExecContext(ctx, `
+		UPDATE tasks SET deleted_at = $2
+		WHERE id = $1 AND deleted_at IS NULL AND ($3::bigint = 0 OR version = $3)`,
+		id, time.Now(), version,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+
+	return versionError(ctx, q, `SELECT EXISTS (SELECT 1 FROM tasks WHERE id = $1 AND deleted_at IS NULL)`, id, domain.NewError(domain.ErrNotFound, "task not found"))
+}
+
+// List retrieves a page of the tasks matching filter, ordered by sort
+func (r *TaskRepository) List(ctx context.Context, filter repository.TaskFilter, sort repository.TaskSort, page repository.Page) ([]*entity.Task, error) {
+	q := filterTasks(filter)
+	if page.After != nil {
+		if err := q.after(sort, *page.After); err != nil {
+			return nil, err
+		}
+	}
+	limit, offset := q.arg(page.Limit), q.arg(page.Skip())
+
+	return r.query(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE `+q.clause()+`
+		ORDER BY `+taskOrder(sort)+`
+		LIMIT `+limit+` OFFSET `+offset,
+		q.args...,
+	)
+}
+
+// Count counts the tasks outside the trash matching filter
+func (r *TaskRepository) Count(ctx context.Context, filter repository.TaskFilter) (int64, error) {
+	q := filterTasks(filter)
+
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM tasks WHERE `+q.clause(),
+		q.args...,
+	).Scan(&count)
+
+	return count, err
+}
+
+// Search retrieves a page of the tasks matching filter that contain every
+// search term of query, most relevant first
+func (r *TaskRepository) Search(ctx context.Context, query string, filter repository.TaskFilter, page repository.Page) ([]*repository.TaskMatch, error) {
+	q := filterTasks(filter)
+	from := q.matching(query)
+	if page.After != nil {
+		if err := q.afterRank(*page.After); err != nil {
+			return nil, err
+		}
+	}
+	limit, offset := q.arg(page.Limit), q.arg(page.Skip())
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, `
+		SELECT `+taskColumns+`, rank
+		FROM `+from+`
+		WHERE `+q.clause()+`
+		ORDER BY rank DESC, id
+		LIMIT `+limit+` OFFSET `+offset,
+		q.args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make([]*repository.TaskMatch, 0)
+	for rows.Next() {
+		var rank float64
+		task, err := scanTask(rows, &rank)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, &repository.TaskMatch{Task: task, Rank: rank})
+	}
+
+	return matches, rows.Err()
+}
+
+// CountSearch counts the tasks Search finds for query and filter
+func (r *TaskRepository) CountSearch(ctx context.Context, query string, filter repository.TaskFilter) (int64, error) {
+	q := filterTasks(filter)
+	from := q.matching(query)
+
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM `+from+` WHERE `+q.clause(),
+		q.args...,
+	).Scan(&count)
+
+	return count, err
+}
+
+// ListDeleted retrieves a page of the tasks in the trash, most recently deleted first
+func (r *TaskRepository) ListDeleted(ctx context.Context, page repository.Page) ([]*entity.Task, error) {
+	after, afterID, err := page.AfterTime()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.query(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE deleted_at IS NOT NULL
+			AND ($1::timestamptz IS NULL OR deleted_at < $1 OR (deleted_at = $1 AND id > $2))
+		ORDER BY deleted_at DESC, id
+		LIMIT $3 OFFSET $4`,
+		after, afterID, page.Limit, page.Skip(),
+	)
+}
+
+// CountDeleted counts the tasks in the trash
+func (r *TaskRepository) CountDeleted(ctx context.Context) (int64, error) {
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM tasks WHERE deleted_at IS NOT NULL`,
+	).Scan(&count)
+
+	return count, err
+}
+
+// Restore moves a task out of the trash
+func (r *TaskRepository) Restore(ctx context.Context, id uint64) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE tasks SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result, domain.NewError(domain.ErrNotFound, "task not found in trash"))
+}
+
+// Purge permanently removes tasks moved to the trash before the given time
+func (r *TaskRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `DELETE FROM tasks WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// query runs a query returning task rows
+func (r *TaskRepository) query(ctx context.Context, query string, args ...any) ([]*entity.Task, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]*entity.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// taskQuery collects the conditions of a query on tasks and their arguments
+type taskQuery struct {
+	conditions []string
+	args       []any
+}
+
+// filterTasks starts a query on the tasks outside the trash matching filter
+func filterTasks(filter repository.TaskFilter) *taskQuery {
+	q := &taskQuery{conditions: []string{"deleted_at IS NULL"}}
+
+	if len(filter.Statuses) > 0 {
+		placeholders := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			placeholders[i] = q.arg(string(status))
+		}
+		q.add("status IN (" + strings.Join(placeholders, ", ") + ")")
+	}
+	if filter.UserID != 0 {
+		q.add("user_id = " + q.arg(filter.UserID))
+	}
+	if filter.DueAfter != nil {
+		q.add("due_date >= " + q.arg(*filter.DueAfter))
+	}
+	if filter.DueBefore != nil {
+		q.add("due_date < " + q.arg(*filter.DueBefore))
+	}
+	if filter.CreatedAfter != nil {
+		q.add("created_at >= " + q.arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		q.add("created_at < " + q.arg(*filter.CreatedBefore))
+	}
+	if filter.TitleContains != "" {
+		q.add("title ILIKE " + q.arg("%"+likeEscaper.Replace(filter.TitleContains)+"%"))
+	}
+
+	return q
+}
+
+// after restricts the query to the tasks following cursor in the order of sort
+func (q *taskQuery) after(sort repository.TaskSort, cursor repository.Cursor) error {
+	id := q.arg(cursor.ID)
+	if sort.Field == "" || sort.Field == repository.TaskSortID {
+		q.add("id > " + id)
+		return nil
+	}
+
+	column := taskSortColumn(sort.Field)
+	var key string
+	if sort.Field == repository.TaskSortTitle {
+		key = q.arg(cursor.Key)
+	} else {
+		t, err := cursor.Time()
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			// Only tasks without a due date follow one without a due date
+			q.add("(" + column + " IS NULL AND id > " + id + ")")
+			return nil
+		}
+		key = q.arg(*t)
+	}
+
+	operator := ">"
+	if sort.Descending {
+		operator = "<"
+	}
+	condition := column + " " + operator + " " + key + " OR (" + column + " = " + key + " AND id > " + id + ")"
+	if sort.Field == repository.TaskSortDueDate {
+		condition += " OR due_date IS NULL"
+	}
+	q.add("(" + condition + ")")
+
+	return nil
+}
+
+// matching makes the query select from the tasks containing every search term
+// of text, adding their rank as a column. It returns the FROM clause to use
+// instead of the tasks table.
+func (q *taskQuery) matching(text string) string {
+	terms := "plainto_tsquery('english', " + q.arg(text) + ")"
+
+	return `(
+			SELECT *, ts_rank(search_vector, ` + terms + `)::float8 AS rank
+			FROM tasks
+			WHERE search_vector @@ ` + terms + `
+		) tasks`
+}
+
+// afterRank restricts the query to the search results following cursor
+func (q *taskQuery) afterRank(cursor repository.Cursor) error {
+	rank, err := cursor.Rank()
+	if err != nil {
+		return err
+	}
+
+	key, id := q.arg(rank), q.arg(cursor.ID)
+	q.add("(rank < " + key + " OR (rank = " + key + " AND id > " + id + "))")
+
+	return nil
+}
+
+// arg adds an argument to the query and returns its placeholder
+func (q *taskQuery) arg(value any) string {
+	q.args = append(q.args, value)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+// add adds a condition to the query
+func (q *taskQuery) add(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// clause returns the conditions of the query for its WHERE clause
+func (q *taskQuery) clause() string {
+	return strings.Join(q.conditions, " AND ")
+}
+
+// taskSortColumn returns the column tasks are sorted by for a sort field.
+// Titles are compared bytewise, like the other repositories compare them.
+func taskSortColumn(field repository.TaskSortField) string {
+	switch field {
+	case repository.TaskSortCreatedAt:
+		return "created_at"
+	case repository.TaskSortUpdatedAt:
+		return "updated_at"
+	case repository.TaskSortDueDate:
+		return "due_date"
+	case repository.TaskSortTitle:
+		return `title COLLATE "C"`
+	default:
+		return "id"
+	}
+}
+
+// taskOrder returns the ORDER BY clause of sort. Missing due dates come last
+// in either direction and ties are broken by ID.
+func taskOrder(sort repository.TaskSort) string {
+	if sort.Field == "" || sort.Field == repository.TaskSortID {
+		return "id"
+	}
+
+	direction := "ASC"
+	if sort.Descending {
+		direction = "DESC"
+	}
+
+	return taskSortColumn(sort.Field) + " " + direction + " NULLS LAST, id"
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// scanTask scans a single tasks row, followed by any extra columns into extra
+func scanTask(row scanner, extra ...any) (*entity.Task, error) {
+	var task entity.Task
+	var dueDate, deletedAt sql.NullTime
+	err := row.Scan(append([]any{
+		&task.ID,
+		&task.Title,
+		&task.Description,
+		&task.Status,
+		&task.UserID,
+		&dueDate,
+		&task.Version,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+		&deletedAt,
+	}, extra...)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NewError(domain.ErrNotFound, "task not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if dueDate.Valid {
+		task.DueDate = &dueDate.Time
+	}
+	if deletedAt.Valid {
+		task.DeletedAt = &deletedAt.Time
+	}
+
+	return &task, nil
+}
+
+// lockOwner locks the user with id for share until the transaction ends, so
+// that they cannot be moved to the trash while they are given tasks: the
+// foreign key only takes a lock that does not conflict with the soft delete's
+// update. It returns an ErrNotFound error if the user is missing or in the trash.
+func lockOwner(ctx context.Context, q querier, id uint64) error {
+	var locked uint64
+	err := q.QueryRowContext(ctx, `
+		SELECT id FROM users WHERE id = $1 AND deleted_at IS NULL FOR SHARE`,
+		id,
+	).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.NewError(domain.ErrNotFound, "user not found")
+	}
+
+	return err
+}
+
+// taskError translates constraint violations into domain errors
+func taskError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return domain.NewError(domain.ErrNotFound, "user not found")
+	}
+
+	return err
+}
@@ -0,0 +1,325 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure UserRepository implements repository.UserRepository
+var _ repository.UserRepository = (*UserRepository)(nil)
+
+const userColumns = `id, username, email, password, first_name, last_name, role, email_verified_at,
+	totp_secret, totp_enabled, totp_last_step, recovery_codes, token_version, version, created_at, updated_at, deleted_at`
+
+// UserRepository is a PostgreSQL implementation of repository.UserRepository
+type UserRepository struct {
+	db *sql.DB
+}
+
+// NewUserRepository creates a new PostgreSQL user repository
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{
+		db: db,
+	}
+}
+
+// GetByID retrieves a user by their ID
+func (r *UserRepository) GetByID(ctx context.Context, id uint64) (*entity.User, error) {
+	row := executor(ctx, r.db).QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1 AND deleted_at IS NULL`, id)
+	return scanUser(row)
+}
+
+// GetByEmail retrieves a user by their email
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	row := executor(ctx, r.db).QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = $1 AND deleted_at IS NULL`, email)
+	return scanUser(row)
+}
+
+// GetByUsername retrieves a user by their username
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*entity.User, error) {
+	row := executor(ctx, r.db).QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE username = $1 AND deleted_at IS NULL`, username)
+	return scanUser(row)
+}
+
+// Create creates a new user
+func (r *UserRepository) Create(ctx context.Context, user *entity.User) error {
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO users (username, email, password, first_name, last_name, role, email_verified_at,
+			totp_secret, totp_enabled, totp_last_step, recovery_codes, token_version, version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, 1, $13, $14)
+		RETURNING id, version`,
+		user.Username,
+		user.Email,
+		user.Password,
+		user.FirstName,
+		user.LastName,
+		user.Role,
+		user.EmailVerifiedAt,
+		user.TOTPSecret,
+		user.TOTPEnabled,
+		user.TOTPLastStep,
+		strings.Join(user.RecoveryCodes, " "),
+		user.TokenVersion,
+		user.CreatedAt,
+		user.UpdatedAt,
+	).Scan(&user.ID, &user.Version)
+
+	return userError(err)
+}
+
+// Bootstrap records that the first user signed up, unless that happened before
+func (r *UserRepository) Bootstrap(ctx context.Context) (bool, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO bootstrap (id, created_at) VALUES (1, $1) ON CONFLICT DO NOTHING`,
+		time.Now(),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	return inserted == 1, err
+}
+
+// Update updates an existing user
+func (r *UserRepository) Update(ctx context.Context, user *entity.User) error {
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		UPDATE users
+		SET username = $3, email = $4, password = $5, first_name = $6, last_name = $7, role = $8, email_verified_at = $9,
+			totp_secret = $10, totp_enabled = $11, totp_last_step = $12, recovery_codes = $13, token_version = $14, updated_at = $15,
+			version = version + 1
+		WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+		RETURNING version`,
+		user.ID,
+		user.Version,
+		user.Username,
+		user.Email,
+		user.Password,
+		user.FirstName,
+		user.LastName,
+		user.Role,
+		user.EmailVerifiedAt,
+		user.TOTPSecret,
+		user.TOTPEnabled,
+		user.TOTPLastStep,
+		strings.Join(user.RecoveryCodes, " "),
+		user.TokenVersion,
+		user.UpdatedAt,
+	).Scan(&user.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return versionError(ctx, executor(ctx, r.db), `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`, user.ID, domain.NewError(domain.ErrNotFound, "user not found"))
+	}
+
+	return userError(err)
+}
+
+// Delete moves a user to the trash. It has to run in a transaction: the user is
+// locked first, so that tasks being given to them concurrently, which lock the
+// user for share, either commit before the check for tasks or wait and then
+// find the user in the trash.
+func (r *UserRepository) Delete(ctx context.Context, id uint64, version uint64) error {
+	q := executor(ctx, r.db)
+
+	var stored uint64
+	err := q.QueryRowContext(ctx, `
+		SELECT version FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		id,
+	).Scan(&stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.NewError(domain.ErrNotFound, "user not found")
+	}
+	if err != nil {
+		return err
+	}
+	if version != 0 && stored != version {
+		return repository.ErrVersionConflict
+	}
+
+	// At READ COMMITTED this statement sees every task committed before the lock was taken
+	result, err := q.ExecContext(ctx, `
+		UPDATE users SET deleted_at = $2
+		WHERE id = $1 AND deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM tasks WHERE user_id = $1 AND deleted_at IS NULL)`,
+		id, time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+
+	return repository.ErrUserHasTasks
+}
+
+// List retrieves a page of users, ordered by ID
+func (r *UserRepository) List(ctx context.Context, page repository.Page) ([]*entity.User, error) {
+	return r.query(ctx, `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE deleted_at IS NULL AND id > $1
+		ORDER BY id
+		LIMIT $2 OFFSET $3`,
+		page.AfterID(), page.Limit, page.Skip(),
+	)
+}
+
+// Count counts the users outside the trash
+func (r *UserRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`,
+	).Scan(&count)
+
+	return count, err
+}
+
+// ListDeleted retrieves a page of the users in the trash, most recently deleted first
+func (r *UserRepository) ListDeleted(ctx context.Context, page repository.Page) ([]*entity.User, error) {
+	after, afterID, err := page.AfterTime()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.query(ctx, `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE deleted_at IS NOT NULL
+			AND ($1::timestamptz IS NULL OR deleted_at < $1 OR (deleted_at = $1 AND id > $2))
+		ORDER BY deleted_at DESC, id
+		LIMIT $3 OFFSET $4`,
+		after, afterID, page.Limit, page.Skip(),
+	)
+}
+
+// CountDeleted counts the users in the trash
+func (r *UserRepository) CountDeleted(ctx context.Context) (int64, error) {
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM users WHERE deleted_at IS NOT NULL`,
+	).Scan(&count)
+
+	return count, err
+}
+
+// Restore moves a user out of the trash
+func (r *UserRepository) Restore(ctx context.Context, id uint64) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result, domain.NewError(domain.ErrNotFound, "user not found in trash"))
+}
+
+// Purge permanently removes users moved to the trash before the given time.
+// Users still referenced by tasks are kept until those tasks are purged.
+func (r *UserRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM users
+		WHERE deleted_at < $1
+			AND NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.user_id = users.id)`,
+		before,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// query runs a query returning user rows
+func (r *UserRepository) query(ctx context.Context, query string, args ...any) ([]*entity.User, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*entity.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// scanUser scans a single users row
+func scanUser(row scanner) (*entity.User, error) {
+	var user entity.User
+	var recoveryCodes string
+	var emailVerifiedAt, deletedAt sql.NullTime
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.Password,
+		&user.FirstName,
+		&user.LastName,
+		&user.Role,
+		&emailVerifiedAt,
+		&user.TOTPSecret,
+		&user.TOTPEnabled,
+		&user.TOTPLastStep,
+		&recoveryCodes,
+		&user.TokenVersion,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&deletedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NewError(domain.ErrNotFound, "user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user.RecoveryCodes = strings.Fields(recoveryCodes)
+
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
+	}
+
+	return &user, nil
+}
+
+// userError translates constraint violations into the errors the memory repository returns
+func userError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch {
+	case pqErr.Code == uniqueViolation && pqErr.Constraint == "users_email_key":
+		return domain.NewError(domain.ErrConflict, "email already exists")
+	case pqErr.Code == uniqueViolation && pqErr.Constraint == "users_username_key":
+		return domain.NewError(domain.ErrConflict, "username already exists")
+	case pqErr.Code == foreignKeyViolation:
+		return repository.ErrUserHasTasks
+	}
+
+	return err
+}
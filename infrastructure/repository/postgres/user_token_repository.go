@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure UserTokenRepository implements repository.UserTokenRepository
+var _ repository.UserTokenRepository = (*UserTokenRepository)(nil)
+
+const userTokenColumns = `id, user_id, purpose, token_hash, email, created_at, expires_at, used_at`
+
+// UserTokenRepository is a PostgreSQL implementation of repository.UserTokenRepository
+type UserTokenRepository struct {
+	db *sql.DB
+}
+
+// NewUserTokenRepository creates a new PostgreSQL user token repository
+func NewUserTokenRepository(db *sql.DB) *UserTokenRepository {
+	return &UserTokenRepository{
+		db: db,
+	}
+}
+
+// GetByTokenHash retrieves a token by the hash of its secret
+func (r *UserTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*entity.UserToken, error) {
+	row := executor(ctx, r.db).QueryRowContext(ctx, `SELECT `+userTokenColumns+` FROM user_tokens WHERE token_hash = $1`, tokenHash)
+	return scanUserToken(row)
+}
+
+// Create creates a new token
+func (r *UserTokenRepository) Create(ctx context.Context, token *entity.UserToken) error {
+	return executor(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, email, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		token.UserID,
+		token.Purpose,
+		token.TokenHash,
+		token.Email,
+		token.CreatedAt,
+		token.ExpiresAt,
+	).Scan(&token.ID)
+}
+
+// MarkUsed records that a token was used
+func (r *UserTokenRepository) MarkUsed(ctx context.Context, id uint64, at time.Time) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE user_tokens SET used_at = $2 WHERE id = $1 AND used_at IS NULL`,
+		id, at,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result, domain.NewError(domain.ErrNotFound, "user token not found"))
+}
+
+// DeleteByUserID removes every token of a user issued for purpose
+func (r *UserTokenRepository) DeleteByUserID(ctx context.Context, userID uint64, purpose entity.TokenPurpose) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2`,
+		userID, purpose,
+	)
+
+	return err
+}
+
+// Purge removes tokens that were used or expired before now
+func (r *UserTokenRepository) Purge(ctx context.Context, now time.Time) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM user_tokens WHERE used_at IS NOT NULL OR expires_at < $1`,
+		now,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// scanUserToken scans a single user_tokens row
+func scanUserToken(row scanner) (*entity.UserToken, error) {
+	var token entity.UserToken
+	var usedAt sql.NullTime
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Purpose,
+		&token.TokenHash,
+		&token.Email,
+		&token.CreatedAt,
+		&token.ExpiresAt,
+		&usedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NewError(domain.ErrNotFound, "user token not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+
+	return &token, nil
+}
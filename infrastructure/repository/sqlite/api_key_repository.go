@@ -0,0 +1,193 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure APIKeyRepository implements repository.APIKeyRepository
+var _ repository.APIKeyRepository = (*APIKeyRepository)(nil)
+
+const apiKeyColumns = `id, user_id, name, prefix, hash, scopes, expires_at, last_used_at, created_at, revoked_at`
+
+// APIKeyRepository is a SQLite implementation of repository.APIKeyRepository
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository creates a new SQLite API key repository
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{
+		db: db,
+	}
+}
+
+// GetByID retrieves an API key by its ID
+func (r *APIKeyRepository) GetByID(ctx context.Context, id uint64) (*entity.APIKey, error) {
+	row := executor(ctx, r.db).QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id)
+	return scanAPIKey(row)
+}
+
+// GetByPrefix retrieves an API key by its prefix
+func (r *APIKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*entity.APIKey, error) {
+	row := executor(ctx, r.db).QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = ?`, prefix)
+	return scanAPIKey(row)
+}
+
+// ListByUserID retrieves every API key owned by a user, newest first
+func (r *APIKeyRepository) ListByUserID(ctx context.Context, userID uint64) ([]*entity.APIKey, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE user_id = ?
+		ORDER BY id DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]*entity.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Create creates a new API key
+func (r *APIKeyRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO api_keys (user_id, name, prefix, hash, scopes, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		joinScopes(key.Scopes),
+		formatNullTime(key.ExpiresAt),
+		formatTime(key.CreatedAt),
+	).Scan(&key.ID)
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == constraintUnique {
+		return domain.NewError(domain.ErrConflict, "api key prefix already exists")
+	}
+
+	return err
+}
+
+// Revoke marks an API key as revoked
+func (r *APIKeyRepository) Revoke(ctx context.Context, id uint64, at time.Time) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?`,
+		formatTime(at), id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result, domain.NewError(domain.ErrNotFound, "api key not found"))
+}
+
+// RevokeByUserID marks every API key of a user not revoked yet as revoked
+func (r *APIKeyRepository) RevokeByUserID(ctx context.Context, userID uint64, at time.Time) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`,
+		formatTime(at), userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// MarkUsed records the time an API key was last used
+func (r *APIKeyRepository) MarkUsed(ctx context.Context, id uint64, at time.Time) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = ? WHERE id = ?`,
+		formatTime(at), id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result, domain.NewError(domain.ErrNotFound, "api key not found"))
+}
+
+// scanAPIKey scans a single api_keys row
+func scanAPIKey(row scanner) (*entity.APIKey, error) {
+	var key entity.APIKey
+	var scopes, createdAt string
+	var expiresAt, lastUsedAt, revokedAt sql.NullString
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		&scopes,
+		&expiresAt,
+		&lastUsedAt,
+		&createdAt,
+		&revokedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NewError(domain.ErrNotFound, "api key not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = splitScopes(scopes)
+	if key.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if key.ExpiresAt, err = parseNullTime(expiresAt); err != nil {
+		return nil, err
+	}
+	if key.LastUsedAt, err = parseNullTime(lastUsedAt); err != nil {
+		return nil, err
+	}
+	if key.RevokedAt, err = parseNullTime(revokedAt); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// joinScopes converts API key scopes to their stored space-separated representation
+func joinScopes(scopes []entity.APIKeyScope) string {
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, string(scope))
+	}
+
+	return strings.Join(names, " ")
+}
+
+// splitScopes converts stored space-separated scopes back to API key scopes
+func splitScopes(value string) []entity.APIKeyScope {
+	scopes := make([]entity.APIKeyScope, 0)
+	for _, name := range strings.Fields(value) {
+		scopes = append(scopes, entity.APIKeyScope(name))
+	}
+
+	return scopes
+}
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure AuditRepository implements repository.AuditRepository
+var _ repository.AuditRepository = (*AuditRepository)(nil)
+
+const auditColumns = `id, action, actor_id, user_id, email, ip_address, detail, created_at`
+
+// AuditRepository is a SQLite implementation of repository.AuditRepository
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository creates a new SQLite audit repository
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{
+		db: db,
+	}
+}
+
+// Create appends an entry to the audit log
+func (r *AuditRepository) Create(ctx context.Context, entry *entity.AuditEntry) error {
+	return executor(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO audit_log (action, actor_id, user_id, email, ip_address, detail, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		entry.Action,
+		entry.ActorID,
+		entry.UserID,
+		entry.Email,
+		entry.IPAddress,
+		entry.Detail,
+		formatTime(entry.CreatedAt),
+	).Scan(&entry.ID)
+}
+
+// List retrieves a page of audit entries, newest first
+func (r *AuditRepository) List(ctx context.Context, page repository.Page) ([]*entity.AuditEntry, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, `
+		SELECT `+auditColumns+`
+		FROM audit_log
+		WHERE ? = 0 OR id < ?
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?`,
+		page.AfterID(), page.AfterID(), page.Limit, page.Skip(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*entity.AuditEntry, 0)
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// Count counts the audit entries
+func (r *AuditRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log`).Scan(&count)
+
+	return count, err
+}
+
+// scanAuditEntry scans a single audit_log row
+func scanAuditEntry(row scanner) (*entity.AuditEntry, error) {
+	var entry entity.AuditEntry
+	var actorID, userID sql.NullInt64
+	var createdAt string
+	err := row.Scan(
+		&entry.ID,
+		&entry.Action,
+		&actorID,
+		&userID,
+		&entry.Email,
+		&entry.IPAddress,
+		&entry.Detail,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+
+	entry.ActorID = nullID(actorID)
+	entry.UserID = nullID(userID)
+
+	return &entry, nil
+}
+
+// nullID converts an optional stored ID back to a *uint64
+func nullID(value sql.NullInt64) *uint64 {
+	if !value.Valid {
+		return nil
+	}
+
+	id := uint64(value.Int64)
+	return &id
+}
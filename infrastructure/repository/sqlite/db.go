@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	// Register the pure Go SQLite driver with database/sql
+	_ "modernc.org/sqlite"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/config"
+)
+
+// NewDB opens the SQLite database file named in cfg.Name in WAL mode.
+// The schema is managed by the migrations package.
+func NewDB(cfg config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", DSN(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	// Configure connection pool
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	return db, nil
+}
+
+// DSN builds a SQLite connection string from the database configuration
+func DSN(cfg config.DatabaseConfig) string {
+	query := url.Values{}
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "synchronous(NORMAL)")
+	// Take the write lock up front so concurrent transactions wait instead of failing
+	query.Set("_txlock", "immediate")
+
+	return "file:" + cfg.Name + "?" + query.Encode()
+}
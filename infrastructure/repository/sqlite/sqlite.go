@@ -0,0 +1,86 @@
+// Package sqlite provides embedded SQLite implementations of the domain repositories
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// SQLite extended result codes the repositories translate
+const (
+	constraintForeignKey = 787
+	constraintUnique     = 2067
+)
+
+// timeLayout stores timestamps as fixed-width UTC text so they sort chronologically
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// versionError tells apart a missing row from a stale version after an
+// optimistic update matched no rows, using a query returning whether id exists
+func versionError(ctx context.Context, q querier, existsQuery string, id uint64, notFound error) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx, existsQuery, id).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return notFound
+	}
+
+	return repository.ErrVersionConflict
+}
+
+// requireRow returns notFound when a statement affected no rows
+func requireRow(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+// formatTime converts a timestamp to its stored representation
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// formatNullTime converts an optional timestamp to its stored representation
+func formatNullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: formatTime(*t), Valid: true}
+}
+
+// parseTime converts a stored timestamp back to a time.Time
+func parseTime(value string) (time.Time, error) {
+	return time.Parse(timeLayout, value)
+}
+
+// parseNullTime converts an optional stored timestamp back to a *time.Time
+func parseNullTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+
+	t, err := parseTime(value.String)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
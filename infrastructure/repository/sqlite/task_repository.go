@@ -0,0 +1,514 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/search"
+)
+
+// Ensure TaskRepository implements repository.TaskRepository
+var _ repository.TaskRepository = (*TaskRepository)(nil)
+
+const taskColumns = `id, title, description, status, user_id, due_date, version, created_at, updated_at, deleted_at`
+
+// TaskRepository is a SQLite implementation of repository.TaskRepository
+type TaskRepository struct {
+	db *sql.DB
+}
+
+// NewTaskRepository creates a new SQLite task repository
+func NewTaskRepository(db *sql.DB) *TaskRepository {
+	return &TaskRepository{
+		db: db,
+	}
+}
+
+// GetByID retrieves a task by its ID
+func (r *TaskRepository) GetByID(ctx context.Context, id uint64) (*entity.Task, error) {
+	row := executor(ctx, r.db).QueryRowContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = ? AND deleted_at IS NULL`, id)
+	return scanTask(row)
+}
+
+// CountByUserID counts the tasks owned by a user, excluding trashed ones
+func (r *TaskRepository) CountByUserID(ctx context.Context, userID uint64) (int64, error) {
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM tasks WHERE user_id = ? AND deleted_at IS NULL`,
+		userID,
+	).Scan(&count)
+
+	return count, err
+}
+
+// DeleteByUserID moves every task owned by a user to the trash
+func (r *TaskRepository) DeleteByUserID(ctx context.Context, userID uint64) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE tasks SET deleted_at = ? WHERE user_id = ? AND deleted_at IS NULL`,
+		formatTime(time.Now()), userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ReassignUser transfers every task owned by a user, including trashed ones, to another user
+func (r *TaskRepository) ReassignUser(ctx context.Context, fromUserID, toUserID uint64) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE tasks SET user_id = ?, updated_at = ?, version = version + 1 WHERE user_id = ?`,
+		toUserID, formatTime(time.Now()), fromUserID,
+	)
+	if err != nil {
+		return 0, taskError(err)
+	}
+
+	return result.RowsAffected()
+}
+
+// Create creates a new task
+func (r *TaskRepository) Create(ctx context.Context, task *entity.Task) error {
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO tasks (title, description, status, user_id, due_date, version, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, 1, ?, ?)
+		RETURNING id, version`,
+		task.Title,
+		task.Description,
+		task.Status,
+		task.UserID,
+		formatNullTime(task.DueDate),
+		formatTime(task.CreatedAt),
+		formatTime(task.UpdatedAt),
+	).Scan(&task.ID, &task.Version)
+
+	return taskError(err)
+}
+
+// Update updates an existing task
+func (r *TaskRepository) Update(ctx context.Context, task *entity.Task) error {
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		UPDATE tasks
+		SET title = ?, description = ?, status = ?, user_id = ?, due_date = ?, updated_at = ?,
+			version = version + 1
+		WHERE id = ? AND version = ? AND deleted_at IS NULL
+		RETURNING version`,
+		task.Title,
+		task.Description,
+		task.Status,
+		task.UserID,
+		formatNullTime(task.DueDate),
+		formatTime(task.UpdatedAt),
+		task.ID,
+		task.Version,
+	).Scan(&task.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return versionError(ctx, executor(ctx, r.db), `SELECT EXISTS (SELECT 1 FROM tasks WHERE id = ? AND deleted_at IS NULL)`, task.ID, domain.NewError(domain.ErrNotFound, "task not found"))
+	}
+
+	return taskError(err)
+}
+
+// Delete moves a task to the trash
+func (r *TaskRepository) Delete(ctx context.Context, id uint64, version uint64) error {
+	q := executor(ctx, r.db)
+	result, err := q.ExecContext(ctx, `
+		UPDATE tasks SET deleted_at = ?
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`,
+		formatTime(time.Now()), id, version, version,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+
+	return versionError(ctx, q, `SELECT EXISTS (SELECT 1 FROM tasks WHERE id = ? AND deleted_at IS NULL)`, id, domain.NewError(domain.ErrNotFound, "task not found"))
+}
+
+// List retrieves a page of the tasks matching filter, ordered by sort
+func (r *TaskRepository) List(ctx context.Context, filter repository.TaskFilter, sort repository.TaskSort, page repository.Page) ([]*entity.Task, error) {
+	q := filterTasks(filter)
+	if page.After != nil {
+		if err := q.after(sort, *page.After); err != nil {
+			return nil, err
+		}
+	}
+	limit, offset := q.arg(page.Limit), q.arg(page.Skip())
+
+	return r.query(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE `+q.clause()+`
+		ORDER BY `+taskOrder(sort)+`
+		LIMIT `+limit+` OFFSET `+offset,
+		q.args...,
+	)
+}
+
+// Count counts the tasks outside the trash matching filter
+func (r *TaskRepository) Count(ctx context.Context, filter repository.TaskFilter) (int64, error) {
+	q := filterTasks(filter)
+
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM tasks WHERE `+q.clause(),
+		q.args...,
+	).Scan(&count)
+
+	return count, err
+}
+
+// Search retrieves a page of the tasks matching filter that contain every
+// search term of query, most relevant first
+func (r *TaskRepository) Search(ctx context.Context, query string, filter repository.TaskFilter, page repository.Page) ([]*repository.TaskMatch, error) {
+	// FTS5 rejects queries without words
+	if len(search.Words(query)) == 0 {
+		return []*repository.TaskMatch{}, nil
+	}
+
+	q := filterTasks(filter)
+	from := q.matching(query)
+	if page.After != nil {
+		if err := q.afterRank(*page.After); err != nil {
+			return nil, err
+		}
+	}
+	limit, offset := q.arg(page.Limit), q.arg(page.Skip())
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, `
+		SELECT `+taskColumns+`, rank
+		FROM `+from+`
+		WHERE `+q.clause()+`
+		ORDER BY rank DESC, id
+		LIMIT `+limit+` OFFSET `+offset,
+		q.args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make([]*repository.TaskMatch, 0)
+	for rows.Next() {
+		var rank float64
+		task, err := scanTask(rows, &rank)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, &repository.TaskMatch{Task: task, Rank: rank})
+	}
+
+	return matches, rows.Err()
+}
+
+// CountSearch counts the tasks Search finds for query and filter
+func (r *TaskRepository) CountSearch(ctx context.Context, query string, filter repository.TaskFilter) (int64, error) {
+	if len(search.Words(query)) == 0 {
+		return 0, nil
+	}
+
+	q := filterTasks(filter)
+	from := q.matching(query)
+
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM `+from+` WHERE `+q.clause(),
+		q.args...,
+	).Scan(&count)
+
+	return count, err
+}
+
+// ListDeleted retrieves a page of the tasks in the trash, most recently deleted first
+func (r *TaskRepository) ListDeleted(ctx context.Context, page repository.Page) ([]*entity.Task, error) {
+	after, afterID, err := page.AfterTime()
+	if err != nil {
+		return nil, err
+	}
+	afterTime := formatNullTime(after)
+
+	return r.query(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE deleted_at IS NOT NULL
+			AND (? IS NULL OR deleted_at < ? OR (deleted_at = ? AND id > ?))
+		ORDER BY deleted_at DESC, id
+		LIMIT ? OFFSET ?`,
+		afterTime, afterTime, afterTime, afterID, page.Limit, page.Skip(),
+	)
+}
+
+// CountDeleted counts the tasks in the trash
+func (r *TaskRepository) CountDeleted(ctx context.Context) (int64, error) {
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM tasks WHERE deleted_at IS NOT NULL`,
+	).Scan(&count)
+
+	return count, err
+}
+
+// Restore moves a task out of the trash
+func (r *TaskRepository) Restore(ctx context.Context, id uint64) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE tasks SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result, domain.NewError(domain.ErrNotFound, "task not found in trash"))
+}
+
+// Purge permanently removes tasks moved to the trash before the given time
+func (r *TaskRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `DELETE FROM tasks WHERE deleted_at < ?`, formatTime(before))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// query runs a query returning task rows
+func (r *TaskRepository) query(ctx context.Context, query string, args ...any) ([]*entity.Task, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]*entity.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// taskQuery collects the conditions of a query on tasks and their arguments
+type taskQuery struct {
+	conditions []string
+	args       []any
+}
+
+// filterTasks starts a query on the tasks outside the trash matching filter
+func filterTasks(filter repository.TaskFilter) *taskQuery {
+	q := &taskQuery{conditions: []string{"deleted_at IS NULL"}}
+
+	if len(filter.Statuses) > 0 {
+		placeholders := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			placeholders[i] = q.arg(string(status))
+		}
+		q.add("status IN (" + strings.Join(placeholders, ", ") + ")")
+	}
+	if filter.UserID != 0 {
+		q.add("user_id = " + q.arg(filter.UserID))
+	}
+	if filter.DueAfter != nil {
+		q.add("due_date >= " + q.arg(formatTime(*filter.DueAfter)))
+	}
+	if filter.DueBefore != nil {
+		q.add("due_date < " + q.arg(formatTime(*filter.DueBefore)))
+	}
+	if filter.CreatedAfter != nil {
+		q.add("created_at >= " + q.arg(formatTime(*filter.CreatedAfter)))
+	}
+	if filter.CreatedBefore != nil {
+		q.add("created_at < " + q.arg(formatTime(*filter.CreatedBefore)))
+	}
+	if filter.TitleContains != "" {
+		q.add("title LIKE " + q.arg("%"+likeEscaper.Replace(filter.TitleContains)+"%") + ` ESCAPE '\'`)
+	}
+
+	return q
+}
+
+// after restricts the query to the tasks following cursor in the order of sort
+func (q *taskQuery) after(sort repository.TaskSort, cursor repository.Cursor) error {
+	id := q.arg(cursor.ID)
+	if sort.Field == "" || sort.Field == repository.TaskSortID {
+		q.add("id > " + id)
+		return nil
+	}
+
+	column := taskSortColumn(sort.Field)
+	var key string
+	if sort.Field == repository.TaskSortTitle {
+		key = q.arg(cursor.Key)
+	} else {
+		t, err := cursor.Time()
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			// Only tasks without a due date follow one without a due date
+			q.add("(" + column + " IS NULL AND id > " + id + ")")
+			return nil
+		}
+		key = q.arg(formatTime(*t))
+	}
+
+	operator := ">"
+	if sort.Descending {
+		operator = "<"
+	}
+	condition := column + " " + operator + " " + key + " OR (" + column + " = " + key + " AND id > " + id + ")"
+	if sort.Field == repository.TaskSortDueDate {
+		condition += " OR due_date IS NULL"
+	}
+	q.add("(" + condition + ")")
+
+	return nil
+}
+
+// matching makes the query select from the tasks containing every search word
+// of text, adding their rank as a column. It returns the FROM clause to use
+// instead of the tasks table.
+func (q *taskQuery) matching(text string) string {
+	// Quote the words so they cannot be read as FTS5 query syntax
+	words := search.Words(text)
+	for i, word := range words {
+		words[i] = `"` + word + `"`
+	}
+
+	return `(
+			SELECT tasks.*, -bm25(tasks_search, 2.0, 1.0) AS rank
+			FROM tasks_search
+			JOIN tasks ON tasks.id = tasks_search.rowid
+			WHERE tasks_search MATCH ` + q.arg(strings.Join(words, " ")) + `
+		) tasks`
+}
+
+// afterRank restricts the query to the search results following cursor
+func (q *taskQuery) afterRank(cursor repository.Cursor) error {
+	rank, err := cursor.Rank()
+	if err != nil {
+		return err
+	}
+
+	key, id := q.arg(rank), q.arg(cursor.ID)
+	q.add("(rank < " + key + " OR (rank = " + key + " AND id > " + id + "))")
+
+	return nil
+}
+
+// arg adds an argument to the query and returns its numbered placeholder,
+// which may appear in the query more than once
+func (q *taskQuery) arg(value any) string {
+	q.args = append(q.args, value)
+	return "?" + strconv.Itoa(len(q.args))
+}
+
+// add adds a condition to the query
+func (q *taskQuery) add(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// clause returns the conditions of the query for its WHERE clause
+func (q *taskQuery) clause() string {
+	return strings.Join(q.conditions, " AND ")
+}
+
+// taskSortColumn returns the column tasks are sorted by for a sort field
+func taskSortColumn(field repository.TaskSortField) string {
+	switch field {
+	case repository.TaskSortCreatedAt:
+		return "created_at"
+	case repository.TaskSortUpdatedAt:
+		return "updated_at"
+	case repository.TaskSortDueDate:
+		return "due_date"
+	case repository.TaskSortTitle:
+		return "title"
+	default:
+		return "id"
+	}
+}
+
+// taskOrder returns the ORDER BY clause of sort. Missing due dates come last
+// in either direction and ties are broken by ID.
+func taskOrder(sort repository.TaskSort) string {
+	if sort.Field == "" || sort.Field == repository.TaskSortID {
+		return "id"
+	}
+
+	direction := "ASC"
+	if sort.Descending {
+		direction = "DESC"
+	}
+
+	return taskSortColumn(sort.Field) + " " + direction + " NULLS LAST, id"
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// scanTask scans a single tasks row, followed by any extra columns into extra
+func scanTask(row scanner, extra ...any) (*entity.Task, error) {
+	var task entity.Task
+	var dueDate, deletedAt sql.NullString
+	var createdAt, updatedAt string
+	err := row.Scan(append([]any{
+		&task.ID,
+		&task.Title,
+		&task.Description,
+		&task.Status,
+		&task.UserID,
+		&dueDate,
+		&task.Version,
+		&createdAt,
+		&updatedAt,
+		&deletedAt,
+	}, extra...)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NewError(domain.ErrNotFound, "task not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if task.DueDate, err = parseNullTime(dueDate); err != nil {
+		return nil, err
+	}
+	if task.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if task.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, err
+	}
+	if task.DeletedAt, err = parseNullTime(deletedAt); err != nil {
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+// taskError translates constraint violations into domain errors
+func taskError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == constraintForeignKey {
+		return domain.NewError(domain.ErrNotFound, "user not found")
+	}
+
+	return err
+}
@@ -0,0 +1,326 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// Ensure UserRepository implements repository.UserRepository
+var _ repository.UserRepository = (*UserRepository)(nil)
+
+const userColumns = `id, username, email, password, first_name, last_name, role, email_verified_at,
+	totp_secret, totp_enabled, totp_last_step, recovery_codes, token_version, version, created_at, updated_at, deleted_at`
+
+// UserRepository is a SQLite implementation of repository.UserRepository
+type UserRepository struct {
+	db *sql.DB
+}
+
+// NewUserRepository creates a new SQLite user repository
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{
+		db: db,
+	}
+}
+
+// GetByID retrieves a user by their ID
+func (r *UserRepository) GetByID(ctx context.Context, id uint64) (*entity.User, error) {
+	row := executor(ctx, r.db).QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ? AND deleted_at IS NULL`, id)
+	return scanUser(row)
+}
+
+// GetByEmail retrieves a user by their email
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	row := executor(ctx, r.db).QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = ? AND deleted_at IS NULL`, email)
+	return scanUser(row)
+}
+
+// GetByUsername retrieves a user by their username
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*entity.User, error) {
+	row := executor(ctx, r.db).QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE username = ? AND deleted_at IS NULL`, username)
+	return scanUser(row)
+}
+
+// Create creates a new user
+func (r *UserRepository) Create(ctx context.Context, user *entity.User) error {
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO users (username, email, password, first_name, last_name, role, email_verified_at,
+			totp_secret, totp_enabled, totp_last_step, recovery_codes, token_version, version, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?)
+		RETURNING id, version`,
+		user.Username,
+		user.Email,
+		user.Password,
+		user.FirstName,
+		user.LastName,
+		user.Role,
+		formatNullTime(user.EmailVerifiedAt),
+		user.TOTPSecret,
+		user.TOTPEnabled,
+		user.TOTPLastStep,
+		strings.Join(user.RecoveryCodes, " "),
+		user.TokenVersion,
+		formatTime(user.CreatedAt),
+		formatTime(user.UpdatedAt),
+	).Scan(&user.ID, &user.Version)
+
+	return userError(err)
+}
+
+// Bootstrap records that the first user signed up, unless that happened before
+func (r *UserRepository) Bootstrap(ctx context.Context) (bool, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		INSERT OR IGNORE INTO bootstrap (id, created_at) VALUES (1, ?)`,
+		formatTime(time.Now()),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	return inserted == 1, err
+}
+
+// Update updates an existing user
+func (r *UserRepository) Update(ctx context.Context, user *entity.User) error {
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		UPDATE users
+		SET username = ?, email = ?, password = ?, first_name = ?, last_name = ?, role = ?, email_verified_at = ?,
+			totp_secret = ?, totp_enabled = ?, totp_last_step = ?, recovery_codes = ?, token_version = ?, updated_at = ?,
+			version = version + 1
+		WHERE id = ? AND version = ? AND deleted_at IS NULL
+		RETURNING version`,
+		user.Username,
+		user.Email,
+		user.Password,
+		user.FirstName,
+		user.LastName,
+		user.Role,
+		formatNullTime(user.EmailVerifiedAt),
+		user.TOTPSecret,
+		user.TOTPEnabled,
+		user.TOTPLastStep,
+		strings.Join(user.RecoveryCodes, " "),
+		user.TokenVersion,
+		formatTime(user.UpdatedAt),
+		user.ID,
+		user.Version,
+	).Scan(&user.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return versionError(ctx, executor(ctx, r.db), `SELECT EXISTS (SELECT 1 FROM users WHERE id = ? AND deleted_at IS NULL)`, user.ID, domain.NewError(domain.ErrNotFound, "user not found"))
+	}
+
+	return userError(err)
+}
+
+// Delete moves a user to the trash
+func (r *UserRepository) Delete(ctx context.Context, id uint64, version uint64) error {
+	q := executor(ctx, r.db)
+	result, err := q.ExecContext(ctx, `
+		UPDATE users SET deleted_at = ?
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+			AND NOT EXISTS (SELECT 1 FROM tasks WHERE user_id = users.id AND deleted_at IS NULL)`,
+		formatTime(time.Now()), id, version, version,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+
+	// Tell apart a missing user, a stale version and a user who still owns tasks
+	var stored uint64
+	err = q.QueryRowContext(ctx, `SELECT version FROM users WHERE id = ? AND deleted_at IS NULL`, id).Scan(&stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.NewError(domain.ErrNotFound, "user not found")
+	}
+	if err != nil {
+		return err
+	}
+	if version != 0 && stored != version {
+		return repository.ErrVersionConflict
+	}
+
+	return repository.ErrUserHasTasks
+}
+
+// List retrieves a page of users, ordered by ID
+func (r *UserRepository) List(ctx context.Context, page repository.Page) ([]*entity.User, error) {
+	return r.query(ctx, `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE deleted_at IS NULL AND id > ?
+		ORDER BY id
+		LIMIT ? OFFSET ?`,
+		page.AfterID(), page.Limit, page.Skip(),
+	)
+}
+
+// Count counts the users outside the trash
+func (r *UserRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`,
+	).Scan(&count)
+
+	return count, err
+}
+
+// ListDeleted retrieves a page of the users in the trash, most recently deleted first
+func (r *UserRepository) ListDeleted(ctx context.Context, page repository.Page) ([]*entity.User, error) {
+	after, afterID, err := page.AfterTime()
+	if err != nil {
+		return nil, err
+	}
+	afterTime := formatNullTime(after)
+
+	return r.query(ctx, `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE deleted_at IS NOT NULL
+			AND (? IS NULL OR deleted_at < ? OR (deleted_at = ? AND id > ?))
+		ORDER BY deleted_at DESC, id
+		LIMIT ? OFFSET ?`,
+		afterTime, afterTime, afterTime, afterID, page.Limit, page.Skip(),
+	)
+}
+
+// CountDeleted counts the users in the trash
+func (r *UserRepository) CountDeleted(ctx context.Context) (int64, error) {
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM users WHERE deleted_at IS NOT NULL`,
+	).Scan(&count)
+
+	return count, err
+}
+
+// Restore moves a user out of the trash
+func (r *UserRepository) Restore(ctx context.Context, id uint64) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE users SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result, domain.NewError(domain.ErrNotFound, "user not found in trash"))
+}
+
+// Purge permanently removes users moved to the trash before the given time.
+// Users still referenced by tasks are kept until those tasks are purged.
+func (r *UserRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM users
+		WHERE deleted_at < ?
+			AND NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.user_id = users.id)`,
+		formatTime(before),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// query runs a query returning user rows
+func (r *UserRepository) query(ctx context.Context, query string, args ...any) ([]*entity.User, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*entity.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// scanUser scans a single users row
+func scanUser(row scanner) (*entity.User, error) {
+	var user entity.User
+	var recoveryCodes string
+	var createdAt, updatedAt string
+	var emailVerifiedAt, deletedAt sql.NullString
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.Password,
+		&user.FirstName,
+		&user.LastName,
+		&user.Role,
+		&emailVerifiedAt,
+		&user.TOTPSecret,
+		&user.TOTPEnabled,
+		&user.TOTPLastStep,
+		&recoveryCodes,
+		&user.TokenVersion,
+		&user.Version,
+		&createdAt,
+		&updatedAt,
+		&deletedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NewError(domain.ErrNotFound, "user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user.RecoveryCodes = strings.Fields(recoveryCodes)
+
+	if user.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if user.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, err
+	}
+	if user.EmailVerifiedAt, err = parseNullTime(emailVerifiedAt); err != nil {
+		return nil, err
+	}
+	if user.DeletedAt, err = parseNullTime(deletedAt); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// userError translates constraint violations into the errors the memory repository returns
+func userError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch {
+	case sqliteErr.Code() == constraintUnique && strings.Contains(sqliteErr.Error(), "users.email"):
+		return domain.NewError(domain.ErrConflict, "email already exists")
+	case sqliteErr.Code() == constraintUnique && strings.Contains(sqliteErr.Error(), "users.username"):
+		return domain.NewError(domain.ErrConflict, "username already exists")
+	case sqliteErr.Code() == constraintForeignKey:
+		return repository.ErrUserHasTasks
+	}
+
+	return err
+}
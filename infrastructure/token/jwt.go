@@ -0,0 +1,218 @@
+// Package token issues and verifies signed JSON Web Tokens
+package token
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/config"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/service"
+)
+
+// Ensure JWTManager implements service.TokenManager
+var _ service.TokenManager = (*JWTManager)(nil)
+
+// minSecretLength is the shortest HS256 secret accepted, matching the hash size
+const minSecretLength = 32
+
+// claims is the JWT payload of issued tokens
+type claims struct {
+	Type        service.TokenType `json:"token_type"`
+	UserVersion uint64            `json:"user_version"`
+	jwt.RegisteredClaims
+}
+
+// JWTManager issues and verifies HS256 or RS256 signed tokens
+type JWTManager struct {
+	method     jwt.SigningMethod
+	signingKey any
+	verifyKey  any
+	issuer     string
+	ttl        map[service.TokenType]time.Duration
+
+	// ephemeral is set when no secret was configured and a random one was generated
+	ephemeral bool
+}
+
+// NewJWTManager creates a token manager from the given configuration. When
+// HS256 is used without a secret, a random one is generated; tokens signed
+// with it do not survive a restart.
+func NewJWTManager(cfg config.JWTConfig) (*JWTManager, error) {
+	m := &JWTManager{
+		issuer: cfg.Issuer,
+		ttl: map[service.TokenType]time.Duration{
+			service.TokenTypeAccess:  cfg.AccessTokenTTL,
+			service.TokenTypeRefresh: cfg.RefreshTokenTTL,
+		},
+	}
+
+	switch cfg.Algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		secret := []byte(cfg.Secret)
+		if len(secret) == 0 {
+			secret = make([]byte, minSecretLength)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, err
+			}
+			m.ephemeral = true
+		}
+		if len(secret) < minSecretLength {
+			return nil, fmt.Errorf("JWT secret must be at least %d bytes", minSecretLength)
+		}
+
+		m.method = jwt.SigningMethodHS256
+		m.signingKey = secret
+		m.verifyKey = secret
+	case jwt.SigningMethodRS256.Alg():
+		privateKey, publicKey, err := loadRSAKeys(cfg.PrivateKeyFile, cfg.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		m.method = jwt.SigningMethodRS256
+		m.signingKey = privateKey
+		m.verifyKey = publicKey
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+
+	for tokenType, ttl := range m.ttl {
+		if ttl <= 0 {
+			return nil, fmt.Errorf("%s token lifetime must be positive", tokenType)
+		}
+	}
+
+	return m, nil
+}
+
+// Ephemeral reports whether the manager signs with a generated secret
+func (m *JWTManager) Ephemeral() bool {
+	return m.ephemeral
+}
+
+// Issue signs a new token of the given type for a user whose token version is userVersion
+func (m *JWTManager) Issue(userID, userVersion uint64, tokenType service.TokenType) (string, *service.TokenClaims, error) {
+	ttl, ok := m.ttl[tokenType]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown token type %q", tokenType)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+
+	// JWT timestamps have a resolution of one second
+	now := time.Now().Truncate(time.Second)
+	issued := &service.TokenClaims{
+		ID:          hex.EncodeToString(id),
+		UserID:      userID,
+		UserVersion: userVersion,
+		Type:        tokenType,
+		IssuedAt:    now,
+		ExpiresAt:   now.Add(ttl),
+	}
+
+	token, err := jwt.NewWithClaims(m.method, claims{
+		Type:        tokenType,
+		UserVersion: userVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        issued.ID,
+			Issuer:    m.issuer,
+			Subject:   strconv.FormatUint(userID, 10),
+			IssuedAt:  jwt.NewNumericDate(issued.IssuedAt),
+			NotBefore: jwt.NewNumericDate(issued.IssuedAt),
+			ExpiresAt: jwt.NewNumericDate(issued.ExpiresAt),
+		},
+	}).SignedString(m.signingKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, issued, nil
+}
+
+// Parse verifies a token's signature, issuer, expiry and type and returns its claims
+func (m *JWTManager) Parse(token string, tokenType service.TokenType) (*service.TokenClaims, error) {
+	var parsed claims
+	_, err := jwt.ParseWithClaims(
+		token,
+		&parsed,
+		func(*jwt.Token) (any, error) { return m.verifyKey, nil },
+		jwt.WithValidMethods([]string{m.method.Alg()}),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refresh tokens must never be accepted as access tokens and vice versa
+	if parsed.Type != tokenType {
+		return nil, fmt.Errorf("expected %s token, got %q", tokenType, parsed.Type)
+	}
+
+	userID, err := strconv.ParseUint(parsed.Subject, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid token subject")
+	}
+
+	result := &service.TokenClaims{
+		ID:          parsed.ID,
+		UserID:      userID,
+		UserVersion: parsed.UserVersion,
+		Type:        parsed.Type,
+		ExpiresAt:   parsed.ExpiresAt.Time,
+	}
+	if parsed.IssuedAt != nil {
+		result.IssuedAt = parsed.IssuedAt.Time
+	}
+
+	return result, nil
+}
+
+// loadRSAKeys reads a PEM-encoded RSA key pair. The public key file may be
+// omitted, in which case it is derived from the private key.
+func loadRSAKeys(privateKeyFile, publicKeyFile string) (*rsa.PrivateKey, *rsa.PublicKey, error) {
+	if privateKeyFile == "" {
+		return nil, nil, errors.New("RS256 requires a private key file")
+	}
+
+	privatePEM, err := os.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read JWT private key: %w", err)
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse JWT private key: %w", err)
+	}
+
+	if publicKeyFile == "" {
+		return privateKey, &privateKey.PublicKey, nil
+	}
+
+	publicPEM, err := os.ReadFile(publicKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read JWT public key: %w", err)
+	}
+
+	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse JWT public key: %w", err)
+	}
+
+	if !privateKey.PublicKey.Equal(publicKey) {
+		return nil, nil, errors.New("JWT public key does not match the private key")
+	}
+
+	return privateKey, publicKey, nil
+}
@@ -0,0 +1,117 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/config"
+)
+
+// rfcSecret is the SHA-1 secret of the RFC 6238 test vectors, "12345678901234567890", in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerate(t *testing.T) {
+	// The RFC lists eight digit codes; six digit codes are their last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	key, _ := encoding.DecodeString(rfcSecret)
+	for _, tt := range tests {
+		if got := generate(key, tt.unix/30); got != tt.want {
+			t.Errorf("generate() at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / 30
+	key, _ := encoding.DecodeString(rfcSecret)
+
+	tests := []struct {
+		name     string
+		skew     int
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current code", secret: rfcSecret, code: generate(key, step), wantStep: step, wantOK: true},
+		{name: "previous code within skew", skew: 1, secret: rfcSecret, code: generate(key, step-1), wantStep: step - 1, wantOK: true},
+		{name: "next code within skew", skew: 1, secret: rfcSecret, code: generate(key, step+1), wantStep: step + 1, wantOK: true},
+		{name: "previous code without skew", secret: rfcSecret, code: generate(key, step-1)},
+		{name: "code beyond skew", skew: 1, secret: rfcSecret, code: generate(key, step-2)},
+		{name: "wrong code", skew: 1, secret: rfcSecret, code: "000000"},
+		{name: "too short", secret: rfcSecret, code: generate(key, step)[:5]},
+		{name: "too long", secret: rfcSecret, code: generate(key, step) + "0"},
+		{name: "invalid secret", secret: "not base32!", code: generate(key, step)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewGenerator(config.TOTPConfig{Issuer: "Test", Skew: tt.skew})
+			if err != nil {
+				t.Fatalf("NewGenerator() error = %v", err)
+			}
+
+			gotStep, gotOK := g.Validate(tt.secret, tt.code, now)
+			if gotOK != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("Validate() = (%d, %v), want (%d, %v)", gotStep, gotOK, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewGeneratorRejectsNegativeSkew(t *testing.T) {
+	if _, err := NewGenerator(config.TOTPConfig{Skew: -1}); err == nil {
+		t.Error("NewGenerator() with a negative skew succeeded")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	g, _ := NewGenerator(config.TOTPConfig{Issuer: "Test"})
+
+	first, err := g.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	second, _ := g.GenerateSecret()
+
+	key, err := encoding.DecodeString(first)
+	if err != nil || len(key) != secretSize {
+		t.Errorf("GenerateSecret() = %q, want %d bytes of base32 (error %v)", first, secretSize, err)
+	}
+	if first == second {
+		t.Error("GenerateSecret() returned the same secret twice")
+	}
+}
+
+func TestURI(t *testing.T) {
+	g, _ := NewGenerator(config.TOTPConfig{Issuer: "Test App"})
+
+	uri, err := url.Parse(g.URI(rfcSecret, "alice@x.io"))
+	if err != nil {
+		t.Fatalf("URI() is not a URL: %v", err)
+	}
+
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Test App:alice@x.io" {
+		t.Errorf("URI() = %s, want otpauth://totp/Test App:alice@x.io", uri)
+	}
+	query := uri.Query()
+	want := map[string]string{"secret": rfcSecret, "issuer": "Test App", "algorithm": "SHA1", "digits": "6", "period": "30"}
+	for name, value := range want {
+		if got := query.Get(name); got != value {
+			t.Errorf("URI() %s = %q, want %q", name, got, value)
+		}
+	}
+}
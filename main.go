@@ -2,56 +2,126 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/dimasbagussusilo/go-clean-boilerplate/config"
 	httpDelivery "github.com/dimasbagussusilo/go-clean-boilerplate/delivery/http"
 	"github.com/dimasbagussusilo/go-clean-boilerplate/delivery/http/middleware"
-	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/repository/memory"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/mail"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/migrations"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/password"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/persistence"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/token"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/totp"
 	"github.com/dimasbagussusilo/go-clean-boilerplate/usecase"
 )
 
 func main() {
 	// Initialize logger
 	logger := log.New(os.Stdout, "[API] ", log.LstdFlags)
-	logger.Println("Starting server...")
 
 	// Initialize configuration
-	cfg := config.NewConfig()
+	cfg, err := config.NewConfig()
+	if err != nil {
+		logger.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Run schema migrations instead of the server when invoked as "migrate"
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrations(cfg.Database, os.Args[2:]); err != nil {
+			logger.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	logger.Println("Starting server...")
 
 	// Initialize repositories
-	userRepo := memory.NewUserRepository()
-	taskRepo := memory.NewTaskRepository()
+	repos, err := persistence.New(cfg.Database)
+	if err != nil {
+		logger.Fatalf("Failed to initialize %s repositories: %v", cfg.Database.Driver, err)
+	}
+	defer repos.Close()
+	logger.Printf("Using %s repositories", cfg.Database.Driver)
+
+	// Initialize services
+	passwordHasher, err := password.NewHasher(cfg.Password)
+	if err != nil {
+		logger.Fatalf("Failed to initialize password hasher: %v", err)
+	}
+
+	tokenManager, err := token.NewJWTManager(cfg.JWT)
+	if err != nil {
+		logger.Fatalf("Failed to initialize token manager: %v", err)
+	}
+	if tokenManager.Ephemeral() {
+		logger.Println("JWT_SECRET is not set; using a random secret, so tokens will not survive a restart")
+	}
+
+	totpGenerator, err := totp.NewGenerator(cfg.TOTP)
+	if err != nil {
+		logger.Fatalf("Failed to initialize TOTP generator: %v", err)
+	}
+
+	mailer, err := mail.New(cfg.Mail, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize mailer: %v", err)
+	}
 
 	// Initialize use cases
-	userUseCase := usecase.NewUserUseCase(userRepo)
-	taskUseCase := usecase.NewTaskUseCase(taskRepo, userRepo)
+	loginGuard := usecase.NewLoginGuard(repos.LoginAttempts, repos.Audit, usecase.LoginLimits{
+		MaxAttempts:     cfg.Login.MaxAttempts,
+		MaxIPAttempts:   cfg.Login.MaxIPAttempts,
+		Backoff:         cfg.Login.Backoff,
+		MaxBackoff:      cfg.Login.MaxBackoff,
+		LockoutDuration: cfg.Login.LockoutDuration,
+	})
+	userUseCase := usecase.NewUserUseCase(repos.Users, repos.Tasks, repos.Sessions, repos.APIKeys, repos.Transactor, passwordHasher, totpGenerator, loginGuard)
+	taskUseCase := usecase.NewTaskUseCase(repos.Tasks, repos.Users, repos.Transactor)
+	apiKeyUseCase := usecase.NewAPIKeyUseCase(repos.APIKeys, repos.Users)
+	authUseCase := usecase.NewAuthUseCase(userUseCase, repos.Users, apiKeyUseCase, tokenManager)
+	sessionUseCase := usecase.NewSessionUseCase(repos.Sessions, repos.Users, userUseCase, cfg.Session.IdleTimeout, cfg.Session.AbsoluteTimeout)
+	accountUseCase := usecase.NewAccountUseCase(repos.UserTokens, repos.Users, userUseCase, repos.Transactor, mailer, cfg.Account.BaseURL, cfg.Account.EmailVerificationTTL, cfg.Account.PasswordResetTTL)
+	purgeUseCase := usecase.NewPurgeUseCase(repos.Tasks, repos.Users, cfg.Trash.Retention)
+	auditUseCase := usecase.NewAuditUseCase(repos.Audit)
 
 	// Initialize HTTP handlers
-	userHandler := httpDelivery.NewUserHandler(userUseCase)
-	taskHandler := httpDelivery.NewTaskHandler(taskUseCase)
+	sessionCookie, err := newSessionCookie(cfg.Session)
+	if err != nil {
+		logger.Fatalf("Invalid session configuration: %v", err)
+	}
 
-	// Create router
-	mux := http.NewServeMux()
+	pagination, err := newPagination(cfg.Pagination)
+	if err != nil {
+		logger.Fatalf("Failed to initialize pagination: %v", err)
+	}
+	if cfg.Pagination.CursorSecret == "" {
+		logger.Println("PAGINATION_CURSOR_SECRET is not set; using a random secret, so cursors will not survive a restart")
+	}
 
-	// Register routes
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, err := w.Write([]byte("OK"))
-		if err != nil {
-			return
-		}
+	router := httpDelivery.NewRouter(httpDelivery.Handlers{
+		Users:    httpDelivery.NewUserHandler(userUseCase, accountUseCase, authUseCase, pagination),
+		Tasks:    httpDelivery.NewTaskHandler(taskUseCase, pagination),
+		Auth:     httpDelivery.NewAuthHandler(authUseCase),
+		APIKeys:  httpDelivery.NewAPIKeyHandler(apiKeyUseCase),
+		Sessions: httpDelivery.NewSessionHandler(sessionUseCase, sessionCookie),
+		TOTP:     httpDelivery.NewTOTPHandler(userUseCase),
+		Accounts: httpDelivery.NewAccountHandler(accountUseCase),
+		Audit:    httpDelivery.NewAuditHandler(auditUseCase, pagination),
 	})
-	userHandler.RegisterRoutes(mux)
-	taskHandler.RegisterRoutes(mux)
 
 	// Apply middleware
-	handler := middleware.Logger(logger)(mux)
+	handler := middleware.Authenticate(authUseCase, router.IsPublicRoute)(router)
+	handler = middleware.Session(sessionUseCase, sessionCookie.Name, router.RequiresCSRFToken)(handler)
+	handler = middleware.Logger(logger)(handler)
 	handler = middleware.ErrorHandler(logger)(handler)
 
 	// Configure server
@@ -63,6 +133,11 @@ func main() {
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
+	// Start the trash, session, token and login attempt purge job
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go runPurgeJob(jobCtx, purgeUseCase, sessionUseCase, accountUseCase, loginGuard, cfg.Trash.PurgeInterval, logger)
+
 	// Start a server in a goroutine
 	go func() {
 		logger.Printf("Server listening on port %s", cfg.Server.Port)
@@ -77,6 +152,7 @@ func main() {
 	<-quit
 
 	logger.Println("Shutting down server...")
+	stopJobs()
 
 	// Implement proper shutdown with context timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -88,3 +164,94 @@ func main() {
 
 	logger.Println("Server stopped")
 }
+
+// runPurgeJob periodically removes trashed records past their retention period,
+// expired sessions, used or expired account tokens and expired failed login
+// counters until ctx is cancelled
+func runPurgeJob(ctx context.Context, purgeUseCase *usecase.PurgeUseCase, sessionUseCase *usecase.SessionUseCase, accountUseCase *usecase.AccountUseCase, loginGuard *usecase.LoginGuard, interval time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := sessionUseCase.Purge(ctx); err != nil {
+				logger.Printf("Session purge failed: %v", err)
+			}
+
+			if _, err := accountUseCase.Purge(ctx); err != nil {
+				logger.Printf("Account token purge failed: %v", err)
+			}
+
+			if _, err := loginGuard.Purge(ctx); err != nil {
+				logger.Printf("Login attempt purge failed: %v", err)
+			}
+
+			tasks, users, err := purgeUseCase.Purge(ctx)
+			if err != nil {
+				logger.Printf("Trash purge failed: %v", err)
+				continue
+			}
+			if tasks > 0 || users > 0 {
+				logger.Printf("Purged %d tasks and %d users from the trash", tasks, users)
+			}
+		}
+	}
+}
+
+// newSessionCookie builds the template of the session cookie from the session configuration
+func newSessionCookie(cfg config.SessionConfig) (http.Cookie, error) {
+	cookie := http.Cookie{
+		Name:     cfg.CookieName,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   cfg.CookieSecure,
+	}
+
+	switch strings.ToLower(cfg.CookieSameSite) {
+	case "strict":
+		cookie.SameSite = http.SameSiteStrictMode
+	case "lax":
+		cookie.SameSite = http.SameSiteLaxMode
+	default:
+		return http.Cookie{}, fmt.Errorf("unsupported SameSite mode %q", cfg.CookieSameSite)
+	}
+
+	return cookie, nil
+}
+
+// newPagination builds the pagination settings of list endpoints from the
+// pagination configuration, generating a cursor secret if none is configured
+func newPagination(cfg config.PaginationConfig) (httpDelivery.Pagination, error) {
+	secret := []byte(cfg.CursorSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return httpDelivery.Pagination{}, err
+		}
+	}
+
+	return httpDelivery.Pagination{
+		DefaultLimit: cfg.DefaultLimit,
+		MaxLimit:     cfg.MaxLimit,
+		CursorSecret: secret,
+	}, nil
+}
+
+// runMigrations executes the migrate subcommand against the configured database
+func runMigrations(cfg config.DatabaseConfig, args []string) error {
+	db, err := persistence.OpenDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrations.New(db, cfg.Driver)
+	if err != nil {
+		return err
+	}
+
+	return migrations.Run(context.Background(), migrator, args, os.Stdout)
+}
@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/service"
+)
+
+// AccountUseCase represents the email verification and password reset use case
+type AccountUseCase struct {
+	tokenRepo       repository.UserTokenRepository
+	userRepo        repository.UserRepository
+	userUseCase     *UserUseCase
+	transactor      repository.Transactor
+	mailer          service.Mailer
+	baseURL         string
+	verificationTTL time.Duration
+	resetTTL        time.Duration
+}
+
+// NewAccountUseCase creates a new account use case. Emailed links point to
+// baseURL and stay valid for verificationTTL or resetTTL.
+func NewAccountUseCase(tokenRepo repository.UserTokenRepository, userRepo repository.UserRepository, userUseCase *UserUseCase, transactor repository.Transactor, mailer service.Mailer, baseURL string, verificationTTL, resetTTL time.Duration) *AccountUseCase {
+	return &AccountUseCase{
+		tokenRepo:       tokenRepo,
+		userRepo:        userRepo,
+		userUseCase:     userUseCase,
+		transactor:      transactor,
+		mailer:          mailer,
+		baseURL:         baseURL,
+		verificationTTL: verificationTTL,
+		resetTTL:        resetTTL,
+	}
+}
+
+// RequestVerification emails the authenticated user a link to verify their email address
+func (uc *AccountUseCase) RequestVerification(ctx context.Context) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, principal.UserID)
+	if err != nil {
+		return err
+	}
+
+	return uc.SendVerification(ctx, user)
+}
+
+// SendVerification emails a user a link to verify their email address, for
+// example right after they signed up. Links sent earlier stop working.
+func (uc *AccountUseCase) SendVerification(ctx context.Context, user *entity.User) error {
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailVerified
+	}
+
+	token, expiresAt, err := uc.issue(ctx, user, entity.TokenPurposeEmailVerification, uc.verificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return uc.mailer.Send(ctx, service.Mail{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"please confirm your email address by opening this link:\n\n%s\n\n"+
+			"The link expires on %s. If you did not sign up, you can ignore this email.\n",
+			user.Username, uc.link("/verify-email", token), expiresAt.UTC().Format(time.RFC1123)),
+	})
+}
+
+// VerifyEmail marks the email address a verification token was sent to as
+// verified. Unknown, used and expired tokens are reported as ErrInvalidToken,
+// as are tokens sent to an address the user has since changed.
+func (uc *AccountUseCase) VerifyEmail(ctx context.Context, token string) error {
+	return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := uc.consume(ctx, token, entity.TokenPurposeEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		user.UpdatedAt = now
+
+		return uc.userRepo.Update(ctx, user)
+	})
+}
+
+// RequestPasswordReset emails a link to choose a new password to the user with
+// the given email. It succeeds whether or not there is such a user, so callers
+// cannot use it to find out which addresses have an account.
+func (uc *AccountUseCase) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := uc.userRepo.GetByEmail(ctx, email)
+	if errors.Is(err, domain.ErrNotFound) {
+		// Unknown addresses are not reported
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, expiresAt, err := uc.issue(ctx, user, entity.TokenPurposePasswordReset, uc.resetTTL)
+	if err != nil {
+		return err
+	}
+
+	return uc.mailer.Send(ctx, service.Mail{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"someone asked to reset the password of your account. Choose a new password by opening this link:\n\n%s\n\n"+
+			"The link expires on %s. If you did not ask for it, you can ignore this email.\n",
+			user.Username, uc.link("/reset-password", token), expiresAt.UTC().Format(time.RFC1123)),
+	})
+}
+
+// ResetPassword sets a new password for the user a password reset token was
+// sent to and signs them out everywhere: every session ends, and the access and
+// refresh tokens and API keys issued to them stop working. Invalid tokens are
+// reported as ErrInvalidToken. Since the token arrived by email, it also
+// verifies the address.
+func (uc *AccountUseCase) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if err := entity.ValidatePassword("new_password", newPassword); err != nil {
+		return err
+	}
+
+	return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := uc.consume(ctx, token, entity.TokenPurposePasswordReset)
+		if err != nil {
+			return err
+		}
+
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+
+		return uc.userUseCase.replacePassword(ctx, user, newPassword, "")
+	})
+}
+
+// Purge removes used and expired tokens from the store
+func (uc *AccountUseCase) Purge(ctx context.Context) (int64, error) {
+	return uc.tokenRepo.Purge(ctx, time.Now())
+}
+
+// issue stores a new token for user, replacing any earlier token with the same
+// purpose, and returns the secret to mail together with its expiry
+func (uc *AccountUseCase) issue(ctx context.Context, user *entity.User, purpose entity.TokenPurpose, lifetime time.Duration) (string, time.Time, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	token := entity.NewUserToken(user.ID, purpose, user.Email, lifetime)
+	token.TokenHash = hashSecret(secret)
+
+	err = uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.tokenRepo.DeleteByUserID(ctx, user.ID, purpose); err != nil {
+			return err
+		}
+
+		return uc.tokenRepo.Create(ctx, token)
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return secret, token.ExpiresAt, nil
+}
+
+// consume marks a token as used and returns the user it was sent to, or
+// ErrInvalidToken if it cannot be used for purpose
+func (uc *AccountUseCase) consume(ctx context.Context, secret string, purpose entity.TokenPurpose) (*entity.User, error) {
+	token, err := uc.tokenRepo.GetByTokenHash(ctx, hashSecret(secret))
+	if err != nil || token.Purpose != purpose || !token.Active(time.Now()) {
+		return nil, ErrInvalidToken
+	}
+
+	// Only one request can use a token, even if several race for it
+	if err := uc.tokenRepo.MarkUsed(ctx, token.ID, time.Now()); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, token.UserID)
+	if err != nil || user.Email != token.Email {
+		return nil, ErrInvalidToken
+	}
+
+	return user, nil
+}
+
+// link returns the URL under baseURL that a token is mailed in
+func (uc *AccountUseCase) link(path, token string) string {
+	return uc.baseURL + path + "?token=" + url.QueryEscape(token)
+}
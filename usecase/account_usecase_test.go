@@ -0,0 +1,226 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/config"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/service"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/repository/memory"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/token"
+)
+
+// mailbox is a mailer keeping the messages sent
+type mailbox struct {
+	mu    sync.Mutex
+	mails []service.Mail
+}
+
+func (m *mailbox) Send(_ context.Context, mail service.Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.mails = append(m.mails, mail)
+	return nil
+}
+
+// linkToken returns the token of the link in the last message sent
+func (m *mailbox) linkToken(t *testing.T) string {
+	t.Helper()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.mails) == 0 {
+		t.Fatal("no mail was sent")
+	}
+	match := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(m.mails[len(m.mails)-1].Body)
+	if match == nil {
+		t.Fatal("mail holds no link with a token")
+	}
+
+	return match[1]
+}
+
+// fakeTOTP accepts validTOTPCode as the code of the current step
+type fakeTOTP struct {
+	step int64
+}
+
+// validTOTPCode is the code fakeTOTP accepts
+const validTOTPCode = "123456"
+
+func (f *fakeTOTP) GenerateSecret() (string, error) {
+	return "SECRET", nil
+}
+
+func (f *fakeTOTP) URI(secret, accountName string) string {
+	return "otpauth://totp/Test:" + accountName + "?secret=" + secret
+}
+
+func (f *fakeTOTP) Validate(_, code string, _ time.Time) (int64, bool) {
+	return f.step, code == validTOTPCode
+}
+
+// credentials are the ways a user signed in before their password changed
+type credentials struct {
+	tokens       *AuthTokens
+	apiKey       string
+	sessionToken string
+}
+
+// testAccounts wires the account use cases to in-memory repositories
+type testAccounts struct {
+	users    *UserUseCase
+	auth     *AuthUseCase
+	sessions *SessionUseCase
+	apiKeys  *APIKeyUseCase
+	accounts *AccountUseCase
+	mailbox  *mailbox
+	totp     *fakeTOTP
+	user     *entity.User
+}
+
+func newTestAccounts(t *testing.T) *testAccounts {
+	t.Helper()
+
+	tasks := memory.NewTaskRepository()
+	users := memory.NewUserRepository(tasks)
+	sessions := memory.NewSessionRepository()
+	apiKeys := memory.NewAPIKeyRepository()
+	userTokens := memory.NewUserTokenRepository()
+	transactor := memory.NewTransactor(users, tasks, sessions, apiKeys, userTokens)
+
+	tokenManager, err := token.NewJWTManager(config.JWTConfig{
+		Algorithm:       "HS256",
+		Issuer:          "test",
+		AccessTokenTTL:  time.Hour,
+		RefreshTokenTTL: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("creating token manager: %v", err)
+	}
+
+	guard, _, _ := newTestLoginGuard(LoginLimits{MaxAttempts: 5, MaxIPAttempts: 100, LockoutDuration: time.Hour})
+	a := &testAccounts{mailbox: &mailbox{}, totp: &fakeTOTP{step: 1}}
+	a.users = NewUserUseCase(users, tasks, sessions, apiKeys, transactor, slowHasher{}, a.totp, guard)
+	a.apiKeys = NewAPIKeyUseCase(apiKeys, users)
+	a.auth = NewAuthUseCase(a.users, users, a.apiKeys, tokenManager)
+	a.sessions = NewSessionUseCase(sessions, users, a.users, time.Hour, time.Hour)
+	a.accounts = NewAccountUseCase(userTokens, users, a.users, transactor, a.mailbox, "http://localhost", time.Hour, time.Hour)
+
+	a.user = entity.NewUser("alice", "alice@x.io", "hash:password1", "Alice", "")
+	if err := users.Create(context.Background(), a.user); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	return a
+}
+
+// signIn signs the user in with a token pair, an API key and a cookie session
+func (a *testAccounts) signIn(t *testing.T, ctx context.Context) *credentials {
+	t.Helper()
+
+	var c credentials
+	var err error
+	if c.tokens, err = a.auth.Login(ctx, "alice@x.io", "password1", "", "192.0.2.1"); err != nil {
+		t.Fatalf("logging in: %v", err)
+	}
+
+	principal, err := a.auth.Authenticate(ctx, c.tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("authenticating access token: %v", err)
+	}
+	if _, c.apiKey, err = a.apiKeys.Create(WithPrincipal(ctx, principal), "ci", []entity.APIKeyScope{entity.APIKeyScopeTasksRead}, nil); err != nil {
+		t.Fatalf("creating API key: %v", err)
+	}
+
+	if _, c.sessionToken, err = a.sessions.Login(ctx, "alice@x.io", "password1", "", "test", "192.0.2.1"); err != nil {
+		t.Fatalf("starting session: %v", err)
+	}
+
+	return &c
+}
+
+// checkSignedOut fails the test if any of c still works
+func (a *testAccounts) checkSignedOut(t *testing.T, ctx context.Context, c *credentials) {
+	t.Helper()
+
+	if _, err := a.auth.Authenticate(ctx, c.tokens.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("access token error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := a.auth.Refresh(ctx, c.tokens.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("refresh token error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := a.auth.Authenticate(ctx, c.apiKey); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("API key error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, _, err := a.sessions.Authenticate(ctx, c.sessionToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("session error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestResetPasswordSignsOutEverywhere(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAccounts(t)
+	before := a.signIn(t, ctx)
+
+	if err := a.accounts.RequestPasswordReset(ctx, "alice@x.io"); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	if err := a.accounts.ResetPassword(ctx, a.mailbox.linkToken(t), "password2"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+
+	a.checkSignedOut(t, ctx, before)
+
+	// Signing in with the new password works as before
+	tokens, err := a.auth.Login(ctx, "alice@x.io", "password2", "", "192.0.2.1")
+	if err != nil {
+		t.Fatalf("logging in with the new password: %v", err)
+	}
+	if _, err := a.auth.Refresh(ctx, tokens.RefreshToken); err != nil {
+		t.Errorf("refreshing a token issued after the reset: %v", err)
+	}
+}
+
+// errUnavailable is the error of unavailableUsers
+var errUnavailable = errors.New("database unavailable")
+
+// unavailableUsers is a user repository whose lookups by email fail
+type unavailableUsers struct {
+	repository.UserRepository
+}
+
+func (unavailableUsers) GetByEmail(context.Context, string) (*entity.User, error) {
+	return nil, errUnavailable
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAccounts(t)
+
+	if err := a.accounts.RequestPasswordReset(ctx, "nobody@x.io"); err != nil {
+		t.Errorf("RequestPasswordReset() for an unknown address error = %v, want nil", err)
+	}
+	if len(a.mailbox.mails) != 0 {
+		t.Errorf("%d mails were sent to an unknown address", len(a.mailbox.mails))
+	}
+
+	if err := a.accounts.RequestPasswordReset(ctx, "alice@x.io"); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	a.mailbox.linkToken(t)
+
+	// Failures other than a missing account are not hidden
+	accounts := NewAccountUseCase(nil, unavailableUsers{}, a.users, nil, a.mailbox, "http://localhost", time.Hour, time.Hour)
+	if err := accounts.RequestPasswordReset(ctx, "alice@x.io"); !errors.Is(err, errUnavailable) {
+		t.Errorf("RequestPasswordReset() with a failing repository error = %v, want %v", err, errUnavailable)
+	}
+}
@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// APIKeyPrefix starts every API key, telling them apart from access tokens
+const APIKeyPrefix = "gcb_"
+
+// apiKeyLastUsedPrecision is how stale the recorded last use of a key may get,
+// so busy keys do not cause a write on every request
+const apiKeyLastUsedPrecision = time.Minute
+
+// APIKeyUseCase represents the API key use case
+type APIKeyUseCase struct {
+	keyRepo  repository.APIKeyRepository
+	userRepo repository.UserRepository
+}
+
+// NewAPIKeyUseCase creates a new API key use case
+func NewAPIKeyUseCase(keyRepo repository.APIKeyRepository, userRepo repository.UserRepository) *APIKeyUseCase {
+	return &APIKeyUseCase{
+		keyRepo:  keyRepo,
+		userRepo: userRepo,
+	}
+}
+
+// Create issues a new API key for the authenticated user and returns it together
+// with the plaintext key, which cannot be retrieved again
+func (uc *APIKeyUseCase) Create(ctx context.Context, name string, scopes []entity.APIKeyScope, expiresAt *time.Time) (*entity.APIKey, string, error) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return nil, "", ErrUnauthenticated
+	}
+
+	if err := authorize(ctx, entity.PermissionUserAPIKeys, principal.UserID); err != nil {
+		return nil, "", err
+	}
+
+	// Create API key entity
+	key := entity.NewAPIKey(principal.UserID, name, scopes, expiresAt)
+
+	// Validate API key
+	if err := key.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	prefix, secret, err := generateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	key.Prefix = prefix
+	key.Hash = hashSecret(secret)
+
+	if err := uc.keyRepo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+// List retrieves every API key owned by a user
+func (uc *APIKeyUseCase) List(ctx context.Context, userID uint64) ([]*entity.APIKey, error) {
+	if err := authorize(ctx, entity.PermissionUserAPIKeys, userID); err != nil {
+		return nil, err
+	}
+
+	return uc.keyRepo.ListByUserID(ctx, userID)
+}
+
+// Revoke permanently disables an API key
+func (uc *APIKeyUseCase) Revoke(ctx context.Context, id uint64) (*entity.APIKey, error) {
+	key, err := uc.keyRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, entity.PermissionUserAPIKeys, key.UserID); err != nil {
+		return nil, err
+	}
+
+	if err := uc.keyRepo.Revoke(ctx, id, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return uc.keyRepo.GetByID(ctx, id)
+}
+
+// Authenticate resolves the principal an API key was issued to. The principal
+// keeps the owner's role, restricted to the scopes of the key.
+func (uc *APIKeyUseCase) Authenticate(ctx context.Context, secret string) (*entity.Principal, error) {
+	prefix, ok := apiKeyPrefix(secret)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := uc.keyRepo.GetByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashSecret(secret))) != 1 || !key.Active(now) {
+		return nil, ErrInvalidToken
+	}
+
+	// Keys of users deleted since they were issued stop working
+	user, err := uc.userRepo.GetByID(ctx, key.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyLastUsedPrecision {
+		if err := uc.keyRepo.MarkUsed(ctx, key.ID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return &entity.Principal{
+		UserID:   user.ID,
+		Role:     user.Role,
+		APIKeyID: key.ID,
+		Scopes:   key.Scopes,
+	}, nil
+}
+
+// IsAPIKey reports whether a credential looks like an API key rather than an access token
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
+
+// generateAPIKey returns a new random API key and the prefix identifying it.
+// Keys look like gcb_<12 hex characters>_<43 base64url characters>.
+func generateAPIKey() (prefix, secret string, err error) {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+
+	random, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	prefix = APIKeyPrefix + hex.EncodeToString(id)
+	return prefix, prefix + "_" + random, nil
+}
+
+// apiKeyPrefix extracts the identifying prefix from an API key
+func apiKeyPrefix(secret string) (string, bool) {
+	if !IsAPIKey(secret) {
+		return "", false
+	}
+
+	prefix, _, ok := strings.Cut(secret[len(APIKeyPrefix):], "_")
+	return APIKeyPrefix + prefix, ok && prefix != ""
+}
@@ -0,0 +1,40 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// AuditUseCase represents the audit log use case
+type AuditUseCase struct {
+	auditRepo repository.AuditRepository
+}
+
+// NewAuditUseCase creates a new audit log use case
+func NewAuditUseCase(auditRepo repository.AuditRepository) *AuditUseCase {
+	return &AuditUseCase{
+		auditRepo: auditRepo,
+	}
+}
+
+// List retrieves a page of audit entries, newest first, along with how many
+// there are in total
+func (uc *AuditUseCase) List(ctx context.Context, page repository.Page) ([]*entity.AuditEntry, int64, error) {
+	if err := authorizeAll(ctx, entity.PermissionAuditRead); err != nil {
+		return nil, 0, err
+	}
+
+	entries, err := uc.auditRepo.List(ctx, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := uc.auditRepo.Count(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/service"
+)
+
+// AuthTokens is the pair of tokens issued on login and refresh
+type AuthTokens struct {
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+// AuthUseCase represents the authentication use case
+type AuthUseCase struct {
+	userUseCase *UserUseCase
+	userRepo    repository.UserRepository
+	apiKeys     *APIKeyUseCase
+	tokens      service.TokenManager
+}
+
+// NewAuthUseCase creates a new authentication use case
+func NewAuthUseCase(userUseCase *UserUseCase, userRepo repository.UserRepository, apiKeys *APIKeyUseCase, tokens service.TokenManager) *AuthUseCase {
+	return &AuthUseCase{
+		userUseCase: userUseCase,
+		userRepo:    userRepo,
+		apiKeys:     apiKeys,
+		tokens:      tokens,
+	}
+}
+
+// Login verifies a user's credentials, including the two-factor code of users
+// who enabled it, and issues a new token pair. ipAddress is the client's address,
+// which failed logins are tracked by besides the account.
+func (uc *AuthUseCase) Login(ctx context.Context, email, password, totpCode, ipAddress string) (*AuthTokens, error) {
+	user, err := uc.userUseCase.VerifyCredentials(ctx, email, password, totpCode, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.issue(user)
+}
+
+// Refresh exchanges a valid refresh token for a new token pair
+func (uc *AuthUseCase) Refresh(ctx context.Context, refreshToken string) (*AuthTokens, error) {
+	claims, err := uc.tokens.Parse(refreshToken, service.TokenTypeRefresh)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Users deleted since the token was issued cannot refresh it, and neither
+	// can anyone after the user's password was changed or reset
+	user, err := uc.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil || user.TokenVersion != claims.UserVersion {
+		return nil, ErrInvalidToken
+	}
+
+	return uc.issue(user)
+}
+
+// Authenticate resolves the principal an access token or API key was issued for
+func (uc *AuthUseCase) Authenticate(ctx context.Context, accessToken string) (*entity.Principal, error) {
+	if IsAPIKey(accessToken) {
+		return uc.apiKeys.Authenticate(ctx, accessToken)
+	}
+
+	claims, err := uc.tokens.Parse(accessToken, service.TokenTypeAccess)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil || user.TokenVersion != claims.UserVersion {
+		return nil, ErrInvalidToken
+	}
+
+	return &entity.Principal{UserID: user.ID, Role: user.Role, TokenID: claims.ID}, nil
+}
+
+// ChangePassword changes a user's password like UserUseCase.ChangePassword.
+// Callers who changed their own password using an access token get a new token
+// pair in place of theirs, which stops working; other callers get nil.
+func (uc *AuthUseCase) ChangePassword(ctx context.Context, id uint64, currentPassword, newPassword string) (*AuthTokens, error) {
+	if err := uc.userUseCase.ChangePassword(ctx, id, currentPassword, newPassword); err != nil {
+		return nil, err
+	}
+
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok || principal.TokenID == "" || principal.UserID != id {
+		return nil, nil
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.issue(user)
+}
+
+// CurrentUser returns the user authenticated in ctx
+func (uc *AuthUseCase) CurrentUser(ctx context.Context) (*entity.User, error) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	return uc.userRepo.GetByID(ctx, principal.UserID)
+}
+
+// issue signs a new access and refresh token for a user
+func (uc *AuthUseCase) issue(user *entity.User) (*AuthTokens, error) {
+	accessToken, accessClaims, err := uc.tokens.Issue(user.ID, user.TokenVersion, service.TokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshClaims, err := uc.tokens.Issue(user.ID, user.TokenVersion, service.TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthTokens{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessClaims.ExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshClaims.ExpiresAt,
+	}, nil
+}
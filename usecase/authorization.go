@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+)
+
+// authorize allows the principal in ctx to apply permission to records owned by
+// the user with the given ID, according to the permission matrix of its role
+func authorize(ctx context.Context, permission entity.Permission, ownerID uint64) error {
+	scope, err := principalScope(ctx, permission)
+	if err != nil {
+		return err
+	}
+
+	principal, _ := PrincipalFromContext(ctx)
+	if scope == entity.ScopeAll || (scope == entity.ScopeOwn && principal.UserID == ownerID) {
+		return nil
+	}
+
+	return ErrForbidden
+}
+
+// authorizeAll allows the principal in ctx to proceed only if its role grants
+// permission on every record
+func authorizeAll(ctx context.Context, permission entity.Permission) error {
+	scope, err := principalScope(ctx, permission)
+	if err != nil {
+		return err
+	}
+
+	if scope != entity.ScopeAll {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// principalScope returns the scope the principal in ctx is granted for permission
+func principalScope(ctx context.Context, permission entity.Permission) (entity.Scope, error) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return entity.ScopeNone, ErrUnauthenticated
+	}
+
+	scope := principal.Scope(permission)
+	if scope == entity.ScopeNone {
+		return entity.ScopeNone, ErrForbidden
+	}
+
+	return scope, nil
+}
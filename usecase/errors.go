@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"errors"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
+)
+
+// ErrInvalidCredentials is returned when a login or password check fails. It
+// deliberately does not reveal whether the account or the password was wrong.
+var ErrInvalidCredentials = domain.NewError(domain.ErrUnauthorized, "invalid credentials")
+
+// ErrInvalidToken is returned when a token is malformed, expired, revoked or of the wrong type
+var ErrInvalidToken = domain.NewError(domain.ErrUnauthorized, "invalid token")
+
+// ErrUnauthenticated is returned when an operation requires a principal but the context carries none
+var ErrUnauthenticated = domain.NewError(domain.ErrUnauthorized, "authentication required")
+
+// ErrForbidden is returned when the authenticated principal may not perform an operation
+var ErrForbidden = domain.NewError(domain.ErrForbidden, "forbidden")
+
+// ErrOwnRole is returned when a user tries to change their own role
+var ErrOwnRole = domain.NewError(domain.ErrForbidden, "cannot change your own role")
+
+// ErrTOTPRequired is returned when the password of an account with two-factor
+// authentication was correct but no code was given
+var ErrTOTPRequired = domain.NewError(domain.ErrUnauthorized, "two-factor code required")
+
+// ErrInvalidTOTPCode is returned when a two-factor or recovery code is wrong or was already used
+var ErrInvalidTOTPCode = domain.NewError(domain.ErrUnauthorized, "invalid two-factor code")
+
+// ErrTOTPEnabled is returned when enrolling in two-factor authentication while it is already enabled
+var ErrTOTPEnabled = domain.NewError(domain.ErrConflict, "two-factor authentication is already enabled")
+
+// ErrTOTPNotEnabled is returned when confirming or changing two-factor authentication that was not set up
+var ErrTOTPNotEnabled = domain.NewError(domain.ErrConflict, "two-factor authentication is not enabled")
+
+// ErrEmailVerified is returned when asking to verify an email address that already is
+var ErrEmailVerified = domain.NewError(domain.ErrConflict, "email address is already verified")
+
+// ErrTooManyAttempts is returned, wrapped in a LoginThrottledError, when a
+// credential check is refused after too many failed attempts
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
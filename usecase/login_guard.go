@@ -0,0 +1,261 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// LoginLimits configures how a LoginGuard throttles failed logins
+type LoginLimits struct {
+	// MaxAttempts is how many failed logins lock an account; MaxIPAttempts is
+	// how many lock out a client address, whichever accounts it tried
+	MaxAttempts   int
+	MaxIPAttempts int
+
+	// Backoff is the delay after the first failure, doubling with each further
+	// one up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// LockoutDuration is how long a lockout lasts and how long failures are remembered
+	LockoutDuration time.Duration
+}
+
+// LoginThrottledError is returned when a login is refused because the account
+// or client address failed too often. It matches ErrTooManyAttempts.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap lets errors.Is match ErrTooManyAttempts
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// LoginGuard tracks failed credential checks per account and per client
+// address. Each failure makes the next attempt wait exponentially longer, and
+// reaching the threshold locks the account or address out for a while. Every
+// lockout is written to the audit log.
+//
+// A login is counted as failed as soon as it starts, with Reserve, and settled
+// once its credentials are checked, so that logins running concurrently cannot
+// all pass the threshold while none of them has failed yet.
+type LoginGuard struct {
+	attemptRepo repository.LoginAttemptRepository
+	auditRepo   repository.AuditRepository
+	limits      LoginLimits
+}
+
+// NewLoginGuard creates a new login guard
+func NewLoginGuard(attemptRepo repository.LoginAttemptRepository, auditRepo repository.AuditRepository, limits LoginLimits) *LoginGuard {
+	return &LoginGuard{
+		attemptRepo: attemptRepo,
+		auditRepo:   auditRepo,
+		limits:      limits,
+	}
+}
+
+// guardedKey is an attempt counter a login is subject to, with its lockout threshold
+type guardedKey struct {
+	key         string
+	maxAttempts int
+	action      entity.AuditAction
+}
+
+// Reserve counts a login for the account and the client address as failed
+// before its credentials are checked, or returns a LoginThrottledError if
+// either must wait before trying again. An empty ipAddress only involves the
+// account. A reservation must be settled with Fail, Succeed or Release once the
+// outcome of the login is known.
+func (g *LoginGuard) Reserve(ctx context.Context, email, ipAddress string) error {
+	now := time.Now()
+	keys := g.keys(email, ipAddress)
+
+	var wait time.Duration
+	for _, guarded := range keys {
+		attempts, err := g.current(ctx, guarded.key, now)
+		if err != nil {
+			return err
+		}
+
+		wait = max(wait, g.retryAfter(attempts, now))
+	}
+
+	if wait > 0 {
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+
+	for i, guarded := range keys {
+		attempts, err := g.attemptRepo.Increment(ctx, guarded.key, now, g.limits.LockoutDuration)
+		if err != nil {
+			return errors.Join(err, g.release(ctx, keys[:i]))
+		}
+
+		// Logins beyond the threshold are refused even while the ones before
+		// them are still being checked
+		if attempts.Locked(now) || attempts.Failures > guarded.maxAttempts {
+			if err := g.release(ctx, keys[:i+1]); err != nil {
+				return err
+			}
+			return &LoginThrottledError{RetryAfter: max(g.retryAfter(attempts, now), time.Second)}
+		}
+	}
+
+	return nil
+}
+
+// Fail settles a reserved login that failed, locking out the account and the
+// client address if they reached their threshold. userID identifies the
+// account's user, or is zero if there is no account with that email.
+func (g *LoginGuard) Fail(ctx context.Context, email, ipAddress string, userID uint64) error {
+	now := time.Now()
+	for _, guarded := range g.keys(email, ipAddress) {
+		attempts, err := g.attemptRepo.Get(ctx, guarded.key)
+		if err != nil {
+			return err
+		}
+		if attempts.Failures < guarded.maxAttempts {
+			continue
+		}
+
+		// Only the login that locks the counter records the lockout
+		lockedUntil := now.Add(g.limits.LockoutDuration)
+		locked, err := g.attemptRepo.Lock(ctx, guarded.key, now, lockedUntil)
+		if err != nil {
+			return err
+		}
+		if !locked {
+			continue
+		}
+
+		entry := entity.NewAuditEntry(guarded.action)
+		if userID != 0 && guarded.action == entity.AuditActionAccountLocked {
+			entry.UserID = &userID
+		}
+		entry.Email = email
+		entry.IPAddress = ipAddress
+		entry.Detail = fmt.Sprintf("locked until %s after %d failed logins", lockedUntil.UTC().Format(time.RFC3339), attempts.Failures)
+		if err := g.auditRepo.Create(ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Succeed settles a reserved login that succeeded. It forgets the failed logins
+// of the account, while the client address only gets its reservation back so
+// that one valid account cannot be used to keep guessing others.
+func (g *LoginGuard) Succeed(ctx context.Context, email, ipAddress string) error {
+	if err := g.attemptRepo.Delete(ctx, accountAttemptKey(email)); err != nil {
+		return err
+	}
+
+	return g.release(ctx, g.keys(email, ipAddress)[1:])
+}
+
+// Release settles a reserved login that neither failed nor succeeded, such as
+// one still missing its second factor, by taking back its reservation
+func (g *LoginGuard) Release(ctx context.Context, email, ipAddress string) error {
+	return g.release(ctx, g.keys(email, ipAddress))
+}
+
+// Unlock lifts the lockout of user's account and records that actorID did so
+func (g *LoginGuard) Unlock(ctx context.Context, user *entity.User, actorID uint64) error {
+	if err := g.attemptRepo.Delete(ctx, accountAttemptKey(user.Email)); err != nil {
+		return err
+	}
+
+	entry := entity.NewAuditEntry(entity.AuditActionAccountUnlocked)
+	entry.ActorID = &actorID
+	entry.UserID = &user.ID
+	entry.Email = user.Email
+
+	return g.auditRepo.Create(ctx, entry)
+}
+
+// Purge removes attempt counters that expired and returns how many there were
+func (g *LoginGuard) Purge(ctx context.Context) (int64, error) {
+	return g.attemptRepo.Purge(ctx, time.Now().Add(-g.limits.LockoutDuration))
+}
+
+// release takes back the reservations of a login on keys
+func (g *LoginGuard) release(ctx context.Context, keys []guardedKey) error {
+	for _, guarded := range keys {
+		if err := g.attemptRepo.Decrement(ctx, guarded.key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keys returns the attempt counters a login for email from ipAddress is subject to
+func (g *LoginGuard) keys(email, ipAddress string) []guardedKey {
+	keys := []guardedKey{{
+		key:         accountAttemptKey(email),
+		maxAttempts: g.limits.MaxAttempts,
+		action:      entity.AuditActionAccountLocked,
+	}}
+
+	if ipAddress != "" {
+		keys = append(keys, guardedKey{
+			key:         "address:" + ipAddress,
+			maxAttempts: g.limits.MaxIPAttempts,
+			action:      entity.AuditActionAddressLocked,
+		})
+	}
+
+	return keys
+}
+
+// current returns the attempts recorded under key, starting over once a
+// lockout ended or the last failure is older than the lockout duration
+func (g *LoginGuard) current(ctx context.Context, key string, now time.Time) (*entity.LoginAttempts, error) {
+	attempts, err := g.attemptRepo.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if attempts.Stale(now, g.limits.LockoutDuration) {
+		return &entity.LoginAttempts{Key: key}, nil
+	}
+
+	return attempts, nil
+}
+
+// retryAfter returns how long to wait before attempts allow another try
+func (g *LoginGuard) retryAfter(attempts *entity.LoginAttempts, now time.Time) time.Duration {
+	if attempts.Locked(now) {
+		return attempts.LockedUntil.Sub(now)
+	}
+
+	if attempts.Failures == 0 {
+		return 0
+	}
+
+	// Double the delay with every failure after the first
+	delay := g.limits.Backoff
+	for i := 1; i < attempts.Failures && delay < g.limits.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, g.limits.MaxBackoff)
+
+	return max(attempts.LastFailureAt.Add(delay).Sub(now), 0)
+}
+
+// accountAttemptKey returns the attempt counter key of the account with email
+func accountAttemptKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
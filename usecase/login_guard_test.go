@@ -0,0 +1,327 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/config"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/persistence"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/infrastructure/repository/memory"
+)
+
+// slowHasher compares passwords with their "hashes" after a delay, keeping
+// concurrent logins in flight long enough to overlap
+type slowHasher struct {
+	delay time.Duration
+}
+
+func (h slowHasher) Hash(password string) (string, error) {
+	time.Sleep(h.delay)
+	return "hash:" + password, nil
+}
+
+func (h slowHasher) Verify(encodedHash, password string) (bool, error) {
+	time.Sleep(h.delay)
+	return encodedHash == "hash:"+password, nil
+}
+
+func (h slowHasher) NeedsRehash(string) bool {
+	return false
+}
+
+func newTestLoginGuard(limits LoginLimits) (*LoginGuard, *memory.LoginAttemptRepository, *memory.AuditRepository) {
+	attempts := memory.NewLoginAttemptRepository()
+	audit := memory.NewAuditRepository()
+
+	return NewLoginGuard(attempts, audit, limits), attempts, audit
+}
+
+func TestLoginGuard(t *testing.T) {
+	limits := LoginLimits{
+		MaxAttempts:     3,
+		MaxIPAttempts:   5,
+		LockoutDuration: time.Hour,
+	}
+
+	// Each step reserves a login and settles it with its outcome
+	type step struct {
+		email   string
+		outcome string // "fail", "succeed" or "release"
+		// throttled tells that the reservation is refused
+		throttled bool
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+		// locks is the number of lockouts written to the audit log
+		locks int
+	}{
+		{
+			name: "failures below the threshold are allowed",
+			steps: []step{
+				{email: "a@x.io", outcome: "fail"},
+				{email: "a@x.io", outcome: "fail"},
+				{email: "a@x.io", outcome: "succeed"},
+			},
+		},
+		{
+			name: "reaching the threshold locks the account",
+			steps: []step{
+				{email: "a@x.io", outcome: "fail"},
+				{email: "a@x.io", outcome: "fail"},
+				{email: "a@x.io", outcome: "fail"},
+				{email: "a@x.io", throttled: true},
+			},
+			locks: 1,
+		},
+		{
+			name: "success forgets the failures of the account",
+			steps: []step{
+				{email: "a@x.io", outcome: "fail"},
+				{email: "a@x.io", outcome: "fail"},
+				{email: "a@x.io", outcome: "succeed"},
+				{email: "a@x.io", outcome: "fail"},
+				{email: "a@x.io", outcome: "fail"},
+				{email: "a@x.io", outcome: "succeed"},
+			},
+		},
+		{
+			name: "released logins do not count",
+			steps: []step{
+				{email: "a@x.io", outcome: "release"},
+				{email: "a@x.io", outcome: "release"},
+				{email: "a@x.io", outcome: "release"},
+				{email: "a@x.io", outcome: "fail"},
+				{email: "a@x.io", outcome: "fail"},
+				{email: "a@x.io", outcome: "succeed"},
+			},
+		},
+		{
+			name: "failures across accounts lock the address",
+			steps: []step{
+				{email: "a@x.io", outcome: "fail"},
+				{email: "b@x.io", outcome: "fail"},
+				{email: "c@x.io", outcome: "fail"},
+				{email: "d@x.io", outcome: "fail"},
+				{email: "e@x.io", outcome: "fail"},
+				{email: "f@x.io", throttled: true},
+			},
+			locks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			guard, _, audit := newTestLoginGuard(limits)
+
+			for i, step := range tt.steps {
+				err := guard.Reserve(ctx, step.email, "192.0.2.1")
+				if throttled := errors.Is(err, ErrTooManyAttempts); throttled != step.throttled {
+					t.Fatalf("step %d: Reserve() error = %v, want throttled %v", i, err, step.throttled)
+				}
+				if step.throttled {
+					continue
+				}
+				if err != nil {
+					t.Fatalf("step %d: Reserve() error = %v", i, err)
+				}
+
+				switch step.outcome {
+				case "fail":
+					err = guard.Fail(ctx, step.email, "192.0.2.1", 0)
+				case "succeed":
+					err = guard.Succeed(ctx, step.email, "192.0.2.1")
+				case "release":
+					err = guard.Release(ctx, step.email, "192.0.2.1")
+				}
+				if err != nil {
+					t.Fatalf("step %d: settling with %s: %v", i, step.outcome, err)
+				}
+			}
+
+			if locks, _ := audit.Count(ctx); locks != int64(tt.locks) {
+				t.Errorf("audit log holds %d lockouts, want %d", locks, tt.locks)
+			}
+		})
+	}
+}
+
+func TestLoginGuardBackoff(t *testing.T) {
+	ctx := context.Background()
+	guard, _, _ := newTestLoginGuard(LoginLimits{
+		MaxAttempts:     10,
+		MaxIPAttempts:   10,
+		Backoff:         time.Minute,
+		MaxBackoff:      time.Hour,
+		LockoutDuration: time.Hour,
+	})
+
+	if err := guard.Reserve(ctx, "a@x.io", ""); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if err := guard.Fail(ctx, "a@x.io", "", 0); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+
+	var throttled *LoginThrottledError
+	err := guard.Reserve(ctx, "a@x.io", "")
+	if !errors.As(err, &throttled) {
+		t.Fatalf("Reserve() after a failure error = %v, want a LoginThrottledError", err)
+	}
+	if throttled.RetryAfter <= 0 || throttled.RetryAfter > time.Minute {
+		t.Errorf("RetryAfter = %s, want up to the 1m backoff", throttled.RetryAfter)
+	}
+}
+
+func TestLoginGuardConcurrentReservations(t *testing.T) {
+	ctx := context.Background()
+	guard, _, _ := newTestLoginGuard(LoginLimits{
+		MaxAttempts:     5,
+		MaxIPAttempts:   100,
+		LockoutDuration: time.Hour,
+	})
+
+	// None of the reservations is settled, as if every login were still being checked
+	var reserved, throttled int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range 30 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := guard.Reserve(ctx, "a@x.io", "192.0.2.1")
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				reserved++
+			case errors.Is(err, ErrTooManyAttempts):
+				throttled++
+			default:
+				t.Errorf("Reserve() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if reserved != 5 || throttled != 25 {
+		t.Errorf("got %d reserved and %d throttled logins, want 5 and 25", reserved, throttled)
+	}
+}
+
+func TestVerifyCredentialsConcurrentFailures(t *testing.T) {
+	ctx := context.Background()
+	tasks := memory.NewTaskRepository()
+	users := memory.NewUserRepository(tasks)
+	guard, _, _ := newTestLoginGuard(LoginLimits{
+		MaxAttempts:     5,
+		MaxIPAttempts:   100,
+		LockoutDuration: time.Hour,
+	})
+	hasher := slowHasher{delay: 20 * time.Millisecond}
+	sessions := memory.NewSessionRepository()
+	apiKeys := memory.NewAPIKeyRepository()
+	uc := NewUserUseCase(users, tasks, sessions, apiKeys, memory.NewTransactor(users, tasks, sessions, apiKeys), hasher, nil, guard)
+
+	user := entity.NewUser("alice", "alice@x.io", "hash:password1", "Alice", "")
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	results := make(chan error, 30)
+	var wg sync.WaitGroup
+	for range 30 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := uc.VerifyCredentials(ctx, "alice@x.io", "wrong", "", "192.0.2.1")
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	var invalid, throttled int
+	for err := range results {
+		switch {
+		case errors.Is(err, ErrInvalidCredentials):
+			invalid++
+		case errors.Is(err, ErrTooManyAttempts):
+			throttled++
+		default:
+			t.Errorf("VerifyCredentials() error = %v", err)
+		}
+	}
+	if invalid != 5 || throttled != 25 {
+		t.Errorf("got %d rejected and %d throttled logins, want 5 and 25", invalid, throttled)
+	}
+
+	// The account is locked now, even for the right password
+	_, err := uc.VerifyCredentials(ctx, "alice@x.io", "password1", "", "192.0.2.2")
+	if !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("VerifyCredentials() with the right password error = %v, want %v", err, ErrTooManyAttempts)
+	}
+}
+
+func TestChangePasswordLocksAccount(t *testing.T) {
+	// A failed check rolls its transaction back, which must not take back the
+	// failed attempt on drivers storing attempts in the database
+	drivers := []config.DatabaseConfig{
+		{Driver: "memory"},
+		{Driver: "sqlite", Name: filepath.Join(t.TempDir(), "test.db"), AutoMigrate: true},
+	}
+
+	for _, cfg := range drivers {
+		t.Run(cfg.Driver, func(t *testing.T) {
+			repos, err := persistence.New(cfg)
+			if err != nil {
+				t.Fatalf("creating repositories: %v", err)
+			}
+			t.Cleanup(func() { repos.Close() })
+
+			guard := NewLoginGuard(repos.LoginAttempts, repos.Audit, LoginLimits{
+				MaxAttempts:     3,
+				MaxIPAttempts:   100,
+				LockoutDuration: time.Hour,
+			})
+			uc := NewUserUseCase(repos.Users, repos.Tasks, repos.Sessions, repos.APIKeys, repos.Transactor, slowHasher{}, nil, guard)
+
+			user := entity.NewUser("alice", "alice@x.io", "hash:password1", "Alice", "")
+			if err := repos.Users.Create(context.Background(), user); err != nil {
+				t.Fatalf("creating user: %v", err)
+			}
+			ctx := WithPrincipal(context.Background(), &entity.Principal{UserID: user.ID, Role: entity.RoleMember})
+
+			for i := range 3 {
+				err := uc.ChangePassword(ctx, user.ID, "wrong", "password2")
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("attempt %d: ChangePassword() error = %v, want %v", i, err, ErrInvalidCredentials)
+				}
+			}
+
+			err = uc.ChangePassword(ctx, user.ID, "password1", "password2")
+			if !errors.Is(err, ErrTooManyAttempts) {
+				t.Errorf("ChangePassword() with the right password error = %v, want %v", err, ErrTooManyAttempts)
+			}
+			if count, err := repos.Audit.Count(context.Background()); err != nil || count != 1 {
+				t.Errorf("audit log holds %d entries (error %v), want the lockout", count, err)
+			}
+		})
+	}
+}
+
+func TestAccountAttemptKey(t *testing.T) {
+	for _, email := range []string{"alice@x.io", " Alice@X.io ", "ALICE@x.IO"} {
+		if key := accountAttemptKey(email); key != "account:alice@x.io" {
+			t.Errorf("accountAttemptKey(%q) = %q, want %q", email, key, "account:alice@x.io")
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// PurgeUseCase permanently removes records that stayed in the trash longer than the retention period
+type PurgeUseCase struct {
+	taskRepo  repository.TaskRepository
+	userRepo  repository.UserRepository
+	retention time.Duration
+}
+
+// NewPurgeUseCase creates a new purge use case
+func NewPurgeUseCase(taskRepo repository.TaskRepository, userRepo repository.UserRepository, retention time.Duration) *PurgeUseCase {
+	return &PurgeUseCase{
+		taskRepo:  taskRepo,
+		userRepo:  userRepo,
+		retention: retention,
+	}
+}
+
+// Purge hard-deletes the tasks and users trashed before the retention cut-off
+// and returns how many of each were removed
+func (uc *PurgeUseCase) Purge(ctx context.Context) (tasks, users int64, err error) {
+	before := time.Now().Add(-uc.retention)
+
+	// Purge tasks first so their users are no longer referenced
+	tasks, err = uc.taskRepo.Purge(ctx, before)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	users, err = uc.userRepo.Purge(ctx, before)
+	if err != nil {
+		return tasks, 0, err
+	}
+
+	return tasks, users, nil
+}
@@ -0,0 +1,26 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// randomToken returns size random bytes encoded as unpadded base64url
+func randomToken(size int) (string, error) {
+	random := make([]byte, size)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// hashSecret returns the stored form of a random secret such as an API key,
+// session token or recovery code. The secrets carry at least 80 bits of
+// randomness, so a fast unsalted hash is enough to protect them at rest.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// sessionLastSeenPrecision is how stale the recorded last use of a session may get,
+// so busy sessions do not cause a write on every request
+const sessionLastSeenPrecision = time.Minute
+
+// SessionUseCase represents the cookie session use case
+type SessionUseCase struct {
+	sessionRepo     repository.SessionRepository
+	userRepo        repository.UserRepository
+	userUseCase     *UserUseCase
+	idleTimeout     time.Duration
+	absoluteTimeout time.Duration
+}
+
+// NewSessionUseCase creates a new session use case. Sessions end after being
+// unused for idleTimeout, and absoluteTimeout after login at the latest.
+func NewSessionUseCase(sessionRepo repository.SessionRepository, userRepo repository.UserRepository, userUseCase *UserUseCase, idleTimeout, absoluteTimeout time.Duration) *SessionUseCase {
+	return &SessionUseCase{
+		sessionRepo:     sessionRepo,
+		userRepo:        userRepo,
+		userUseCase:     userUseCase,
+		idleTimeout:     idleTimeout,
+		absoluteTimeout: absoluteTimeout,
+	}
+}
+
+// Login verifies a user's credentials, including the two-factor code of users
+// who enabled it, and starts a new session, returning it together with the
+// token to store in the session cookie
+func (uc *SessionUseCase) Login(ctx context.Context, email, password, totpCode, userAgent, ipAddress string) (*entity.Session, string, error) {
+	user, err := uc.userUseCase.VerifyCredentials(ctx, email, password, totpCode, ipAddress)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Create session entity
+	session := entity.NewSession(user.ID, userAgent, ipAddress, uc.absoluteTimeout)
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", err
+	}
+	session.ID = hex.EncodeToString(id)
+
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	session.TokenHash = hashSecret(token)
+
+	if session.CSRFToken, err = randomToken(32); err != nil {
+		return nil, "", err
+	}
+
+	if err := uc.sessionRepo.Create(ctx, session); err != nil {
+		return nil, "", err
+	}
+
+	return session, token, nil
+}
+
+// Authenticate resolves the session a cookie token belongs to and the principal
+// it was started for. Expired sessions are removed and reported as ErrInvalidToken.
+func (uc *SessionUseCase) Authenticate(ctx context.Context, token string) (*entity.Principal, *entity.Session, error) {
+	session, err := uc.sessionRepo.GetByTokenHash(ctx, hashSecret(token))
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if !session.Active(now, uc.idleTimeout) {
+		_ = uc.sessionRepo.Delete(ctx, session.ID)
+		return nil, nil, ErrInvalidToken
+	}
+
+	// Sessions of users deleted since they logged in stop working
+	user, err := uc.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionLastSeenPrecision {
+		if err := uc.sessionRepo.Touch(ctx, session.ID, now); err != nil {
+			return nil, nil, err
+		}
+		session.LastSeenAt = now
+	}
+
+	return &entity.Principal{UserID: user.ID, Role: user.Role, SessionID: session.ID}, session, nil
+}
+
+// Logout ends the session a cookie token belongs to. Unknown tokens are ignored.
+func (uc *SessionUseCase) Logout(ctx context.Context, token string) error {
+	session, err := uc.sessionRepo.GetByTokenHash(ctx, hashSecret(token))
+	if err != nil {
+		return nil
+	}
+
+	return uc.sessionRepo.Delete(ctx, session.ID)
+}
+
+// List retrieves the active sessions of a user, most recently used first
+func (uc *SessionUseCase) List(ctx context.Context, userID uint64) ([]*entity.Session, error) {
+	if err := authorize(ctx, entity.PermissionUserSessions, userID); err != nil {
+		return nil, err
+	}
+
+	sessions, err := uc.sessionRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]*entity.Session, 0, len(sessions))
+	for _, session := range sessions {
+		if session.Active(now, uc.idleTimeout) {
+			active = append(active, session)
+		}
+	}
+
+	return active, nil
+}
+
+// RevokeAll ends every session of a user and returns how many were ended
+func (uc *SessionUseCase) RevokeAll(ctx context.Context, userID uint64) (int64, error) {
+	if err := authorize(ctx, entity.PermissionUserSessions, userID); err != nil {
+		return 0, err
+	}
+
+	return uc.sessionRepo.DeleteByUserID(ctx, userID)
+}
+
+// Purge removes expired sessions from the store
+func (uc *SessionUseCase) Purge(ctx context.Context) (int64, error) {
+	now := time.Now()
+	return uc.sessionRepo.Purge(ctx, now, now.Add(-uc.idleTimeout))
+}
@@ -5,46 +5,62 @@ import (
 	"errors"
 	"time"
 
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
 	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
 	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
 )
 
 // TaskUseCase represents the task use case
 type TaskUseCase struct {
-	taskRepo repository.TaskRepository
-	userRepo repository.UserRepository
+	taskRepo   repository.TaskRepository
+	userRepo   repository.UserRepository
+	transactor repository.Transactor
 }
 
 // NewTaskUseCase creates a new task use case
-func NewTaskUseCase(taskRepo repository.TaskRepository, userRepo repository.UserRepository) *TaskUseCase {
+func NewTaskUseCase(taskRepo repository.TaskRepository, userRepo repository.UserRepository, transactor repository.Transactor) *TaskUseCase {
 	return &TaskUseCase{
-		taskRepo: taskRepo,
-		userRepo: userRepo,
+		taskRepo:   taskRepo,
+		userRepo:   userRepo,
+		transactor: transactor,
 	}
 }
 
 // GetByID retrieves a task by its ID
 func (uc *TaskUseCase) GetByID(ctx context.Context, id uint64) (*entity.Task, error) {
-	return uc.taskRepo.GetByID(ctx, id)
+	task, err := uc.taskRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, entity.PermissionTaskRead, task.UserID); err != nil {
+		return nil, err
+	}
+
+	return task, nil
 }
 
-// GetByUserID retrieves tasks by user ID
-func (uc *TaskUseCase) GetByUserID(ctx context.Context, userID uint64, limit, offset int) ([]*entity.Task, error) {
+// GetByUserID retrieves a page of the tasks of a user matching filter, ordered
+// by sort, along with how many match in total
+func (uc *TaskUseCase) GetByUserID(ctx context.Context, userID uint64, filter repository.TaskFilter, sort repository.TaskSort, page repository.Page) ([]*entity.Task, int64, error) {
+	if err := authorize(ctx, entity.PermissionTaskRead, userID); err != nil {
+		return nil, 0, err
+	}
+
 	// Verify user exists
 	_, err := uc.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, 0, err
 	}
 
-	return uc.taskRepo.GetByUserID(ctx, userID, limit, offset)
+	filter.UserID = userID
+	return uc.list(ctx, filter, sort, page)
 }
 
 // Create creates a new task
 func (uc *TaskUseCase) Create(ctx context.Context, title, description string, userID uint64, dueDate *time.Time) (*entity.Task, error) {
-	// Verify user exists
-	_, err := uc.userRepo.GetByID(ctx, userID)
-	if err != nil {
-		return nil, errors.New("user not found")
+	if err := authorize(ctx, entity.PermissionTaskCreate, userID); err != nil {
+		return nil, err
 	}
 
 	// Create task entity
@@ -55,86 +71,259 @@ func (uc *TaskUseCase) Create(ctx context.Context, title, description string, us
 		return nil, err
 	}
 
-	// Create task
-	if err := uc.taskRepo.Create(ctx, task); err != nil {
+	// Verify the user exists and create the task atomically so the user cannot be deleted in between
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		_, err := uc.userRepo.GetByID(ctx, userID)
+		if err == nil {
+			// The repository checks the user again while holding it, in case
+			// they were moved to the trash after the lookup
+			err = uc.taskRepo.Create(ctx, task)
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			return entity.ValidationErrors{{Field: "user_id", Code: entity.ValidationNotFound, Message: "must refer to an existing user"}}
+		}
+
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return task, nil
 }
 
-// Update updates an existing task
-func (uc *TaskUseCase) Update(ctx context.Context, id uint64, title, description string, status entity.TaskStatus, dueDate *time.Time) (*entity.Task, error) {
-	// Get existing task
-	task, err := uc.taskRepo.GetByID(ctx, id)
+// TaskPatch holds the fields of a task a partial update changes. Nil fields are
+// left as they are.
+type TaskPatch struct {
+	Title       *string
+	Description *string
+	Status      *entity.TaskStatus
+	// DueDate points to the new due date, which is nil to clear it
+	DueDate **time.Time
+}
+
+// Update replaces every editable field of an existing task. A non-zero version
+// must match the stored version, otherwise repository.ErrVersionConflict is returned.
+func (uc *TaskUseCase) Update(ctx context.Context, id uint64, title, description string, status entity.TaskStatus, dueDate *time.Time, version uint64) (*entity.Task, error) {
+	return uc.Patch(ctx, id, TaskPatch{
+		Title:       &title,
+		Description: &description,
+		Status:      &status,
+		DueDate:     &dueDate,
+	}, version)
+}
+
+// Patch changes the fields of an existing task set in patch and validates the
+// result. A non-zero version must match the stored version.
+func (uc *TaskUseCase) Patch(ctx context.Context, id uint64, patch TaskPatch, version uint64) (*entity.Task, error) {
+	return uc.modify(ctx, id, version, func(task *entity.Task) error {
+		// Update the supplied fields
+		if patch.Title != nil {
+			task.Title = *patch.Title
+		}
+		if patch.Description != nil {
+			task.Description = *patch.Description
+		}
+		if patch.Status != nil {
+			task.Status = *patch.Status
+		}
+		if patch.DueDate != nil {
+			task.DueDate = *patch.DueDate
+		}
+		task.UpdatedAt = time.Now()
+
+		// Validate task
+		return task.Validate()
+	})
+}
+
+// Delete deletes a task by its ID. A non-zero version must match the stored version.
+func (uc *TaskUseCase) Delete(ctx context.Context, id uint64, version uint64) error {
+	return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		task, err := uc.taskRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := authorize(ctx, entity.PermissionTaskDelete, task.UserID); err != nil {
+			return err
+		}
+
+		return uc.taskRepo.Delete(ctx, id, version)
+	})
+}
+
+// List retrieves a page of the tasks visible to the principal matching filter,
+// ordered by sort, along with how many match in total. Principals that may only
+// read their own tasks are limited to them.
+func (uc *TaskUseCase) List(ctx context.Context, filter repository.TaskFilter, sort repository.TaskSort, page repository.Page) ([]*entity.Task, int64, error) {
+	filter, err := visibleTasks(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	// Update task fields
-	task.Title = title
-	task.Description = description
-	task.Status = status
-	task.DueDate = dueDate
-	task.UpdatedAt = time.Now()
+	return uc.list(ctx, filter, sort, page)
+}
 
-	// Validate task
-	if err := task.Validate(); err != nil {
-		return nil, err
+// Search retrieves a page of the tasks visible to the principal matching filter
+// whose title or description contains every search term of query, most
+// relevant first, along with how many there are in total
+func (uc *TaskUseCase) Search(ctx context.Context, query string, filter repository.TaskFilter, page repository.Page) ([]*repository.TaskMatch, int64, error) {
+	filter, err := visibleTasks(ctx, filter)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// Update task
-	if err := uc.taskRepo.Update(ctx, task); err != nil {
-		return nil, err
+	matches, err := uc.taskRepo.Search(ctx, query, filter, page)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return task, nil
+	total, err := uc.taskRepo.CountSearch(ctx, query, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return matches, total, nil
 }
 
-// Delete deletes a task by its ID
-func (uc *TaskUseCase) Delete(ctx context.Context, id uint64) error {
-	return uc.taskRepo.Delete(ctx, id)
+// visibleTasks restricts filter to the tasks the principal may read. Principals
+// that may only read their own tasks are forbidden to ask for anyone else's.
+func visibleTasks(ctx context.Context, filter repository.TaskFilter) (repository.TaskFilter, error) {
+	scope, err := principalScope(ctx, entity.PermissionTaskRead)
+	if err != nil {
+		return filter, err
+	}
+
+	if scope == entity.ScopeOwn {
+		principal, _ := PrincipalFromContext(ctx)
+		if filter.UserID != 0 && filter.UserID != principal.UserID {
+			return filter, ErrForbidden
+		}
+		filter.UserID = principal.UserID
+	}
+
+	return filter, nil
 }
 
-// List retrieves a list of tasks with pagination
-func (uc *TaskUseCase) List(ctx context.Context, limit, offset int) ([]*entity.Task, error) {
-	return uc.taskRepo.List(ctx, limit, offset)
+// list retrieves a page of the tasks matching filter and their total count
+func (uc *TaskUseCase) list(ctx context.Context, filter repository.TaskFilter, sort repository.TaskSort, page repository.Page) ([]*entity.Task, int64, error) {
+	tasks, err := uc.taskRepo.List(ctx, filter, sort, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := uc.taskRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return tasks, total, nil
 }
 
-// MarkInProgress marks a task as in progress
-func (uc *TaskUseCase) MarkInProgress(ctx context.Context, id uint64) (*entity.Task, error) {
-	// Get existing task
-	task, err := uc.taskRepo.GetByID(ctx, id)
+// ListDeleted retrieves a page of the tasks in the trash, along with how many
+// there are in total
+func (uc *TaskUseCase) ListDeleted(ctx context.Context, page repository.Page) ([]*entity.Task, int64, error) {
+	if err := authorizeAll(ctx, entity.PermissionTaskTrash); err != nil {
+		return nil, 0, err
+	}
+
+	tasks, err := uc.taskRepo.ListDeleted(ctx, page)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	// Mark as in progress
-	task.MarkInProgress()
+	total, err := uc.taskRepo.CountDeleted(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	// Update task
-	if err := uc.taskRepo.Update(ctx, task); err != nil {
+	return tasks, total, nil
+}
+
+// Restore moves a task out of the trash, provided its user still exists
+func (uc *TaskUseCase) Restore(ctx context.Context, id uint64) (*entity.Task, error) {
+	var task *entity.Task
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.taskRepo.Restore(ctx, id); err != nil {
+			return err
+		}
+
+		var err error
+		task, err = uc.taskRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// Rolls the restore back unless the caller may restore the task
+		if err := authorize(ctx, entity.PermissionTaskTrash, task.UserID); err != nil {
+			return err
+		}
+
+		// Verify user exists
+		if _, err := uc.userRepo.GetByID(ctx, task.UserID); err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return domain.NewError(domain.ErrConflict, "the task's user no longer exists")
+			}
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return task, nil
 }
 
+// MarkInProgress marks a task as in progress
+func (uc *TaskUseCase) MarkInProgress(ctx context.Context, id uint64, version uint64) (*entity.Task, error) {
+	return uc.modify(ctx, id, version, func(task *entity.Task) error {
+		task.MarkInProgress()
+		return nil
+	})
+}
+
 // MarkCompleted marks a task as completed
-func (uc *TaskUseCase) MarkCompleted(ctx context.Context, id uint64) (*entity.Task, error) {
-	// Get existing task
-	task, err := uc.taskRepo.GetByID(ctx, id)
-	if err != nil {
-		return nil, err
-	}
+func (uc *TaskUseCase) MarkCompleted(ctx context.Context, id uint64, version uint64) (*entity.Task, error) {
+	return uc.modify(ctx, id, version, func(task *entity.Task) error {
+		task.MarkCompleted()
+		return nil
+	})
+}
+
+// modify loads a task, applies change and stores the result in a single transaction.
+// A non-zero version replaces the loaded one so the repository rejects the write
+// unless it still matches the stored version.
+func (uc *TaskUseCase) modify(ctx context.Context, id uint64, version uint64, change func(task *entity.Task) error) (*entity.Task, error) {
+	var task *entity.Task
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		// Get existing task
+		var err error
+		task, err = uc.taskRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	// Mark as completed
-	task.MarkCompleted()
+		if err := authorize(ctx, entity.PermissionTaskUpdate, task.UserID); err != nil {
+			return err
+		}
 
-	// Update task
-	if err := uc.taskRepo.Update(ctx, task); err != nil {
+		if version != 0 {
+			task.Version = version
+		}
+
+		if err := change(task); err != nil {
+			return err
+		}
+
+		// Update task
+		return uc.taskRepo.Update(ctx, task)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return task, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,339 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+// recoveryCodeCount is how many recovery codes are issued at a time
+const recoveryCodeCount = 10
+
+// recoveryCodeEncoding spells recovery codes with lowercase letters and digits
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// TOTPEnrollment holds the secret a user adds to their authenticator app
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// VerifyCredentials returns the user with the given email if password matches
+// and, for users with two-factor authentication, code is a valid TOTP or unused
+// recovery code. A missing code is reported as ErrTOTPRequired, a wrong one as
+// ErrInvalidTOTPCode. Failed attempts are tracked per account and per client
+// address, and once either failed too often further attempts are refused with
+// a LoginThrottledError until its backoff or lockout ends.
+func (uc *UserUseCase) VerifyCredentials(ctx context.Context, email, password, code, ipAddress string) (*entity.User, error) {
+	if err := uc.loginGuard.Reserve(ctx, email, ipAddress); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.verifyCredentials(ctx, email, password, code)
+	if errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrInvalidTOTPCode) {
+		// Attach the lockout to the account in the audit log, if there is one
+		var userID uint64
+		if existing, lookupErr := uc.userRepo.GetByEmail(ctx, email); lookupErr == nil {
+			userID = existing.ID
+		}
+
+		if failErr := uc.loginGuard.Fail(ctx, email, ipAddress, userID); failErr != nil {
+			return nil, failErr
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Join(err, uc.loginGuard.Release(ctx, email, ipAddress))
+	}
+
+	if err := uc.loginGuard.Succeed(ctx, email, ipAddress); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// verifyCredentials checks password and, if the user has two-factor
+// authentication, code without any throttling
+func (uc *UserUseCase) verifyCredentials(ctx context.Context, email, password, code string) (*entity.User, error) {
+	user, err := uc.verifyPassword(ctx, email, password)
+	if err != nil || !user.TOTPEnabled {
+		return user, err
+	}
+
+	if code == "" {
+		return nil, ErrTOTPRequired
+	}
+
+	if err := uc.useSecondFactor(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// EnrollTOTP starts two-factor enrollment for the authenticated user after
+// checking their password. It takes effect once confirmed with ConfirmTOTP;
+// enrolling again before that replaces the secret.
+func (uc *UserUseCase) EnrollTOTP(ctx context.Context, password string) (*TOTPEnrollment, error) {
+	var enrollment *TOTPEnrollment
+	err := uc.withOwnPassword(ctx, password, func(ctx context.Context, user *entity.User) error {
+		if user.TOTPEnabled {
+			return ErrTOTPEnabled
+		}
+
+		secret, err := uc.totp.GenerateSecret()
+		if err != nil {
+			return err
+		}
+
+		user.TOTPSecret = secret
+		user.UpdatedAt = time.Now()
+		if err := uc.userRepo.Update(ctx, user); err != nil {
+			return err
+		}
+
+		enrollment = &TOTPEnrollment{Secret: secret, URI: uc.totp.URI(secret, user.Email)}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+// ConfirmTOTP enables two-factor authentication for the authenticated user once
+// they prove their authenticator app produces valid codes, and returns their
+// recovery codes. The codes are only stored hashed and cannot be shown again.
+func (uc *UserUseCase) ConfirmTOTP(ctx context.Context, code string) ([]string, error) {
+	var codes []string
+	err := uc.withOwnUser(ctx, func(ctx context.Context, user *entity.User) error {
+		if user.TOTPEnabled || user.TOTPSecret == "" {
+			return ErrTOTPNotEnabled
+		}
+
+		step, ok := uc.totp.Validate(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return ErrInvalidTOTPCode
+		}
+
+		var err error
+		if codes, err = uc.issueRecoveryCodes(user); err != nil {
+			return err
+		}
+
+		user.TOTPEnabled = true
+		user.TOTPLastStep = step
+		user.UpdatedAt = time.Now()
+
+		return uc.userRepo.Update(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the authenticated user's recovery codes after
+// checking their password and a second factor, and returns the new codes
+func (uc *UserUseCase) RegenerateRecoveryCodes(ctx context.Context, password, code string) ([]string, error) {
+	var codes []string
+	err := uc.withOwnPassword(ctx, password, func(ctx context.Context, user *entity.User) error {
+		if err := uc.reauthenticate(user, code); err != nil {
+			return err
+		}
+
+		var err error
+		if codes, err = uc.issueRecoveryCodes(user); err != nil {
+			return err
+		}
+		user.UpdatedAt = time.Now()
+
+		return uc.userRepo.Update(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP turns off two-factor authentication for the authenticated user
+// after checking their password and a second factor
+func (uc *UserUseCase) DisableTOTP(ctx context.Context, password, code string) error {
+	return uc.withOwnPassword(ctx, password, func(ctx context.Context, user *entity.User) error {
+		if err := uc.reauthenticate(user, code); err != nil {
+			return err
+		}
+
+		user.TOTPSecret = ""
+		user.TOTPEnabled = false
+		user.TOTPLastStep = 0
+		user.RecoveryCodes = nil
+		user.UpdatedAt = time.Now()
+
+		return uc.userRepo.Update(ctx, user)
+	})
+}
+
+// withOwnUser loads the authenticated user inside a transaction and passes it to fn
+func (uc *UserUseCase) withOwnUser(ctx context.Context, fn func(ctx context.Context, user *entity.User) error) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	if err := authorize(ctx, entity.PermissionUserTOTP, principal.UserID); err != nil {
+		return err
+	}
+
+	return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := uc.userRepo.GetByID(ctx, principal.UserID)
+		if err != nil {
+			return err
+		}
+
+		return fn(ctx, user)
+	})
+}
+
+// withOwnPassword runs fn like withOwnUser once password matches the
+// authenticated user's. The password is checked before the transaction begins,
+// so a wrong one counts against the account although fn never runs; should the
+// password change in between, ErrInvalidCredentials is returned.
+func (uc *UserUseCase) withOwnPassword(ctx context.Context, password string, fn func(ctx context.Context, user *entity.User) error) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	if err := authorize(ctx, entity.PermissionUserTOTP, principal.UserID); err != nil {
+		return err
+	}
+
+	checked, err := uc.userRepo.GetByID(ctx, principal.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.checkPassword(ctx, checked, password); err != nil {
+		return err
+	}
+
+	return uc.withOwnUser(ctx, func(ctx context.Context, user *entity.User) error {
+		if user.Password != checked.Password {
+			return ErrInvalidCredentials
+		}
+
+		return fn(ctx, user)
+	})
+}
+
+// reauthenticate checks the second factor of a user with two-factor
+// authentication enabled. The used code is recorded on user.
+func (uc *UserUseCase) reauthenticate(user *entity.User, code string) error {
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+
+	if !uc.checkSecondFactor(user, code) {
+		return ErrInvalidTOTPCode
+	}
+
+	return nil
+}
+
+// checkPassword reports ErrInvalidCredentials unless password matches the user's.
+// Wrong passwords count against the account like failed logins do, so it must
+// not run in a transaction, whose rollback would take the failure back.
+func (uc *UserUseCase) checkPassword(ctx context.Context, user *entity.User, password string) error {
+	if err := uc.loginGuard.Reserve(ctx, user.Email, ""); err != nil {
+		return err
+	}
+
+	ok, err := uc.hasher.Verify(user.Password, password)
+	if err != nil || ok {
+		return errors.Join(err, uc.loginGuard.Release(ctx, user.Email, ""))
+	}
+
+	if err := uc.loginGuard.Fail(ctx, user.Email, "", user.ID); err != nil {
+		return err
+	}
+
+	return ErrInvalidCredentials
+}
+
+// useSecondFactor checks code against a user logging in and stores that it was used.
+// Losing a race against another login with the same code counts as a wrong code.
+func (uc *UserUseCase) useSecondFactor(ctx context.Context, user *entity.User, code string) error {
+	if !uc.checkSecondFactor(user, code) {
+		return ErrInvalidTOTPCode
+	}
+
+	user.UpdatedAt = time.Now()
+	err := uc.userRepo.Update(ctx, user)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return ErrInvalidTOTPCode
+	}
+
+	return err
+}
+
+// checkSecondFactor reports whether code is a TOTP code newer than the last one
+// accepted or an unused recovery code, and records its use on user
+func (uc *UserUseCase) checkSecondFactor(user *entity.User, code string) bool {
+	code = strings.TrimSpace(code)
+
+	if step, ok := uc.totp.Validate(user.TOTPSecret, code, time.Now()); ok {
+		if step <= user.TOTPLastStep {
+			return false
+		}
+		user.TOTPLastStep = step
+		return true
+	}
+
+	hash := hashSecret(normalizeRecoveryCode(code))
+	for i, stored := range user.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			user.RecoveryCodes = slices.Delete(slices.Clone(user.RecoveryCodes), i, i+1)
+			return true
+		}
+	}
+
+	return false
+}
+
+// issueRecoveryCodes replaces the recovery codes of user with new ones and
+// returns them. Codes look like abcd-efgh-ijkl-mnop and carry 80 random bits.
+func (uc *UserUseCase) issueRecoveryCodes(user *entity.User) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		random := make([]byte, 10)
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+
+		code := recoveryCodeEncoding.EncodeToString(random)
+		codes[i] = code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16]
+		hashes[i] = hashSecret(code)
+	}
+
+	user.RecoveryCodes = hashes
+	return codes, nil
+}
+
+// normalizeRecoveryCode strips the separators and case users may type a recovery code with
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
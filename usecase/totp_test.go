@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+)
+
+func TestIssueRecoveryCodes(t *testing.T) {
+	uc := &UserUseCase{totp: &fakeTOTP{}}
+	user := &entity.User{}
+
+	codes, err := uc.issueRecoveryCodes(user)
+	if err != nil {
+		t.Fatalf("issueRecoveryCodes() error = %v", err)
+	}
+
+	if len(codes) != recoveryCodeCount || len(user.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("issued %d codes and stored %d, want %d", len(codes), len(user.RecoveryCodes), recoveryCodeCount)
+	}
+	format := regexp.MustCompile(`^[a-z2-7]{4}(-[a-z2-7]{4}){3}$`)
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("code %q does not look like abcd-efgh-ijkl-mnop", code)
+		}
+		if slices.Contains(codes[i+1:], code) {
+			t.Errorf("code %q was issued twice", code)
+		}
+		if slices.Contains(user.RecoveryCodes, code) || slices.Contains(user.RecoveryCodes, normalizeRecoveryCode(code)) {
+			t.Errorf("code %q is stored in plain text", code)
+		}
+	}
+
+	// Issuing again replaces every earlier code
+	if _, err := uc.issueRecoveryCodes(user); err != nil {
+		t.Fatalf("issueRecoveryCodes() error = %v", err)
+	}
+	if uc.checkSecondFactor(user, codes[0]) {
+		t.Error("a replaced recovery code still works")
+	}
+}
+
+func TestCheckSecondFactor(t *testing.T) {
+	tests := []struct {
+		name     string
+		lastStep int64
+		// code picks the code to check from the recovery codes of the user
+		code          func(codes []string) string
+		wantOK        bool
+		wantLastStep  int64
+		wantRemaining int
+	}{
+		{
+			name:          "new TOTP code",
+			lastStep:      9,
+			code:          func([]string) string { return validTOTPCode },
+			wantOK:        true,
+			wantLastStep:  10,
+			wantRemaining: recoveryCodeCount,
+		},
+		{
+			name:          "TOTP code of a step already used",
+			lastStep:      10,
+			code:          func([]string) string { return validTOTPCode },
+			wantLastStep:  10,
+			wantRemaining: recoveryCodeCount,
+		},
+		{
+			name:          "TOTP code with surrounding spaces",
+			lastStep:      9,
+			code:          func([]string) string { return " " + validTOTPCode + "\n" },
+			wantOK:        true,
+			wantLastStep:  10,
+			wantRemaining: recoveryCodeCount,
+		},
+		{
+			name:          "recovery code",
+			code:          func(codes []string) string { return codes[3] },
+			wantOK:        true,
+			wantRemaining: recoveryCodeCount - 1,
+		},
+		{
+			name: "recovery code in capitals without dashes",
+			code: func(codes []string) string {
+				return strings.ToUpper(strings.ReplaceAll(codes[3], "-", ""))
+			},
+			wantOK:        true,
+			wantRemaining: recoveryCodeCount - 1,
+		},
+		{
+			name:          "recovery code split by spaces",
+			code:          func(codes []string) string { return strings.ReplaceAll(codes[3], "-", " ") },
+			wantOK:        true,
+			wantRemaining: recoveryCodeCount - 1,
+		},
+		{
+			name:          "unknown recovery code",
+			code:          func([]string) string { return "abcd-efgh-ijkl-mnop" },
+			wantRemaining: recoveryCodeCount,
+		},
+		{
+			name:          "empty code",
+			code:          func([]string) string { return "" },
+			wantRemaining: recoveryCodeCount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &UserUseCase{totp: &fakeTOTP{step: 10}}
+			user := &entity.User{TOTPSecret: "SECRET", TOTPEnabled: true, TOTPLastStep: tt.lastStep}
+			codes, err := uc.issueRecoveryCodes(user)
+			if err != nil {
+				t.Fatalf("issueRecoveryCodes() error = %v", err)
+			}
+			stored := user.RecoveryCodes
+
+			if got := uc.checkSecondFactor(user, tt.code(codes)); got != tt.wantOK {
+				t.Errorf("checkSecondFactor() = %v, want %v", got, tt.wantOK)
+			}
+			if user.TOTPLastStep != tt.wantLastStep {
+				t.Errorf("TOTPLastStep = %d, want %d", user.TOTPLastStep, tt.wantLastStep)
+			}
+			if len(user.RecoveryCodes) != tt.wantRemaining {
+				t.Errorf("%d recovery codes remain, want %d", len(user.RecoveryCodes), tt.wantRemaining)
+			}
+			// The stored codes are replaced, not changed in place, so a failed
+			// update leaves the loaded user untouched
+			if len(stored) != recoveryCodeCount {
+				t.Errorf("the original recovery codes were changed in place")
+			}
+		})
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAccounts(t)
+
+	tokens, err := a.auth.Login(ctx, "alice@x.io", "password1", "", "192.0.2.1")
+	if err != nil {
+		t.Fatalf("logging in: %v", err)
+	}
+	principal, err := a.auth.Authenticate(ctx, tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("authenticating: %v", err)
+	}
+	ctx = WithPrincipal(ctx, principal)
+
+	if _, err := a.users.EnrollTOTP(ctx, "password1"); err != nil {
+		t.Fatalf("EnrollTOTP() error = %v", err)
+	}
+	if _, err := a.users.ConfirmTOTP(ctx, "000000"); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("ConfirmTOTP() with a wrong code error = %v, want %v", err, ErrInvalidTOTPCode)
+	}
+	codes, err := a.users.ConfirmTOTP(ctx, validTOTPCode)
+	if err != nil {
+		t.Fatalf("ConfirmTOTP() error = %v", err)
+	}
+
+	steps := []struct {
+		name string
+		// nextStep moves the clock of the authenticator to the next step first
+		nextStep bool
+		code     string
+		wantErr  error
+	}{
+		{name: "without a code", wantErr: ErrTOTPRequired},
+		{name: "with the code used to confirm", code: validTOTPCode, wantErr: ErrInvalidTOTPCode},
+		{name: "with the code of the next step", nextStep: true, code: validTOTPCode},
+		{name: "with that code again", code: validTOTPCode, wantErr: ErrInvalidTOTPCode},
+		{name: "with a recovery code", code: codes[0]},
+		{name: "with that recovery code again", code: codes[0], wantErr: ErrInvalidTOTPCode},
+		{name: "with another recovery code", code: codes[1]},
+	}
+
+	for _, step := range steps {
+		if step.nextStep {
+			a.totp.step++
+		}
+
+		_, err := a.auth.Login(context.Background(), "alice@x.io", "password1", step.code, "192.0.2.1")
+		if !errors.Is(err, step.wantErr) {
+			t.Errorf("logging in %s: error = %v, want %v", step.name, err, step.wantErr)
+		}
+	}
+
+	user, err := a.users.userRepo.GetByID(ctx, a.user.ID)
+	if err != nil {
+		t.Fatalf("loading user: %v", err)
+	}
+	if len(user.RecoveryCodes) != recoveryCodeCount-2 {
+		t.Errorf("%d recovery codes remain, want %d", len(user.RecoveryCodes), recoveryCodeCount-2)
+	}
+}
@@ -3,103 +3,512 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain"
 	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
 	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/service"
 )
 
+// DeletePolicy decides what happens to the tasks of a user being deleted
+type DeletePolicy string
+
+const (
+	// DeletePolicyRestrict refuses to delete a user who still owns tasks
+	DeletePolicyRestrict DeletePolicy = "restrict"
+	// DeletePolicyCascade moves the user's tasks to the trash together with the user
+	DeletePolicyCascade DeletePolicy = "cascade"
+	// DeletePolicyReassign transfers the user's tasks to another user
+	DeletePolicyReassign DeletePolicy = "reassign"
+)
+
+// ParseDeletePolicy converts a policy name to a DeletePolicy, defaulting to restrict
+func ParseDeletePolicy(name string) (DeletePolicy, error) {
+	switch policy := DeletePolicy(name); policy {
+	case "":
+		return DeletePolicyRestrict, nil
+	case DeletePolicyRestrict, DeletePolicyCascade, DeletePolicyReassign:
+		return policy, nil
+	default:
+		return "", domain.NewError(domain.ErrValidation, fmt.Sprintf("unknown delete policy %q", name))
+	}
+}
+
 // UserUseCase represents the user use case
 type UserUseCase struct {
-	userRepo repository.UserRepository
+	userRepo    repository.UserRepository
+	taskRepo    repository.TaskRepository
+	sessionRepo repository.SessionRepository
+	apiKeyRepo  repository.APIKeyRepository
+	transactor  repository.Transactor
+	hasher      service.PasswordHasher
+	totp        service.TOTP
+	loginGuard  *LoginGuard
 }
 
 // NewUserUseCase creates a new user use case
-func NewUserUseCase(userRepo repository.UserRepository) *UserUseCase {
+func NewUserUseCase(userRepo repository.UserRepository, taskRepo repository.TaskRepository, sessionRepo repository.SessionRepository, apiKeyRepo repository.APIKeyRepository, transactor repository.Transactor, hasher service.PasswordHasher, totp service.TOTP, loginGuard *LoginGuard) *UserUseCase {
 	return &UserUseCase{
-		userRepo: userRepo,
+		userRepo:    userRepo,
+		taskRepo:    taskRepo,
+		sessionRepo: sessionRepo,
+		apiKeyRepo:  apiKeyRepo,
+		transactor:  transactor,
+		hasher:      hasher,
+		totp:        totp,
+		loginGuard:  loginGuard,
 	}
 }
 
 // GetByID retrieves a user by their ID
 func (uc *UserUseCase) GetByID(ctx context.Context, id uint64) (*entity.User, error) {
+	if err := authorize(ctx, entity.PermissionUserRead, id); err != nil {
+		return nil, err
+	}
+
 	return uc.userRepo.GetByID(ctx, id)
 }
 
 // GetByEmail retrieves a user by their email
 func (uc *UserUseCase) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
-	return uc.userRepo.GetByEmail(ctx, email)
+	user, err := uc.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, entity.PermissionUserRead, user.ID); err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
 
 // GetByUsername retrieves a user by their username
 func (uc *UserUseCase) GetByUsername(ctx context.Context, username string) (*entity.User, error) {
-	return uc.userRepo.GetByUsername(ctx, username)
+	user, err := uc.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, entity.PermissionUserRead, user.ID); err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
 
-// Create creates a new user
+// Create creates a new member. Anyone may sign up, but authenticated callers
+// need permission to create users on behalf of others.
 func (uc *UserUseCase) Create(ctx context.Context, username, email, password, firstName, lastName string) (*entity.User, error) {
+	if _, ok := PrincipalFromContext(ctx); ok {
+		if err := authorizeAll(ctx, entity.PermissionUserCreate); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create user entity
 	user := entity.NewUser(username, email, password, firstName, lastName)
 
 	// Validate user
-	if err := user.Validate(); err != nil {
+	if err := entity.MergeValidationErrors(user.Validate(), entity.ValidatePassword("password", password)); err != nil {
 		return nil, err
 	}
 
-	// Check if email already exists
-	existingUser, err := uc.userRepo.GetByEmail(ctx, email)
-	if err == nil && existingUser != nil {
-		return nil, errors.New("email already exists")
+	// Never store the plaintext password
+	hash, err := uc.hasher.Hash(password)
+	if err != nil {
+		return nil, err
 	}
+	user.Password = hash
 
-	// Check if username already exists
-	existingUser, err = uc.userRepo.GetByUsername(ctx, username)
-	if err == nil && existingUser != nil {
-		return nil, errors.New("username already exists")
-	}
+	err = uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		// Check if email already exists
+		existingUser, err := uc.userRepo.GetByEmail(ctx, email)
+		if err == nil && existingUser != nil {
+			return domain.NewError(domain.ErrConflict, "email already exists")
+		}
+
+		// Check if username already exists
+		existingUser, err = uc.userRepo.GetByUsername(ctx, username)
+		if err == nil && existingUser != nil {
+			return domain.NewError(domain.ErrConflict, "username already exists")
+		}
+
+		// The first user to sign up administers the installation
+		first, err := uc.userRepo.Bootstrap(ctx)
+		if err != nil {
+			return err
+		}
+		if first {
+			user.Role = entity.RoleAdmin
+		}
 
-	// Create user
-	if err := uc.userRepo.Create(ctx, user); err != nil {
+		// Create user
+		return uc.userRepo.Create(ctx, user)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
 
-// Update updates an existing user
-func (uc *UserUseCase) Update(ctx context.Context, id uint64, username, email, firstName, lastName string) (*entity.User, error) {
-	// Get existing user
-	user, err := uc.userRepo.GetByID(ctx, id)
+// verifyPassword returns the user with the given email if password matches their
+// stored hash, or ErrInvalidCredentials. Hashes produced with outdated parameters
+// are transparently replaced with fresh ones.
+func (uc *UserUseCase) verifyPassword(ctx context.Context, email, password string) (*entity.User, error) {
+	user, err := uc.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		// Spend as long as a real check so response times do not reveal unknown emails
+		_, _ = uc.hasher.Hash(password)
+		return nil, ErrInvalidCredentials
+	}
+
+	ok, err := uc.hasher.Verify(user.Password, password)
 	if err != nil {
 		return nil, err
 	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	if uc.hasher.NeedsRehash(user.Password) {
+		// Rehashing is best effort: the login succeeds even if another write wins the race
+		if err := uc.setPassword(ctx, user, password); err != nil && !errors.Is(err, repository.ErrVersionConflict) {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// ChangePassword replaces a user's password after checking their current one
+// and signs the user out everywhere, except in the cookie session the change
+// was made in. A wrong current password is reported as ErrInvalidCredentials.
+func (uc *UserUseCase) ChangePassword(ctx context.Context, id uint64, currentPassword, newPassword string) error {
+	if err := authorize(ctx, entity.PermissionUserPassword, id); err != nil {
+		return err
+	}
+
+	if err := entity.ValidatePassword("new_password", newPassword); err != nil {
+		return err
+	}
+
+	checked, err := uc.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Outside the transaction, whose rollback would take back a failed attempt
+	if err := uc.checkPassword(ctx, checked, currentPassword); err != nil {
+		return err
+	}
+
+	return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := uc.userRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// The password changed since it was checked
+		if user.Password != checked.Password {
+			return ErrInvalidCredentials
+		}
 
-	// Update user fields
-	user.Username = username
-	user.Email = email
-	user.FirstName = firstName
-	user.LastName = lastName
+		var keepSessionID string
+		if principal, ok := PrincipalFromContext(ctx); ok && principal.UserID == id {
+			keepSessionID = principal.SessionID
+		}
+
+		return uc.replacePassword(ctx, user, newPassword, keepSessionID)
+	})
+}
+
+// setPassword hashes password and stores it as the user's password
+func (uc *UserUseCase) setPassword(ctx context.Context, user *entity.User, password string) error {
+	hash, err := uc.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	user.Password = hash
 	user.UpdatedAt = time.Now()
 
-	// Validate user
-	if err := user.Validate(); err != nil {
+	return uc.userRepo.Update(ctx, user)
+}
+
+// replacePassword stores password as the user's new password and signs them out
+// everywhere: their sessions but the one with ID keepSessionID end, and the
+// tokens and API keys issued to them stop working. It has to run in a transaction.
+func (uc *UserUseCase) replacePassword(ctx context.Context, user *entity.User, password, keepSessionID string) error {
+	user.TokenVersion++
+	if err := uc.setPassword(ctx, user, password); err != nil {
+		return err
+	}
+
+	if _, err := uc.sessionRepo.DeleteOthers(ctx, user.ID, keepSessionID); err != nil {
+		return err
+	}
+
+	_, err := uc.apiKeyRepo.RevokeByUserID(ctx, user.ID, time.Now())
+	return err
+}
+
+// UserPatch holds the profile fields of a user a partial update changes. Nil
+// fields are left as they are.
+type UserPatch struct {
+	Username  *string
+	Email     *string
+	FirstName *string
+	LastName  *string
+}
+
+// Update replaces the profile of an existing user. A non-zero version must match
+// the stored version, otherwise repository.ErrVersionConflict is returned.
+func (uc *UserUseCase) Update(ctx context.Context, id uint64, username, email, firstName, lastName string, version uint64) (*entity.User, error) {
+	return uc.Patch(ctx, id, UserPatch{
+		Username:  &username,
+		Email:     &email,
+		FirstName: &firstName,
+		LastName:  &lastName,
+	}, version)
+}
+
+// Patch changes the profile fields of an existing user set in patch and
+// validates the result. A non-zero version must match the stored version.
+func (uc *UserUseCase) Patch(ctx context.Context, id uint64, patch UserPatch, version uint64) (*entity.User, error) {
+	if err := authorize(ctx, entity.PermissionUserUpdate, id); err != nil {
 		return nil, err
 	}
 
-	// Update user
-	if err := uc.userRepo.Update(ctx, user); err != nil {
+	var user *entity.User
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		// Get existing user
+		var err error
+		user, err = uc.userRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// Let the repository reject the write if the caller's version is stale
+		if version != 0 {
+			user.Version = version
+		}
+
+		// A new email address has to be verified again
+		if patch.Email != nil && *patch.Email != user.Email {
+			user.EmailVerifiedAt = nil
+		}
+
+		// Update the supplied fields
+		if patch.Username != nil {
+			user.Username = *patch.Username
+		}
+		if patch.Email != nil {
+			user.Email = *patch.Email
+		}
+		if patch.FirstName != nil {
+			user.FirstName = *patch.FirstName
+		}
+		if patch.LastName != nil {
+			user.LastName = *patch.LastName
+		}
+		user.UpdatedAt = time.Now()
+
+		// Validate user
+		if err := user.Validate(); err != nil {
+			return err
+		}
+
+		// Update user
+		return uc.userRepo.Update(ctx, user)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
 
-// Delete deletes a user by their ID
-func (uc *UserUseCase) Delete(ctx context.Context, id uint64) error {
-	return uc.userRepo.Delete(ctx, id)
+// Unlock lifts the lockout of a user's account and forgets its failed logins.
+// The unlock is recorded in the audit log.
+func (uc *UserUseCase) Unlock(ctx context.Context, id uint64) error {
+	if err := authorizeAll(ctx, entity.PermissionUserUnlock); err != nil {
+		return err
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	principal, _ := PrincipalFromContext(ctx)
+	return uc.loginGuard.Unlock(ctx, user, principal.UserID)
+}
+
+// ChangeRole assigns a new role to a user. Callers cannot change their own role,
+// so an installation always keeps the admin who makes the change.
+func (uc *UserUseCase) ChangeRole(ctx context.Context, id uint64, role entity.Role, version uint64) (*entity.User, error) {
+	if err := authorizeAll(ctx, entity.PermissionUserRole); err != nil {
+		return nil, err
+	}
+
+	if principal, _ := PrincipalFromContext(ctx); principal.UserID == id {
+		return nil, ErrOwnRole
+	}
+
+	var user *entity.User
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = uc.userRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// Let the repository reject the write if the caller's version is stale
+		if version != 0 {
+			user.Version = version
+		}
+
+		user.Role = role
+		user.UpdatedAt = time.Now()
+
+		if err := user.Validate(); err != nil {
+			return err
+		}
+
+		return uc.userRepo.Update(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Delete deletes a user by their ID. A non-zero version must match the stored version.
+// The policy decides what happens to the user's tasks: restrict fails with
+// repository.ErrUserHasTasks if there are any, cascade moves them to the trash and
+// reassign transfers them to the user identified by reassignTo, which requires
+// permission to reassign every user's tasks.
+func (uc *UserUseCase) Delete(ctx context.Context, id uint64, version uint64, policy DeletePolicy, reassignTo uint64) error {
+	if err := authorize(ctx, entity.PermissionUserDelete, id); err != nil {
+		return err
+	}
+
+	// Handing tasks to someone else is not up to the user being deleted
+	if policy == DeletePolicyReassign {
+		if err := authorizeAll(ctx, entity.PermissionTaskReassign); err != nil {
+			return err
+		}
+	}
+
+	// Run in a transaction so the tasks are handled atomically with the user
+	return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := uc.userRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// Fail early, before any tasks are handled; deleting checks the version again
+		if version != 0 && user.Version != version {
+			return repository.ErrVersionConflict
+		}
+
+		switch policy {
+		case DeletePolicyRestrict:
+			count, err := uc.taskRepo.CountByUserID(ctx, id)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return repository.ErrUserHasTasks
+			}
+		case DeletePolicyCascade:
+			if _, err := uc.taskRepo.DeleteByUserID(ctx, id); err != nil {
+				return err
+			}
+		case DeletePolicyReassign:
+			if reassignTo == 0 || reassignTo == id {
+				return domain.NewError(domain.ErrValidation, "reassign target must be another user")
+			}
+
+			// Verify the new owner exists
+			if _, err := uc.userRepo.GetByID(ctx, reassignTo); err != nil {
+				return domain.NewError(domain.ErrValidation, "reassign target not found")
+			}
+
+			if _, err := uc.taskRepo.ReassignUser(ctx, id, reassignTo); err != nil {
+				return err
+			}
+		default:
+			return domain.NewError(domain.ErrValidation, fmt.Sprintf("unknown delete policy %q", policy))
+		}
+
+		return uc.userRepo.Delete(ctx, id, version)
+	})
+}
+
+// List retrieves a page of users, along with how many there are in total
+func (uc *UserUseCase) List(ctx context.Context, page repository.Page) ([]*entity.User, int64, error) {
+	if err := authorizeAll(ctx, entity.PermissionUserList); err != nil {
+		return nil, 0, err
+	}
+
+	users, err := uc.userRepo.List(ctx, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := uc.userRepo.Count(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
 }
 
-// List retrieves a list of users with pagination
-func (uc *UserUseCase) List(ctx context.Context, limit, offset int) ([]*entity.User, error) {
-	return uc.userRepo.List(ctx, limit, offset)
+// ListDeleted retrieves a page of the users in the trash, along with how many
+// there are in total
+func (uc *UserUseCase) ListDeleted(ctx context.Context, page repository.Page) ([]*entity.User, int64, error) {
+	if err := authorizeAll(ctx, entity.PermissionUserTrash); err != nil {
+		return nil, 0, err
+	}
+
+	users, err := uc.userRepo.ListDeleted(ctx, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := uc.userRepo.CountDeleted(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// Restore moves a user out of the trash
+func (uc *UserUseCase) Restore(ctx context.Context, id uint64) (*entity.User, error) {
+	if err := authorize(ctx, entity.PermissionUserTrash, id); err != nil {
+		return nil, err
+	}
+
+	var user *entity.User
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.userRepo.Restore(ctx, id); err != nil {
+			return err
+		}
+
+		var err error
+		user, err = uc.userRepo.GetByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/entity"
+	"github.com/dimasbagussusilo/go-clean-boilerplate/domain/repository"
+)
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name string
+		// via picks the credentials the change is made with
+		via func(a *testAccounts, ctx context.Context, c *credentials) (context.Context, error)
+		// keepsSession tells that the session of c stays logged in
+		keepsSession bool
+		// newTokens tells that the caller gets a new token pair
+		newTokens bool
+	}{
+		{
+			name: "with a session cookie",
+			via: func(a *testAccounts, ctx context.Context, c *credentials) (context.Context, error) {
+				principal, _, err := a.sessions.Authenticate(ctx, c.sessionToken)
+				return WithPrincipal(ctx, principal), err
+			},
+			keepsSession: true,
+		},
+		{
+			name: "with an access token",
+			via: func(a *testAccounts, ctx context.Context, c *credentials) (context.Context, error) {
+				principal, err := a.auth.Authenticate(ctx, c.tokens.AccessToken)
+				return WithPrincipal(ctx, principal), err
+			},
+			newTokens: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			a := newTestAccounts(t)
+			current := a.signIn(t, ctx)
+			other := a.signIn(t, ctx)
+
+			callerCtx, err := tt.via(a, ctx, current)
+			if err != nil {
+				t.Fatalf("authenticating the caller: %v", err)
+			}
+
+			tokens, err := a.auth.ChangePassword(callerCtx, a.user.ID, "password1", "password2")
+			if err != nil {
+				t.Fatalf("ChangePassword() error = %v", err)
+			}
+
+			// Everything signed in elsewhere stops working
+			a.checkSignedOut(t, ctx, other)
+
+			if _, _, err := a.sessions.Authenticate(ctx, current.sessionToken); (err == nil) != tt.keepsSession {
+				t.Errorf("session of the caller error = %v, want it kept %v", err, tt.keepsSession)
+			}
+
+			if (tokens != nil) != tt.newTokens {
+				t.Fatalf("ChangePassword() returned tokens %v, want new tokens %v", tokens != nil, tt.newTokens)
+			}
+			if tokens != nil {
+				if _, err := a.auth.Authenticate(ctx, tokens.AccessToken); err != nil {
+					t.Errorf("authenticating with the new access token: %v", err)
+				}
+				if _, err := a.auth.Authenticate(ctx, current.tokens.AccessToken); err == nil {
+					t.Error("the access token used for the change still works")
+				}
+			}
+		})
+	}
+}
+
+func TestCreateMakesOnlyTheFirstUserAdmin(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAccounts(t)
+
+	// newTestAccounts stored alice without signing her up, so the installation
+	// is not bootstrapped yet; trash her to check trashed users do not count
+	if err := a.users.userRepo.Delete(ctx, a.user.ID, 0); err != nil {
+		t.Fatalf("deleting user: %v", err)
+	}
+
+	roles := make(chan entity.Role, 10)
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("user%d", i)
+			user, err := a.users.Create(ctx, name, name+"@x.io", "password1", "", "")
+			if err != nil {
+				t.Errorf("Create() error = %v", err)
+				return
+			}
+			roles <- user.Role
+		}()
+	}
+	wg.Wait()
+	close(roles)
+
+	var admins int
+	for role := range roles {
+		if role == entity.RoleAdmin {
+			admins++
+		}
+	}
+	if admins != 1 {
+		t.Fatalf("%d of the concurrent first signups became admin, want 1", admins)
+	}
+
+	// Trashing every user does not make the next signup an admin
+	users, _ := a.users.userRepo.List(ctx, repository.Page{Limit: 100})
+	for _, user := range users {
+		if err := a.users.userRepo.Delete(ctx, user.ID, 0); err != nil {
+			t.Fatalf("deleting user: %v", err)
+		}
+	}
+	user, err := a.users.Create(ctx, "late", "late@x.io", "password1", "", "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if user.Role != entity.RoleMember {
+		t.Errorf("signup after trashing every user got role %q, want %q", user.Role, entity.RoleMember)
+	}
+}